@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/rsdlab-dk/tft-api/internal/api"
+	"github.com/rsdlab-dk/tft-api/internal/auth"
+	"github.com/rsdlab-dk/tft-api/internal/cache"
+	"github.com/rsdlab-dk/tft-api/internal/config"
+	"github.com/rsdlab-dk/tft-api/internal/database"
+	"github.com/rsdlab-dk/tft-api/internal/jobs"
+	"github.com/rsdlab-dk/tft-api/internal/messaging"
+	"github.com/rsdlab-dk/tft-api/internal/models"
+	"github.com/rsdlab-dk/tft-api/internal/patches"
+	"github.com/rsdlab-dk/tft-api/internal/repository"
+	"github.com/rsdlab-dk/tft-api/internal/retention"
+	"github.com/rsdlab-dk/tft-api/internal/riot"
+	"github.com/rsdlab-dk/tft-api/internal/warmup"
+	"go.uber.org/zap"
+)
+
+const patchRefreshInterval = 5 * time.Minute
+
+func main() {
+	configPath := flag.String("config", ".env.development", "Path to configuration file")
+	flag.Parse()
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	logger, err := newLogger(cfg)
+	if err != nil {
+		log.Fatalf("Failed to create logger: %v", err)
+	}
+	defer logger.Sync()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	db, err := database.NewPostgres(ctx, cfg)
+	if err != nil {
+		logger.Fatal("failed to connect to database", zap.Error(err))
+	}
+	defer db.Close()
+
+	rdb, err := database.NewRedis(ctx, cfg)
+	if err != nil {
+		logger.Fatal("failed to connect to redis", zap.Error(err))
+	}
+	defer rdb.Close()
+
+	connect := messaging.Connect
+	if cfg.NATS.Embedded {
+		embedded, err := messaging.StartEmbedded(cfg.NATS, logger)
+		if err != nil {
+			logger.Fatal("failed to start embedded nats server", zap.Error(err))
+		}
+		defer embedded.Shutdown()
+		// With EmbeddedPort -1 the server picks a port, so connect to the
+		// address it reports rather than the configured one.
+		connect = embedded.Connect
+	}
+
+	nc, err := connect(cfg.NATS, "tft-api", logger)
+	if err != nil {
+		logger.Fatal("failed to connect to nats", zap.Error(err))
+	}
+	defer nc.Drain()
+
+	queue, err := jobs.NewQueue(ctx, nc, cfg.Jobs)
+	if err != nil {
+		logger.Fatal("failed to set up job queue", zap.Error(err))
+	}
+
+	jobRepo := repository.NewJobRepository(db)
+	queue.SetHistory(jobs.NewHistory(jobRepo, logger))
+	queue.SetDeduper(jobs.NewDeduper(rdb, cfg.Jobs.DedupeWindow))
+
+	comps := repository.NewCompositionRepository(db)
+	analytics := repository.NewAnalyticsRepository(db)
+	invalidator := cache.NewInvalidator(nc, logger)
+	retentionRunner := retention.NewRunner(repository.NewRetentionRepository(db), comps, cfg.Retention, logger)
+
+	defaultPatch := models.Patch(cfg.Riot.DefaultPatch)
+	if defaultPatch != "" && !defaultPatch.IsValid() {
+		logger.Fatal("invalid default patch", zap.String("patch", cfg.Riot.DefaultPatch))
+	}
+	registry := patches.NewRegistry(repository.NewPatchRepository(db), defaultPatch, logger)
+	if err := registry.Refresh(ctx); err != nil {
+		logger.Warn("failed to load patches", zap.Error(err))
+	}
+	go registry.Run(ctx, patchRefreshInterval)
+
+	readCache := cache.New(rdb, cfg.Cache, logger)
+	go func() {
+		if err := readCache.RunInvalidations(ctx, nc); err != nil {
+			logger.Error("cache invalidation listener stopped", zap.Error(err))
+		}
+	}()
+	popularity := cache.NewPopularity(rdb, logger)
+	go popularity.Run(ctx)
+	warmupRunner := warmup.NewRunner(readCache, popularity, comps, analytics, registry, cfg.Cache.WarmKeys, logger)
+
+	riotClient := riot.NewClient(cfg.Riot)
+
+	pool := jobs.NewPool(queue, cfg.Jobs, logger)
+	pool.Register(models.JobTypeCollectChallenger, jobs.NewCollectChallengerHandler(riotClient, queue, logger))
+	pool.Register(models.JobTypeCollectPlayer, jobs.NewCollectPlayerHandler(riotClient, repository.NewSummonerRepository(db)))
+	pool.Register(models.JobTypeAnalyzeCompositions, jobs.NewAnalyzeCompositionsHandler(comps, repository.NewMatchRepository(db), invalidator, logger))
+	pool.Register(models.JobTypeRefreshLeaderboard, jobs.NewRefreshLeaderboardHandler(comps, invalidator))
+	pool.Register(models.JobTypeUpdateMetaData, jobs.NewUpdateMetaDataHandler(analytics, invalidator, logger))
+	pool.Register(models.JobTypeCleanupCache, jobs.NewCleanupHandler(retentionRunner, invalidator, logger))
+	pool.Register(models.JobTypeWarmCache, jobs.NewWarmCacheHandler(warmupRunner, logger))
+
+	scheduler := jobs.NewScheduler(queue, rdb, cfg, logger)
+	go scheduler.Run(ctx)
+
+	// Warm up after every deploy; replicas starting together are merged
+	// into one job by the deduper.
+	if _, err := queue.Enqueue(ctx, jobs.WarmCachePayload{}); err != nil {
+		logger.Warn("failed to enqueue cache warm-up", zap.Error(err))
+	}
+
+	server, err := api.NewServer(cfg, logger)
+	if err != nil {
+		logger.Fatal("failed to set up http server", zap.Error(err))
+	}
+
+	authService, err := auth.NewService(repository.NewUserRepository(db), rdb, cfg.Auth, logger)
+	if err != nil {
+		logger.Fatal("failed to set up auth", zap.Error(err))
+	}
+
+	apiKeyRepo := repository.NewAPIKeyRepository(db)
+	apiKeys := auth.NewAPIKeys(apiKeyRepo)
+	apiKeyUsage := auth.NewUsage(apiKeyRepo, logger)
+	go apiKeyUsage.Run(ctx)
+	apiKeyAuth := api.NewAPIKeyAuth(apiKeys, auth.NewRateLimiter(rdb, logger), apiKeyUsage, logger)
+
+	authHandler := api.NewAuthHandler(authService, logger)
+	access := api.NewAccess(authHandler, apiKeyAuth, cfg.Server.AdminToken, logger)
+
+	v1 := server.Engine().Group("/api/v1")
+	authHandler.Register(v1)
+	api.NewCompositionsHandler(comps, analytics, registry, readCache, popularity, apiKeyAuth, logger).Register(v1)
+
+	riotLinks := auth.NewRiotLinks(
+		repository.NewRiotLinkRepository(db),
+		repository.NewSummonerRepository(db),
+		riotClient,
+		rdb, cfg.Auth, logger,
+	)
+	api.NewRiotAccountsHandler(riotLinks, authHandler, logger).Register(v1)
+
+	// Every admin route needs a user, an API key or the admin token; each
+	// handler then checks the role's permissions per route group.
+	admin := server.Engine().Group("/admin", access.Authenticate())
+	api.NewJobsAdminHandler(queue, jobRepo, access, logger).Register(admin)
+	api.NewCacheAdminHandler(readCache, invalidator, access, logger).Register(admin)
+	api.NewAPIKeysAdminHandler(apiKeys, access, logger).Register(admin)
+	api.NewUsersAdminHandler(authService, access, logger).Register(admin)
+	api.NewSystemAdminHandler(repository.NewMigrationRepository(db), access, logger).Register(admin)
+
+	progressRelay := api.NewProgressRelay(nc, access, rdb, cfg.Server.AllowedOrigins, logger)
+	progressRelay.Register(admin)
+	// Browsers cannot send credentials with a WebSocket upgrade, so the
+	// stream sits outside the admin group and accepts a ticket instead.
+	progressRelay.RegisterStream(server.Engine().Group("/admin"))
+	go func() {
+		if err := progressRelay.Run(ctx); err != nil {
+			logger.Error("job progress relay stopped", zap.Error(err))
+		}
+	}()
+
+	serverDone := make(chan struct{})
+	go func() {
+		defer close(serverDone)
+		if err := server.Run(ctx); err != nil {
+			logger.Error("http server stopped", zap.Error(err))
+			stop()
+		}
+	}()
+
+	logger.Info("starting job workers", zap.Int("workers", cfg.Jobs.WorkerCount))
+	if err := pool.Run(ctx); err != nil {
+		logger.Fatal("job workers stopped", zap.Error(err))
+	}
+	<-serverDone
+
+	logger.Info("shutdown complete")
+}
+
+func newLogger(cfg *config.Config) (*zap.Logger, error) {
+	if cfg.IsDevelopment() {
+		return zap.NewDevelopment()
+	}
+	return zap.NewProduction()
+}
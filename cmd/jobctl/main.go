@@ -0,0 +1,312 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/rsdlab-dk/tft-api/internal/config"
+	"github.com/rsdlab-dk/tft-api/internal/database"
+	"github.com/rsdlab-dk/tft-api/internal/jobs"
+	"github.com/rsdlab-dk/tft-api/internal/messaging"
+	"github.com/rsdlab-dk/tft-api/internal/models"
+	"github.com/rsdlab-dk/tft-api/internal/repository"
+	"github.com/rsdlab-dk/tft-api/internal/retention"
+	"go.uber.org/zap"
+)
+
+const usage = `Usage: jobctl [-config path] <command> [flags]
+
+Commands:
+  enqueue -type <job type> [-region r] [-puuid p] [-patch v] [-full] [-repair] [-dry-run] [-limit n]
+                        Enqueue a job outside the schedule
+  depth                 Show pending and in-flight jobs per type
+  pause <job type|all>  Stop workers from starting jobs of a type
+  resume <job type|all> Resume a paused job type
+  tail [-type t]        Print job results as they finish
+  retention [-dry-run]  Apply the retention windows now and print what was deleted
+
+Job types: collect_challenger, collect_player, analyze_compositions,
+           refresh_leaderboard, cleanup_cache, update_meta_data, warm_cache
+`
+
+// errUsage makes main print the usage and exit with status 2.
+var errUsage = errors.New("invalid usage")
+
+func main() {
+	configPath := flag.String("config", ".env.development", "Path to configuration file")
+	flag.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	flag.Parse()
+
+	// run returns instead of exiting so its deferred cleanup, such as
+	// draining the NATS connection, always happens.
+	if err := run(*configPath); err != nil {
+		if errors.Is(err, errUsage) {
+			flag.Usage()
+			os.Exit(2)
+		}
+		log.Fatal(err)
+	}
+}
+
+func run(configPath string) error {
+	if flag.NArg() == 0 {
+		return errUsage
+	}
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	nc, err := messaging.Connect(cfg.NATS, "tft-jobctl", zap.NewNop())
+	if err != nil {
+		return err
+	}
+	defer nc.Drain()
+
+	// The API and workers own the stream and consumer configuration;
+	// jobctl only looks them up.
+	queue, err := jobs.OpenQueue(ctx, nc, cfg.Jobs)
+	if err != nil {
+		return err
+	}
+
+	command, args := flag.Arg(0), flag.Args()[1:]
+	switch command {
+	case "enqueue":
+		err = enqueue(ctx, cfg, queue, args)
+	case "depth":
+		err = depth(ctx, queue)
+	case "pause":
+		err = setPaused(ctx, queue, args, true)
+	case "resume":
+		err = setPaused(ctx, queue, args, false)
+	case "tail":
+		err = tail(ctx, nc, args)
+	case "retention":
+		err = runRetention(ctx, cfg, args)
+	default:
+		return errUsage
+	}
+
+	if err != nil {
+		return fmt.Errorf("%s failed: %w", command, err)
+	}
+	return nil
+}
+
+func enqueue(ctx context.Context, cfg *config.Config, queue *jobs.Queue, args []string) error {
+	fs := flag.NewFlagSet("enqueue", flag.ExitOnError)
+	jobType := fs.String("type", "", "Job type to enqueue")
+	region := fs.String("region", "", "Region, e.g. kr or euw1")
+	puuid := fs.String("puuid", "", "Player PUUID (collect_player)")
+	patch := fs.String("patch", "", "Patch version, e.g. 15.1")
+	full := fs.Bool("full", false, "Recompute every bucket instead of changed ones (update_meta_data)")
+	repair := fs.Bool("repair", false, "Rebuild comp counters from comp_games first (analyze_compositions)")
+	dryRun := fs.Bool("dry-run", false, "Only count what would be deleted (cleanup_cache)")
+	limit := fs.Int("limit", 0, "Reads to warm per cache key prefix (warm_cache)")
+	fs.Parse(args)
+
+	payload, err := buildPayload(models.JobType(*jobType), models.Region(*region), *puuid, models.Patch(*patch), *full, *repair, *dryRun, *limit)
+	if err != nil {
+		return err
+	}
+	// Reject bad flags before connecting to the database.
+	if err := payload.Validate(); err != nil {
+		return fmt.Errorf("invalid %s job: %w", *jobType, err)
+	}
+
+	// Record and deduplicate the job like the API does.
+	db, err := database.NewPostgres(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	queue.SetHistory(jobs.NewHistory(repository.NewJobRepository(db), zap.NewNop()))
+
+	rdb, err := database.NewRedis(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer rdb.Close()
+	queue.SetDeduper(jobs.NewDeduper(rdb, cfg.Jobs.DedupeWindow))
+
+	job, err := queue.Enqueue(ctx, payload)
+	if err != nil {
+		return err
+	}
+
+	if job.Merged {
+		fmt.Printf("Merged into pending %s job %s\n", job.Type, job.ID)
+		return nil
+	}
+	fmt.Printf("Enqueued %s job %s\n", job.Type, job.ID)
+	return nil
+}
+
+func buildPayload(jobType models.JobType, region models.Region, puuid string, patch models.Patch, full, repair, dryRun bool, limit int) (jobs.Payload, error) {
+	if puuid != "" && jobType != models.JobTypeCollectPlayer {
+		return nil, fmt.Errorf("%s takes no -puuid", jobType)
+	}
+
+	switch jobType {
+	case models.JobTypeCollectChallenger:
+		return jobs.CollectChallengerPayload{Region: region}, nil
+	case models.JobTypeCollectPlayer:
+		return jobs.CollectPlayerPayload{PUUID: puuid, Region: region}, nil
+	case models.JobTypeAnalyzeCompositions:
+		return jobs.AnalyzeCompositionsPayload{Patch: patch, Region: region, Repair: repair}, nil
+	case models.JobTypeRefreshLeaderboard:
+		if region != "" {
+			return nil, fmt.Errorf("%s rebuilds every region and takes no -region", jobType)
+		}
+		return jobs.RefreshLeaderboardPayload{}, nil
+	case models.JobTypeCleanupCache:
+		return jobs.CleanupCachePayload{DryRun: dryRun}, nil
+	case models.JobTypeUpdateMetaData:
+		return jobs.UpdateMetaDataPayload{Patch: patch, Region: region, Full: full}, nil
+	case models.JobTypeWarmCache:
+		return jobs.WarmCachePayload{Limit: limit}, nil
+	default:
+		return nil, fmt.Errorf("unknown job type %q", jobType)
+	}
+}
+
+func depth(ctx context.Context, queue *jobs.Queue) error {
+	depths, err := queue.Depth(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TYPE\tPENDING\tIN FLIGHT\tREDELIVERED\tSTATE")
+	for _, d := range depths {
+		state := "running"
+		if d.Paused {
+			state = "paused"
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%s\n", d.Type, d.Pending, d.InFlight, d.Redelivered, state)
+	}
+	return w.Flush()
+}
+
+func setPaused(ctx context.Context, queue *jobs.Queue, args []string, paused bool) error {
+	if len(args) != 1 {
+		return fmt.Errorf("expected one job type or \"all\"")
+	}
+
+	jobTypes := models.AllJobTypes()
+	if args[0] != "all" {
+		jobType := models.JobType(args[0])
+		if !jobType.IsValid() {
+			return fmt.Errorf("unknown job type %q", args[0])
+		}
+		jobTypes = []models.JobType{jobType}
+	}
+
+	for _, jobType := range jobTypes {
+		if paused {
+			if err := queue.Pause(ctx, jobType); err != nil {
+				return err
+			}
+			fmt.Printf("Paused %s\n", jobType)
+		} else {
+			if err := queue.Resume(ctx, jobType); err != nil {
+				return err
+			}
+			fmt.Printf("Resumed %s\n", jobType)
+		}
+	}
+	return nil
+}
+
+func tail(ctx context.Context, nc *nats.Conn, args []string) error {
+	fs := flag.NewFlagSet("tail", flag.ExitOnError)
+	jobType := fs.String("type", "", "Only show results for this job type")
+	fs.Parse(args)
+
+	subject := jobs.ResultSubjectPrefix + ".>"
+	if *jobType != "" {
+		if !models.JobType(*jobType).IsValid() {
+			return fmt.Errorf("unknown job type %q", *jobType)
+		}
+		subject = jobs.ResultSubject(models.JobType(*jobType))
+	}
+
+	sub, err := nc.Subscribe(subject, func(msg *nats.Msg) {
+		var result jobs.Result
+		if err := json.Unmarshal(msg.Data, &result); err != nil {
+			fmt.Fprintf(os.Stderr, "undecodable result on %s: %v\n", msg.Subject, err)
+			return
+		}
+
+		line := fmt.Sprintf("%s  %-20s %s  attempt=%d  %-13s %s",
+			result.FinishedAt.Local().Format(time.TimeOnly),
+			result.Type,
+			result.JobID,
+			result.Attempt,
+			result.Status,
+			time.Duration(result.DurationMs)*time.Millisecond,
+		)
+		if result.Error != "" {
+			line += "  error=" + result.Error
+		}
+		fmt.Println(line)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %w", subject, err)
+	}
+	defer sub.Unsubscribe()
+
+	fmt.Fprintf(os.Stderr, "Tailing %s, press Ctrl+C to stop\n", subject)
+	<-ctx.Done()
+	return nil
+}
+
+func runRetention(ctx context.Context, cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("retention", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "Only count what would be deleted")
+	fs.Parse(args)
+
+	db, err := database.NewPostgres(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	runner := retention.NewRunner(
+		repository.NewRetentionRepository(db),
+		repository.NewCompositionRepository(db),
+		cfg.Retention,
+		zap.NewNop(),
+	)
+
+	report, err := runner.Run(ctx, *dryRun, nil)
+	if report != nil {
+		column := "DELETED"
+		if report.DryRun {
+			column = "WOULD DELETE"
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "TARGET\tWINDOW\t%s\tBATCHES\tDURATION\n", column)
+		for _, t := range report.Targets {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\n", t.Name, t.Window, t.Deleted, t.Batches, t.Duration.Round(time.Millisecond))
+		}
+		w.Flush()
+	}
+	return err
+}
@@ -4,7 +4,6 @@ import (
 	"flag"
 	"fmt"
 	"log"
-	"os"
 
 	"github.com/rsdlab-dk/tft-api/internal/config"
 )
@@ -40,7 +39,11 @@ func printConfigSummary(cfg *config.Config) {
 	fmt.Printf("🌐 Server: %s\n", cfg.ServerAddr())
 	fmt.Printf("🗄️  Database: %s:%d/%s\n", cfg.Database.Host, cfg.Database.Port, cfg.Database.Name)
 	fmt.Printf("⚡ Redis: %s\n", cfg.RedisAddr())
-	fmt.Printf("📨 NATS: %v\n", cfg.NATS.URLs)
+	if cfg.NATS.Embedded {
+		fmt.Printf("📨 NATS: %v (embedded)\n", cfg.NATS.ClientURLs())
+	} else {
+		fmt.Printf("📨 NATS: %v\n", cfg.NATS.URLs)
+	}
 	fmt.Printf("🎮 Riot API: %s (Region: %s)\n", 
 		maskAPIKey(cfg.Riot.APIKey), cfg.Riot.DefaultRegion)
 	
@@ -8,6 +8,7 @@ require (
 	github.com/lib/pq v1.10.9
 	github.com/go-redis/redis/v8 v8.11.5
 	github.com/nats-io/nats.go v1.37.0
+	github.com/nats-io/nats-server/v2 v2.10.22
 	github.com/golang-migrate/migrate/v4 v4.17.1
 	github.com/joho/godotenv v1.5.1
 	github.com/go-playground/validator/v10 v10.22.1
@@ -15,7 +16,9 @@ require (
 	go.uber.org/zap v1.27.0
 	github.com/google/uuid v1.6.0
 	github.com/stretchr/testify v1.9.0
+	github.com/alicebob/miniredis/v2 v2.33.0
 	github.com/golang-jwt/jwt/v5 v5.2.1
 	golang.org/x/crypto v0.29.0
 	golang.org/x/time v0.8.0
+	golang.org/x/sync v0.10.0
 )
\ No newline at end of file
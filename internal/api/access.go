@@ -0,0 +1,112 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rsdlab-dk/tft-api/internal/models"
+	"github.com/rsdlab-dk/tft-api/internal/repository"
+	"go.uber.org/zap"
+)
+
+const principalContextKey = "auth.principal"
+
+// Principal is who an admin request is made by: a user or an API key.
+type Principal struct {
+	Role     models.Role
+	UserID   string
+	APIKeyID string
+}
+
+// Access guards the admin routes. Authenticate runs once for the group and
+// Require on each route group with the permission its routes need.
+type Access struct {
+	users      *AuthHandler
+	apiKeys    *APIKeyAuth
+	adminToken string
+	logger     *zap.Logger
+}
+
+func NewAccess(users *AuthHandler, apiKeys *APIKeyAuth, adminToken string, logger *zap.Logger) *Access {
+	return &Access{users: users, apiKeys: apiKeys, adminToken: adminToken, logger: logger}
+}
+
+// Authenticate accepts an API key, a bearer access token or the admin
+// token and rejects anonymous requests. The admin token acts as the admin
+// role, so the first admin user or key can be created; without
+// ADMIN_TOKEN it is not accepted at all. A user's role is
+// read from the database on every request, so demotions apply at once
+// rather than when tokens expire.
+func (a *Access) Authenticate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if hasAdminToken(c, a.adminToken) {
+			c.Set(principalContextKey, &Principal{Role: models.RoleAdmin})
+			c.Next()
+			return
+		}
+
+		if c.GetHeader(APIKeyHeader) != "" {
+			key, ok := a.apiKeys.authenticate(c, "")
+			if !ok {
+				return
+			}
+			c.Set(apiKeyContextKey, key)
+			c.Set(principalContextKey, &Principal{Role: key.Role, APIKeyID: key.ID})
+			c.Next()
+			return
+		}
+
+		claims, ok := a.users.authenticate(c)
+		if !ok {
+			return
+		}
+		user, err := a.users.service.User(c.Request.Context(), claims.UserID())
+		if errors.Is(err, repository.ErrUserNotFound) {
+			respondError(c, http.StatusUnauthorized, ErrCodeUnauthorized, "user no longer exists")
+			return
+		}
+		if err != nil {
+			a.logger.Error("failed to get user", zap.String("user_id", claims.UserID()), zap.Error(err))
+			respondError(c, http.StatusInternalServerError, ErrCodeInternal, "failed to authenticate")
+			return
+		}
+
+		c.Set(claimsContextKey, claims)
+		c.Set(principalContextKey, &Principal{Role: user.Role, UserID: user.ID})
+		c.Next()
+	}
+}
+
+// Require rejects requests whose principal's role lacks permission. It
+// must run after Authenticate.
+func (a *Access) Require(permission models.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal := principalOf(c)
+		if principal == nil {
+			respondError(c, http.StatusUnauthorized, ErrCodeUnauthorized, "authentication required")
+			return
+		}
+		if !principal.Role.Can(permission) {
+			a.logger.Warn("admin access denied",
+				zap.String("user_id", principal.UserID),
+				zap.String("api_key_id", principal.APIKeyID),
+				zap.String("role", principal.Role.String()),
+				zap.String("permission", permission.String()),
+				zap.String("path", c.FullPath()),
+			)
+			respondError(c, http.StatusForbidden, ErrCodeForbidden,
+				fmt.Sprintf("role %s lacks the %s permission", principal.Role, permission))
+			return
+		}
+		c.Next()
+	}
+}
+
+// principalOf returns the principal stored by Authenticate, or nil.
+func principalOf(c *gin.Context) *Principal {
+	principal, _ := c.Get(principalContextKey)
+	p, _ := principal.(*Principal)
+	return p
+}
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rsdlab-dk/tft-api/internal/auth"
+	"github.com/rsdlab-dk/tft-api/internal/models"
+	"github.com/rsdlab-dk/tft-api/internal/repository"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultUsageDays = 30
+	maxUsageDays     = 366
+)
+
+type APIKeysAdminHandler struct {
+	keys   *auth.APIKeys
+	access *Access
+	logger *zap.Logger
+}
+
+func NewAPIKeysAdminHandler(keys *auth.APIKeys, access *Access, logger *zap.Logger) *APIKeysAdminHandler {
+	return &APIKeysAdminHandler{keys: keys, access: access, logger: logger}
+}
+
+func (h *APIKeysAdminHandler) Register(group *gin.RouterGroup) {
+	manage := group.Group("", h.access.Require(models.PermissionAPIKeysManage))
+	manage.POST("/api-keys", h.createKey)
+	manage.GET("/api-keys", h.listKeys)
+	manage.DELETE("/api-keys/:id", h.revokeKey)
+	manage.GET("/api-keys/:id/usage", h.keyUsage)
+}
+
+func (h *APIKeysAdminHandler) createKey(c *gin.Context) {
+	var req models.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, "invalid request body")
+		return
+	}
+
+	key, err := h.keys.Create(c.Request.Context(), req)
+	var validationErr *models.ValidationError
+	if errors.As(err, &validationErr) {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, err.Error())
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to create api key", zap.Error(err))
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "failed to create api key")
+		return
+	}
+
+	h.logger.Info("api key created", zap.String("api_key_id", key.ID), zap.String("name", key.Name))
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusCreated, models.NewSuccessResponse(key))
+}
+
+func (h *APIKeysAdminHandler) listKeys(c *gin.Context) {
+	keys, err := h.keys.List(c.Request.Context())
+	if err != nil {
+		h.logger.Error("failed to list api keys", zap.Error(err))
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "failed to list api keys")
+		return
+	}
+
+	respondOK(c, keys)
+}
+
+func (h *APIKeysAdminHandler) revokeKey(c *gin.Context) {
+	id, ok := apiKeyIDParam(c)
+	if !ok {
+		return
+	}
+
+	err := h.keys.Revoke(c.Request.Context(), id)
+	if errors.Is(err, repository.ErrAPIKeyNotFound) {
+		respondError(c, http.StatusNotFound, ErrCodeNotFound, "api key not found or already revoked")
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to revoke api key", zap.String("api_key_id", id), zap.Error(err))
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "failed to revoke api key")
+		return
+	}
+
+	h.logger.Info("api key revoked", zap.String("api_key_id", id))
+	respondOK(c, gin.H{"id": id, "revoked": true})
+}
+
+func (h *APIKeysAdminHandler) keyUsage(c *gin.Context) {
+	id, ok := apiKeyIDParam(c)
+	if !ok {
+		return
+	}
+
+	days := defaultUsageDays
+	if raw := c.Query("days"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxUsageDays {
+			respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, "days must be between 1 and 366")
+			return
+		}
+		days = n
+	}
+
+	usage, err := h.keys.Usage(c.Request.Context(), id, days)
+	if err != nil {
+		h.logger.Error("failed to get api key usage", zap.String("api_key_id", id), zap.Error(err))
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "failed to get api key usage")
+		return
+	}
+
+	respondOK(c, usage)
+}
+
+func apiKeyIDParam(c *gin.Context) (string, bool) {
+	id := c.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, "api key id must be a UUID")
+		return "", false
+	}
+	return id, true
+}
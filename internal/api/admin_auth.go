@@ -0,0 +1,18 @@
+package api
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// hasAdminToken compares in constant time so the token cannot be guessed
+// byte by byte from response times.
+func hasAdminToken(c *gin.Context, token string) bool {
+	presented, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok || presented == "" || token == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(presented), []byte(token)) == 1
+}
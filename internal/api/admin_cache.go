@@ -0,0 +1,65 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rsdlab-dk/tft-api/internal/cache"
+	"github.com/rsdlab-dk/tft-api/internal/models"
+	"go.uber.org/zap"
+)
+
+// flushablePrefixes are invalidated by a flush that names no prefixes.
+var flushablePrefixes = []string{
+	models.CacheKeyLeaderboard,
+	models.CacheKeyComposition,
+	models.CacheKeyCompositionDetail,
+	models.CacheKeyMeta,
+}
+
+type CacheAdminHandler struct {
+	cache       *cache.Cache
+	invalidator *cache.Invalidator
+	access      *Access
+	logger      *zap.Logger
+}
+
+func NewCacheAdminHandler(cache *cache.Cache, invalidator *cache.Invalidator, access *Access, logger *zap.Logger) *CacheAdminHandler {
+	return &CacheAdminHandler{cache: cache, invalidator: invalidator, access: access, logger: logger}
+}
+
+func (h *CacheAdminHandler) Register(group *gin.RouterGroup) {
+	read := group.Group("", h.access.Require(models.PermissionCacheRead))
+	read.GET("/cache/stats", h.stats)
+
+	manage := group.Group("", h.access.Require(models.PermissionCacheManage))
+	manage.POST("/cache/flush", h.flush)
+}
+
+func (h *CacheAdminHandler) stats(c *gin.Context) {
+	respondOK(c, h.cache.Stats())
+}
+
+// flush publishes an invalidation so every replica drops the matching
+// entries. The body is optional; without one all cached reads go.
+func (h *CacheAdminHandler) flush(c *gin.Context) {
+	var inv cache.Invalidation
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&inv); err != nil {
+			respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, "invalid request body")
+			return
+		}
+	}
+	if len(inv.Prefixes) == 0 {
+		inv.Prefixes = flushablePrefixes
+	}
+
+	h.invalidator.Publish(inv)
+	h.logger.Info("cache flush requested",
+		zap.Strings("prefixes", inv.Prefixes),
+		zap.String("patch", inv.Patch),
+		zap.String("region", inv.Region),
+		zap.String("tier", inv.Tier),
+	)
+	c.JSON(http.StatusAccepted, models.NewSuccessResponse(inv))
+}
@@ -0,0 +1,231 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rsdlab-dk/tft-api/internal/jobs"
+	"github.com/rsdlab-dk/tft-api/internal/models"
+	"github.com/rsdlab-dk/tft-api/internal/repository"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultDeadLetterLimit = 50
+	maxDeadLetterLimit     = 500
+)
+
+type JobsAdminHandler struct {
+	queue  *jobs.Queue
+	repo   *repository.JobRepository
+	access *Access
+	logger *zap.Logger
+}
+
+func NewJobsAdminHandler(queue *jobs.Queue, repo *repository.JobRepository, access *Access, logger *zap.Logger) *JobsAdminHandler {
+	return &JobsAdminHandler{queue: queue, repo: repo, access: access, logger: logger}
+}
+
+func (h *JobsAdminHandler) Register(group *gin.RouterGroup) {
+	read := group.Group("", h.access.Require(models.PermissionJobsRead))
+	read.GET("/jobs", h.listJobs)
+	read.GET("/jobs/summary", h.summary)
+	read.GET("/jobs/:id", h.getJob)
+	read.GET("/jobs/dead-letters", h.listDeadLetters)
+
+	manage := group.Group("", h.access.Require(models.PermissionJobsManage))
+	manage.POST("/jobs/:id/rerun", h.rerunJob)
+	manage.POST("/jobs/:id/cancel", h.cancelJob)
+	manage.POST("/jobs/dead-letters/replay", h.replayDeadLetters)
+	manage.POST("/jobs/dead-letters/:sequence/replay", h.replayDeadLetter)
+}
+
+func (h *JobsAdminHandler) listJobs(c *gin.Context) {
+	var filters models.JobExecutionFilters
+	if err := c.ShouldBindQuery(&filters); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, "invalid query parameters")
+		return
+	}
+	filters.SetDefaults()
+	if err := filters.Validate(); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, err.Error())
+		return
+	}
+
+	executions, total, err := h.repo.List(c.Request.Context(), filters)
+	if err != nil {
+		h.logger.Error("failed to list jobs", zap.Error(err))
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "failed to list jobs")
+		return
+	}
+
+	page := filters.Offset/filters.Limit + 1
+	c.JSON(http.StatusOK, models.NewPaginatedResponse(executions, models.NewPaginationMeta(page, filters.Limit, total)))
+}
+
+// summary reports the latest outcome per job type, including types that
+// have never been enqueued.
+func (h *JobsAdminHandler) summary(c *gin.Context) {
+	summaries, err := h.repo.Summaries(c.Request.Context())
+	if err != nil {
+		h.logger.Error("failed to summarize jobs", zap.Error(err))
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "failed to summarize jobs")
+		return
+	}
+
+	respondOK(c, summaries)
+}
+
+func (h *JobsAdminHandler) getJob(c *gin.Context) {
+	jobID, ok := jobIDParam(c)
+	if !ok {
+		return
+	}
+
+	executions, err := h.repo.History(c.Request.Context(), jobID)
+	if errors.Is(err, repository.ErrJobNotFound) {
+		respondError(c, http.StatusNotFound, ErrCodeNotFound, "job not found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to load job", zap.String("job_id", jobID), zap.Error(err))
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "failed to load job")
+		return
+	}
+
+	respondOK(c, executions)
+}
+
+func (h *JobsAdminHandler) rerunJob(c *gin.Context) {
+	jobID, ok := jobIDParam(c)
+	if !ok {
+		return
+	}
+
+	job, err := h.queue.Rerun(c.Request.Context(), jobID)
+	if errors.Is(err, repository.ErrJobNotFound) {
+		respondError(c, http.StatusNotFound, ErrCodeNotFound, "job not found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to rerun job", zap.String("job_id", jobID), zap.Error(err))
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "failed to rerun job")
+		return
+	}
+
+	h.logger.Info("job rerun", zap.String("job_id", jobID), zap.String("new_job_id", job.ID))
+	respondOK(c, job)
+}
+
+func (h *JobsAdminHandler) cancelJob(c *gin.Context) {
+	jobID, ok := jobIDParam(c)
+	if !ok {
+		return
+	}
+
+	status, err := h.queue.Cancel(c.Request.Context(), jobID)
+	switch {
+	case errors.Is(err, repository.ErrJobNotFound):
+		respondError(c, http.StatusNotFound, ErrCodeNotFound, "job not found")
+		return
+	case errors.Is(err, repository.ErrJobNotPending):
+		respondError(c, http.StatusConflict, ErrCodeConflict, "job already finished with status "+status.String())
+		return
+	case err != nil:
+		h.logger.Error("failed to cancel job", zap.String("job_id", jobID), zap.Error(err))
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "failed to cancel job")
+		return
+	}
+
+	h.logger.Info("job cancel requested", zap.String("job_id", jobID), zap.String("status", status.String()))
+	respondOK(c, gin.H{"job_id": jobID, "previous_status": status})
+}
+
+func (h *JobsAdminHandler) listDeadLetters(c *gin.Context) {
+	jobType, limit, ok := deadLetterQuery(c)
+	if !ok {
+		return
+	}
+
+	letters, err := h.queue.ListDeadLetters(c.Request.Context(), jobType, limit)
+	if err != nil {
+		h.logger.Error("failed to list dead letters", zap.Error(err))
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "failed to list dead-lettered jobs")
+		return
+	}
+
+	respondOK(c, letters)
+}
+
+// replayDeadLetters re-enqueues every dead-lettered job matching the type
+// and limit query parameters.
+func (h *JobsAdminHandler) replayDeadLetters(c *gin.Context) {
+	jobType, limit, ok := deadLetterQuery(c)
+	if !ok {
+		return
+	}
+
+	replayed, err := h.queue.ReplayDeadLetters(c.Request.Context(), jobType, limit)
+	if err != nil {
+		h.logger.Error("failed to replay dead letters", zap.Int("replayed", len(replayed)), zap.Error(err))
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "failed to replay dead-lettered jobs")
+		return
+	}
+
+	h.logger.Info("replayed dead-lettered jobs", zap.Int("count", len(replayed)))
+	respondOK(c, replayed)
+}
+
+func (h *JobsAdminHandler) replayDeadLetter(c *gin.Context) {
+	sequence, err := strconv.ParseUint(c.Param("sequence"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, "sequence must be a positive integer")
+		return
+	}
+
+	job, err := h.queue.ReplayDeadLetter(c.Request.Context(), sequence)
+	if errors.Is(err, jobs.ErrDeadLetterNotFound) {
+		respondError(c, http.StatusNotFound, ErrCodeNotFound, "dead-lettered job not found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to replay dead letter", zap.Uint64("sequence", sequence), zap.Error(err))
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "failed to replay dead-lettered job")
+		return
+	}
+
+	h.logger.Info("replayed dead-lettered job", zap.String("job_id", job.ID), zap.String("job_type", job.Type.String()))
+	respondOK(c, job)
+}
+
+func jobIDParam(c *gin.Context) (string, bool) {
+	jobID := c.Param("id")
+	if _, err := uuid.Parse(jobID); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, "job id must be a UUID")
+		return "", false
+	}
+	return jobID, true
+}
+
+func deadLetterQuery(c *gin.Context) (models.JobType, int, bool) {
+	jobType := models.JobType(c.Query("type"))
+	if jobType != "" && !jobType.IsValid() {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, "unknown job type")
+		return "", 0, false
+	}
+
+	limit := defaultDeadLetterLimit
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxDeadLetterLimit {
+			respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, "limit must be between 1 and 500")
+			return "", 0, false
+		}
+		limit = n
+	}
+
+	return jobType, limit, true
+}
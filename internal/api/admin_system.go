@@ -0,0 +1,35 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rsdlab-dk/tft-api/internal/models"
+	"github.com/rsdlab-dk/tft-api/internal/repository"
+	"go.uber.org/zap"
+)
+
+type SystemAdminHandler struct {
+	migrations *repository.MigrationRepository
+	access     *Access
+	logger     *zap.Logger
+}
+
+func NewSystemAdminHandler(migrations *repository.MigrationRepository, access *Access, logger *zap.Logger) *SystemAdminHandler {
+	return &SystemAdminHandler{migrations: migrations, access: access, logger: logger}
+}
+
+func (h *SystemAdminHandler) Register(group *gin.RouterGroup) {
+	read := group.Group("", h.access.Require(models.PermissionSystemRead))
+	read.GET("/migrations", h.migrationStatus)
+}
+
+func (h *SystemAdminHandler) migrationStatus(c *gin.Context) {
+	status, err := h.migrations.Status(c.Request.Context())
+	if err != nil {
+		h.logger.Error("failed to get migration status", zap.Error(err))
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "failed to get migration status")
+		return
+	}
+	respondOK(c, status)
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rsdlab-dk/tft-api/internal/auth"
+	"github.com/rsdlab-dk/tft-api/internal/models"
+	"github.com/rsdlab-dk/tft-api/internal/repository"
+	"go.uber.org/zap"
+)
+
+type UsersAdminHandler struct {
+	service *auth.Service
+	access  *Access
+	logger  *zap.Logger
+}
+
+func NewUsersAdminHandler(service *auth.Service, access *Access, logger *zap.Logger) *UsersAdminHandler {
+	return &UsersAdminHandler{service: service, access: access, logger: logger}
+}
+
+func (h *UsersAdminHandler) Register(group *gin.RouterGroup) {
+	manage := group.Group("", h.access.Require(models.PermissionUsersManage))
+	manage.PUT("/users/:id/role", h.setRole)
+}
+
+func (h *UsersAdminHandler) setRole(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, "invalid user id")
+		return
+	}
+
+	var req models.UpdateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, "invalid request body")
+		return
+	}
+
+	err := h.service.SetRole(c.Request.Context(), id, req)
+	var validationErr *models.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, err.Error())
+		return
+	case errors.Is(err, repository.ErrUserNotFound):
+		respondError(c, http.StatusNotFound, ErrCodeNotFound, err.Error())
+		return
+	case err != nil:
+		h.logger.Error("failed to set user role", zap.String("user_id", id), zap.Error(err))
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "failed to set role")
+		return
+	}
+
+	principal := principalOf(c)
+	h.logger.Info("user role changed",
+		zap.String("user_id", id),
+		zap.String("role", req.Role.String()),
+		zap.String("by_user_id", principal.UserID),
+		zap.String("by_api_key_id", principal.APIKeyID),
+	)
+	respondOK(c, gin.H{"id": id, "role": req.Role})
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rsdlab-dk/tft-api/internal/auth"
+	"github.com/rsdlab-dk/tft-api/internal/models"
+	"go.uber.org/zap"
+)
+
+const (
+	APIKeyHeader = "X-API-Key"
+
+	apiKeyContextKey = "auth.api_key"
+)
+
+// APIKeyAuth holds requests made with an API key to the key's scopes and
+// quota, and sets the X-RateLimit-* headers on them.
+type APIKeyAuth struct {
+	keys    *auth.APIKeys
+	limiter *auth.RateLimiter
+	usage   *auth.Usage
+	logger  *zap.Logger
+}
+
+func NewAPIKeyAuth(keys *auth.APIKeys, limiter *auth.RateLimiter, usage *auth.Usage, logger *zap.Logger) *APIKeyAuth {
+	return &APIKeyAuth{keys: keys, limiter: limiter, usage: usage, logger: logger}
+}
+
+// Optional lets requests without a key through, so public reads keep
+// working for the website, and checks the ones that send a key.
+func (a *APIKeyAuth) Optional(scope models.Scope) gin.HandlerFunc {
+	return a.check(scope, false)
+}
+
+// Require rejects requests without a key.
+func (a *APIKeyAuth) Require(scope models.Scope) gin.HandlerFunc {
+	return a.check(scope, true)
+}
+
+func (a *APIKeyAuth) check(scope models.Scope, required bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader(APIKeyHeader) == "" {
+			if required {
+				respondError(c, http.StatusUnauthorized, ErrCodeUnauthorized, "missing api key")
+				return
+			}
+			c.Next()
+			return
+		}
+
+		key, ok := a.authenticate(c, scope)
+		if !ok {
+			return
+		}
+
+		c.Set(apiKeyContextKey, key)
+		c.Next()
+	}
+}
+
+// authenticate verifies the key sent with c and charges the request to
+// its quota. It responds and returns false if the key is not valid, lacks
+// scope or is over its quota. An empty scope skips the scope check, for
+// routes that go by the key's role instead.
+func (a *APIKeyAuth) authenticate(c *gin.Context, scope models.Scope) (*models.APIKey, bool) {
+	key, err := a.keys.Authenticate(c.Request.Context(), c.GetHeader(APIKeyHeader))
+	if errors.Is(err, auth.ErrInvalidAPIKey) {
+		respondError(c, http.StatusUnauthorized, ErrCodeUnauthorized, err.Error())
+		return nil, false
+	}
+	if err != nil {
+		a.logger.Error("failed to authenticate api key", zap.Error(err))
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "failed to authenticate")
+		return nil, false
+	}
+
+	if scope != "" && !key.HasScope(scope) {
+		respondError(c, http.StatusForbidden, ErrCodeForbidden, fmt.Sprintf("api key lacks the %s scope", scope))
+		return nil, false
+	}
+
+	quota, err := a.limiter.Allow(c.Request.Context(), key)
+	if err != nil {
+		a.logger.Error("failed to check api key quota", zap.String("api_key_id", key.ID), zap.Error(err))
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "failed to check quota")
+		return nil, false
+	}
+	setRateLimitHeaders(c, quota)
+	a.usage.Record(key.ID, !quota.Allowed)
+
+	if !quota.Allowed {
+		c.Header("Retry-After", strconv.Itoa(int(time.Until(quota.Reset).Seconds())+1))
+		respondError(c, http.StatusTooManyRequests, ErrCodeRateLimited, "api key quota exceeded")
+		return nil, false
+	}
+	return key, true
+}
+
+func setRateLimitHeaders(c *gin.Context, quota *auth.Quota) {
+	c.Header("X-RateLimit-Limit", strconv.Itoa(quota.Limit))
+	// Remaining is unknown while only the local limit applies.
+	if quota.Remaining >= 0 {
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(quota.Remaining))
+	}
+	c.Header("X-RateLimit-Reset", strconv.FormatInt(quota.Reset.Unix(), 10))
+}
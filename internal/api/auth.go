@@ -0,0 +1,222 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rsdlab-dk/tft-api/internal/auth"
+	"github.com/rsdlab-dk/tft-api/internal/models"
+	"github.com/rsdlab-dk/tft-api/internal/repository"
+	"go.uber.org/zap"
+)
+
+const claimsContextKey = "auth.claims"
+
+type AuthHandler struct {
+	service *auth.Service
+	logger  *zap.Logger
+}
+
+func NewAuthHandler(service *auth.Service, logger *zap.Logger) *AuthHandler {
+	return &AuthHandler{service: service, logger: logger}
+}
+
+func (h *AuthHandler) Register(group *gin.RouterGroup) {
+	group.POST("/auth/register", h.register)
+	group.POST("/auth/login", h.login)
+	group.POST("/auth/refresh", h.refresh)
+	group.GET("/auth/me", h.RequireAuth(), h.me)
+
+	sessions := group.Group("/auth", h.RequireAuth())
+	sessions.POST("/logout", h.logout)
+	sessions.POST("/logout-all", h.logoutAll)
+	sessions.GET("/sessions", h.listSessions)
+	sessions.DELETE("/sessions/:id", h.endSession)
+}
+
+// RequireAuth rejects requests without a valid bearer access token and
+// stores its claims for the handlers.
+func (h *AuthHandler) RequireAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := h.authenticate(c)
+		if !ok {
+			return
+		}
+
+		c.Set(claimsContextKey, claims)
+		c.Next()
+	}
+}
+
+// authenticate verifies the bearer access token of c. It responds and
+// returns false if there is none or it is not valid.
+func (h *AuthHandler) authenticate(c *gin.Context) (*auth.Claims, bool) {
+	token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		respondError(c, http.StatusUnauthorized, ErrCodeUnauthorized, "missing bearer token")
+		return nil, false
+	}
+
+	claims, err := h.service.Authenticate(c.Request.Context(), token)
+	switch {
+	case errors.Is(err, auth.ErrInvalidToken), errors.Is(err, auth.ErrSessionExpired):
+		respondError(c, http.StatusUnauthorized, ErrCodeUnauthorized, err.Error())
+		return nil, false
+	case err != nil:
+		h.logger.Error("failed to authenticate request", zap.Error(err))
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "failed to authenticate")
+		return nil, false
+	}
+	return claims, true
+}
+
+// claimsOf returns the claims stored by RequireAuth.
+func claimsOf(c *gin.Context) *auth.Claims {
+	claims, _ := c.Get(claimsContextKey)
+	return claims.(*auth.Claims)
+}
+
+func (h *AuthHandler) register(c *gin.Context) {
+	var req models.RegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, "invalid request body")
+		return
+	}
+
+	user, err := h.service.Register(c.Request.Context(), req)
+	var validationErr *models.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, err.Error())
+		return
+	case errors.Is(err, repository.ErrUserExists):
+		respondError(c, http.StatusConflict, ErrCodeConflict, err.Error())
+		return
+	case err != nil:
+		h.logger.Error("failed to register user", zap.Error(err))
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "failed to register")
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.NewSuccessResponse(user))
+}
+
+func (h *AuthHandler) login(c *gin.Context) {
+	var req models.LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, "invalid request body")
+		return
+	}
+	req.UserAgent = c.Request.UserAgent()
+	req.IP = c.ClientIP()
+
+	tokens, _, err := h.service.Login(c.Request.Context(), req)
+	switch {
+	case errors.Is(err, auth.ErrInvalidCredentials):
+		respondError(c, http.StatusUnauthorized, ErrCodeUnauthorized, err.Error())
+		return
+	case err != nil:
+		h.logger.Error("failed to log in", zap.Error(err))
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "failed to log in")
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	respondOK(c, tokens)
+}
+
+func (h *AuthHandler) refresh(c *gin.Context) {
+	var req models.RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.RefreshToken == "" {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, "invalid request body")
+		return
+	}
+
+	tokens, err := h.service.Refresh(c.Request.Context(), req.RefreshToken)
+	switch {
+	case errors.Is(err, auth.ErrInvalidToken), errors.Is(err, auth.ErrSessionExpired),
+		errors.Is(err, auth.ErrRefreshTokenReused):
+		respondError(c, http.StatusUnauthorized, ErrCodeUnauthorized, err.Error())
+		return
+	case err != nil:
+		h.logger.Error("failed to refresh tokens", zap.Error(err))
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "failed to refresh tokens")
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	respondOK(c, tokens)
+}
+
+func (h *AuthHandler) me(c *gin.Context) {
+	user, err := h.service.User(c.Request.Context(), claimsOf(c).UserID())
+	if errors.Is(err, repository.ErrUserNotFound) {
+		respondError(c, http.StatusNotFound, ErrCodeNotFound, "user not found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to get user", zap.Error(err))
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "failed to get user")
+		return
+	}
+
+	respondOK(c, user)
+}
+
+func (h *AuthHandler) logout(c *gin.Context) {
+	claims := claimsOf(c)
+	// The session may have expired since RequireAuth touched it; the
+	// caller is logged out either way.
+	if err := h.service.Logout(c.Request.Context(), claims); err != nil && !errors.Is(err, auth.ErrSessionNotFound) {
+		h.logger.Error("failed to log out", zap.String("user_id", claims.UserID()), zap.Error(err))
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "failed to log out")
+		return
+	}
+
+	respondOK(c, gin.H{"session_id": claims.SessionID, "ended": true})
+}
+
+func (h *AuthHandler) logoutAll(c *gin.Context) {
+	userID := claimsOf(c).UserID()
+	ended, err := h.service.LogoutAll(c.Request.Context(), userID)
+	if err != nil {
+		h.logger.Error("failed to log out all sessions", zap.String("user_id", userID), zap.Error(err))
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "failed to log out")
+		return
+	}
+
+	h.logger.Info("all sessions ended", zap.String("user_id", userID), zap.Int("sessions", ended))
+	respondOK(c, gin.H{"ended": ended})
+}
+
+func (h *AuthHandler) listSessions(c *gin.Context) {
+	claims := claimsOf(c)
+	sessions, err := h.service.Sessions(c.Request.Context(), claims)
+	if err != nil {
+		h.logger.Error("failed to list sessions", zap.String("user_id", claims.UserID()), zap.Error(err))
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "failed to list sessions")
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	respondOK(c, sessions)
+}
+
+func (h *AuthHandler) endSession(c *gin.Context) {
+	userID := claimsOf(c).UserID()
+	sessionID := c.Param("id")
+	err := h.service.EndSession(c.Request.Context(), userID, sessionID)
+	if errors.Is(err, auth.ErrSessionNotFound) {
+		respondError(c, http.StatusNotFound, ErrCodeNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to end session", zap.String("user_id", userID), zap.Error(err))
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "failed to end session")
+		return
+	}
+
+	respondOK(c, gin.H{"session_id": sessionID, "ended": true})
+}
@@ -0,0 +1,174 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rsdlab-dk/tft-api/internal/cache"
+	"github.com/rsdlab-dk/tft-api/internal/models"
+	"github.com/rsdlab-dk/tft-api/internal/patches"
+	"github.com/rsdlab-dk/tft-api/internal/repository"
+	"go.uber.org/zap"
+)
+
+// CompositionsHandler serves the public leaderboard and meta reads. Every
+// read goes through cache.Fetch, so concurrent misses share one query and
+// expired entries are served stale while they refresh. Responses carry
+// validators and Cache-Control matching the cache TTLs of their prefix.
+// Successful reads are recorded in popularity for the warm-up job. The
+// reads are public; requests with an API key need the matching scope.
+type CompositionsHandler struct {
+	comps      *repository.CompositionRepository
+	analytics  *repository.AnalyticsRepository
+	patches    *patches.Registry
+	cache      *cache.Cache
+	popularity *cache.Popularity
+	apiKeys    *APIKeyAuth
+	logger     *zap.Logger
+}
+
+func NewCompositionsHandler(comps *repository.CompositionRepository, analytics *repository.AnalyticsRepository, registry *patches.Registry, c *cache.Cache, popularity *cache.Popularity, apiKeys *APIKeyAuth, logger *zap.Logger) *CompositionsHandler {
+	return &CompositionsHandler{
+		comps:      comps,
+		analytics:  analytics,
+		patches:    registry,
+		cache:      c,
+		popularity: popularity,
+		apiKeys:    apiKeys,
+		logger:     logger,
+	}
+}
+
+func (h *CompositionsHandler) Register(group *gin.RouterGroup) {
+	compositions := h.apiKeys.Optional(models.ScopeReadCompositions)
+	group.GET("/compositions", compositions, h.conditional(models.CacheKeyLeaderboard), h.listCompositions)
+	group.GET("/compositions/:hash", compositions, h.conditional(models.CacheKeyCompositionDetail), h.getComposition)
+	group.GET("/meta", h.apiKeys.Optional(models.ScopeReadMeta), h.conditional(models.CacheKeyMeta), h.getMeta)
+}
+
+func (h *CompositionsHandler) conditional(prefix string) gin.HandlerFunc {
+	return conditional(h.cache.TTL(models.NewCacheKey(prefix)), h.cache.StaleTTL())
+}
+
+func (h *CompositionsHandler) listCompositions(c *gin.Context) {
+	var filters models.CompositionFilters
+	if err := c.ShouldBindQuery(&filters); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, "invalid query parameters")
+		return
+	}
+	popular := filters
+	filters.SetDefaults(h.patches.CurrentPatch())
+	if filters.Patch == "" {
+		respondNoPatch(c)
+		return
+	}
+	if err := filters.Validate(); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, err.Error())
+		return
+	}
+
+	var page models.CompositionPage
+	err := h.cache.Fetch(c.Request.Context(), filters.CacheKey(), &page, func(ctx context.Context) (interface{}, error) {
+		return h.comps.Page(ctx, filters)
+	})
+	if err != nil {
+		h.logger.Error("failed to list compositions", zap.Error(err))
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "failed to list compositions")
+		return
+	}
+	// Recorded as requested, so reads of the current patch keep following
+	// it after a patch change.
+	h.popularity.Record(models.CacheKeyLeaderboard, popular)
+
+	setContentValidators(c, page, newestUpdate(time.Time{}, page.Compositions))
+
+	meta := models.NewPaginationMeta(filters.Offset/filters.Limit+1, filters.Limit, page.Total)
+	c.JSON(http.StatusOK, models.NewPaginatedResponse(page.Compositions, meta))
+}
+
+// respondNoPatch answers requests for the current patch while none is
+// known, rather than querying and caching an empty page under their key.
+func respondNoPatch(c *gin.Context) {
+	respondError(c, http.StatusServiceUnavailable, ErrCodeUnavailable, "no patch registered yet; pass patch explicitly")
+}
+
+func (h *CompositionsHandler) getComposition(c *gin.Context) {
+	hash := c.Param("hash")
+	if len(hash) != 64 {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, "invalid composition hash")
+		return
+	}
+
+	var comp models.TeamComposition
+	key := models.NewCacheKey(models.CacheKeyCompositionDetail, hash)
+	err := h.cache.Fetch(c.Request.Context(), key, &comp, func(ctx context.Context) (interface{}, error) {
+		return h.comps.GetByHash(ctx, hash)
+	})
+	if errors.Is(err, repository.ErrCompositionNotFound) {
+		respondError(c, http.StatusNotFound, ErrCodeNotFound, "composition not found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to get composition", zap.String("comp_hash", hash), zap.Error(err))
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "failed to get composition")
+		return
+	}
+	h.popularity.Record(models.CacheKeyCompositionDetail, hash)
+
+	// tier_rank and pick_rate move with the rest of the leaderboard, so
+	// last_updated alone does not version the payload; the ETag does.
+	setContentValidators(c, comp, comp.LastUpdated)
+	respondOK(c, comp)
+}
+
+func (h *CompositionsHandler) getMeta(c *gin.Context) {
+	var filters models.MetaFilters
+	if err := c.ShouldBindQuery(&filters); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, "invalid query parameters")
+		return
+	}
+	popular := filters
+	filters.SetDefaults(h.patches.CurrentPatch())
+	if filters.Patch == "" {
+		respondNoPatch(c)
+		return
+	}
+	if err := filters.Validate(); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, err.Error())
+		return
+	}
+
+	var snapshot models.MetaSnapshot
+	err := h.cache.Fetch(c.Request.Context(), filters.CacheKey(), &snapshot, func(ctx context.Context) (interface{}, error) {
+		return h.analytics.CurrentMeta(ctx, filters)
+	})
+	if errors.Is(err, repository.ErrMetaNotFound) {
+		respondError(c, http.StatusNotFound, ErrCodeNotFound, "no meta computed for this bucket yet")
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to get meta", zap.Error(err))
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "failed to get meta")
+		return
+	}
+	h.popularity.Record(models.CacheKeyMeta, popular)
+
+	setContentValidators(c, snapshot, newestUpdate(snapshot.LastUpdated,
+		snapshot.STierComps, snapshot.ATierComps, snapshot.BTierComps))
+	respondOK(c, snapshot)
+}
+
+// newestUpdate returns the latest of since and the last_updated of comps.
+func newestUpdate(since time.Time, comps ...[]models.TeamComposition) time.Time {
+	for _, list := range comps {
+		for _, comp := range list {
+			if comp.LastUpdated.After(since) {
+				since = comp.LastUpdated
+			}
+		}
+	}
+	return since
+}
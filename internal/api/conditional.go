@@ -0,0 +1,164 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const validatorsContextKey = "api.validators"
+
+// validators identify the representation a handler is about to send.
+// lastModified is only as precise as the timestamps in the payload, so
+// the ETag is what versions it and wins whenever a client sends both.
+type validators struct {
+	etag         string
+	lastModified time.Time
+}
+
+// setContentValidators derives a strong ETag from data, the payload inside
+// the response envelope; the envelope itself carries a timestamp and would
+// never match. A zero lastModified omits the Last-Modified header.
+func setContentValidators(c *gin.Context, data interface{}, lastModified time.Time) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+	c.Set(validatorsContextKey, &validators{etag: strongETag(raw), lastModified: lastModified})
+}
+
+func strongETag(data []byte) string {
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// conditional answers If-None-Match and If-Modified-Since with 304 Not
+// Modified and marks
+// successful responses cacheable for ttl, so browsers and the CDN
+// revalidate instead of downloading again. Responses to requests with an
+// API key carry that key's X-RateLimit-* headers and are private, so a
+// shared cache never hands one key's quota to another client. Responses
+// are buffered until the handler returns; handlers should set validators,
+// otherwise the ETag is computed from the whole body.
+func conditional(ttl, stale time.Duration) gin.HandlerFunc {
+	directives := fmt.Sprintf("max-age=%d", int(ttl.Seconds()))
+	if stale > 0 {
+		directives += fmt.Sprintf(", stale-while-revalidate=%d", int(stale.Seconds()))
+	}
+
+	return func(c *gin.Context) {
+		w := &bufferedWriter{ResponseWriter: c.Writer, status: http.StatusOK}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+
+		header := w.Header()
+		header.Add("Vary", APIKeyHeader)
+		if w.status != http.StatusOK {
+			header.Set("Cache-Control", "no-store")
+			w.flush()
+			return
+		}
+
+		v, _ := c.Get(validatorsContextKey)
+		val, ok := v.(*validators)
+		if !ok {
+			val = &validators{etag: strongETag(w.body.Bytes())}
+		}
+
+		header.Set("ETag", val.etag)
+		if !val.lastModified.IsZero() {
+			header.Set("Last-Modified", val.lastModified.UTC().Format(http.TimeFormat))
+		}
+		if c.GetHeader(APIKeyHeader) != "" {
+			header.Set("Cache-Control", "private, "+directives)
+		} else {
+			header.Set("Cache-Control", "public, "+directives)
+		}
+
+		if notModified(c.Request, val) {
+			header.Del("Content-Type")
+			header.Del("Content-Length")
+			w.ResponseWriter.WriteHeader(http.StatusNotModified)
+			w.ResponseWriter.WriteHeaderNow()
+			return
+		}
+		w.flush()
+	}
+}
+
+// notModified follows RFC 9110: If-None-Match uses weak comparison, and
+// If-Modified-Since is only evaluated when If-None-Match is absent.
+func notModified(r *http.Request, val *validators) bool {
+	inm := r.Header.Get("If-None-Match")
+	if inm == "" {
+		return notModifiedSince(r, val)
+	}
+	for _, tag := range strings.Split(inm, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == val.etag {
+			return true
+		}
+	}
+	return false
+}
+
+// notModifiedSince compares at the one second resolution of HTTP dates and
+// ignores dates it cannot parse.
+func notModifiedSince(r *http.Request, val *validators) bool {
+	ims := r.Header.Get("If-Modified-Since")
+	if ims == "" || val.lastModified.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+	return !val.lastModified.Truncate(time.Second).After(since)
+}
+
+// bufferedWriter holds the status and body back until conditional has
+// decided between the full response and 304.
+type bufferedWriter struct {
+	gin.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bufferedWriter) WriteHeader(code int) {
+	w.status = code
+}
+
+func (w *bufferedWriter) WriteHeaderNow() {}
+
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+func (w *bufferedWriter) Status() int {
+	return w.status
+}
+
+func (w *bufferedWriter) Size() int {
+	return w.body.Len()
+}
+
+func (w *bufferedWriter) Written() bool {
+	return w.body.Len() > 0
+}
+
+func (w *bufferedWriter) flush() {
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.Write(w.body.Bytes())
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var conditionalLastUpdated = time.Date(2025, 6, 1, 12, 30, 15, 500_000_000, time.UTC)
+
+func newConditionalEngine() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET("/meta", conditional(time.Minute, 0), func(c *gin.Context) {
+		data := gin.H{"patch": "15.1"}
+		setContentValidators(c, data, conditionalLastUpdated)
+		respondOK(c, data)
+	})
+	return engine
+}
+
+func TestConditionalRevalidatesByETag(t *testing.T) {
+	engine := newConditionalEngine()
+
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/meta", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	etag := rec.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+	assert.Equal(t, "public, max-age=60", rec.Header().Get("Cache-Control"))
+	assert.Equal(t, "Sun, 01 Jun 2025 12:30:15 GMT", rec.Header().Get("Last-Modified"))
+
+	req := httptest.NewRequest(http.MethodGet, "/meta", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Empty(t, rec.Body.Bytes())
+}
+
+func TestConditionalRevalidatesByLastModified(t *testing.T) {
+	engine := newConditionalEngine()
+
+	req := httptest.NewRequest(http.MethodGet, "/meta", nil)
+	req.Header.Set("If-Modified-Since", conditionalLastUpdated.Format(http.TimeFormat))
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Empty(t, rec.Body.Bytes())
+
+	req = httptest.NewRequest(http.MethodGet, "/meta", nil)
+	req.Header.Set("If-Modified-Since", conditionalLastUpdated.Add(-time.Minute).Format(http.TimeFormat))
+	rec = httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotEmpty(t, rec.Body.Bytes())
+}
+
+func TestConditionalIgnoresModifiedSinceWithETag(t *testing.T) {
+	engine := newConditionalEngine()
+
+	req := httptest.NewRequest(http.MethodGet, "/meta", nil)
+	req.Header.Set("If-None-Match", `"stale"`)
+	req.Header.Set("If-Modified-Since", conditionalLastUpdated.Format(http.TimeFormat))
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotEmpty(t, rec.Body.Bytes())
+}
+
+func TestConditionalKeepsAPIKeyResponsesPrivate(t *testing.T) {
+	engine := newConditionalEngine()
+
+	req := httptest.NewRequest(http.MethodGet, "/meta", nil)
+	req.Header.Set(APIKeyHeader, "tft_test")
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "private, max-age=60", rec.Header().Get("Cache-Control"))
+	assert.Contains(t, rec.Header().Values("Vary"), APIKeyHeader)
+}
@@ -0,0 +1,322 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/gorilla/websocket"
+	"github.com/nats-io/nats.go"
+	"github.com/rsdlab-dk/tft-api/internal/jobs"
+	"github.com/rsdlab-dk/tft-api/internal/models"
+	"go.uber.org/zap"
+)
+
+const (
+	progressWriteWait  = 10 * time.Second
+	progressPongWait   = 60 * time.Second
+	progressPingPeriod = progressPongWait * 9 / 10
+	progressSendBuffer = 64
+
+	// Finished attempts stay in the snapshot for a while so a dashboard
+	// opened just after a job ended still shows the outcome. Attempts that
+	// stopped reporting, e.g. because their worker died, expire later.
+	progressFinishedTTL = 5 * time.Minute
+	progressStaleTTL    = time.Hour
+)
+
+// ProgressRelay forwards job progress events from NATS to WebSocket
+// clients. It keeps the latest event of every recent attempt so new
+// clients start from the current pipeline state instead of a blank page.
+type ProgressRelay struct {
+	nc       *nats.Conn
+	access   *Access
+	tickets  *progressTickets
+	logger   *zap.Logger
+	upgrader websocket.Upgrader
+
+	mu      sync.Mutex
+	latest  map[string]jobs.Progress
+	clients map[*progressClient]struct{}
+	closed  bool
+}
+
+type progressClient struct {
+	jobType models.JobType
+	region  models.Region
+	send    chan []byte
+}
+
+func (c *progressClient) wants(p jobs.Progress) bool {
+	return (c.jobType == "" || c.jobType == p.Type) && (c.region == "" || c.region == p.Region)
+}
+
+// NewProgressRelay accepts WebSocket upgrades from the API's own host and
+// from allowedOrigins.
+func NewProgressRelay(nc *nats.Conn, access *Access, client *redis.Client, allowedOrigins []string, logger *zap.Logger) *ProgressRelay {
+	return &ProgressRelay{
+		nc:      nc,
+		access:  access,
+		tickets: &progressTickets{client: client},
+		logger:  logger,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 4096,
+			CheckOrigin:     checkOrigin(allowedOrigins),
+		},
+		latest:  make(map[string]jobs.Progress),
+		clients: make(map[*progressClient]struct{}),
+	}
+}
+
+// Register adds the snapshot and ticket routes to the authenticated admin
+// group.
+func (r *ProgressRelay) Register(group *gin.RouterGroup) {
+	read := group.Group("", r.access.Require(models.PermissionJobsRead))
+	read.GET("/jobs/progress", r.snapshot)
+	read.POST("/jobs/progress/ticket", r.issueTicket)
+}
+
+// RegisterStream adds the WebSocket route to group, which must not run
+// Access.Authenticate: the route authenticates with a ticket, or with the
+// usual credentials for clients that can send headers.
+func (r *ProgressRelay) RegisterStream(group *gin.RouterGroup) {
+	group.GET("/jobs/progress/ws", r.authenticateStream(), r.access.Require(models.PermissionJobsRead), r.stream)
+}
+
+func (r *ProgressRelay) authenticateStream() gin.HandlerFunc {
+	authenticate := r.access.Authenticate()
+	return func(c *gin.Context) {
+		ticket := c.Query("ticket")
+		if ticket == "" {
+			authenticate(c)
+			return
+		}
+
+		principal, err := r.tickets.redeem(c.Request.Context(), ticket)
+		if errors.Is(err, errInvalidProgressTicket) {
+			respondError(c, http.StatusUnauthorized, ErrCodeUnauthorized, err.Error())
+			return
+		}
+		if err != nil {
+			r.logger.Error("failed to redeem progress ticket", zap.Error(err))
+			respondError(c, http.StatusInternalServerError, ErrCodeInternal, "failed to authenticate")
+			return
+		}
+		c.Set(principalContextKey, principal)
+		c.Next()
+	}
+}
+
+// issueTicket returns a ticket that authenticates one progress WebSocket
+// as the caller.
+func (r *ProgressRelay) issueTicket(c *gin.Context) {
+	ticket, err := r.tickets.issue(c.Request.Context(), principalOf(c))
+	if err != nil {
+		r.logger.Error("failed to issue progress ticket", zap.Error(err))
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "failed to issue ticket")
+		return
+	}
+	respondOK(c, ticket)
+}
+
+// Run relays progress events until ctx is done, then disconnects all
+// clients.
+func (r *ProgressRelay) Run(ctx context.Context) error {
+	sub, err := r.nc.Subscribe(jobs.ProgressSubjectPrefix+".>", r.receive)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to job progress: %w", err)
+	}
+	defer sub.Unsubscribe()
+
+	<-ctx.Done()
+
+	r.mu.Lock()
+	r.closed = true
+	for client := range r.clients {
+		close(client.send)
+		delete(r.clients, client)
+	}
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *ProgressRelay) receive(msg *nats.Msg) {
+	var progress jobs.Progress
+	if err := json.Unmarshal(msg.Data, &progress); err != nil {
+		r.logger.Warn("dropping undecodable job progress", zap.String("subject", msg.Subject), zap.Error(err))
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.latest[progress.JobID] = progress
+	r.prune(time.Now())
+
+	for client := range r.clients {
+		if !client.wants(progress) {
+			continue
+		}
+		select {
+		case client.send <- msg.Data:
+		default:
+			// The client cannot keep up; drop it rather than stall the
+			// relay for everyone else.
+			close(client.send)
+			delete(r.clients, client)
+		}
+	}
+}
+
+// prune drops expired attempts. r.mu must be held.
+func (r *ProgressRelay) prune(now time.Time) {
+	for id, p := range r.latest {
+		age := now.Sub(p.UpdatedAt)
+		if (p.Finished() && age > progressFinishedTTL) || age > progressStaleTTL {
+			delete(r.latest, id)
+		}
+	}
+}
+
+func (r *ProgressRelay) current(client *progressClient) []jobs.Progress {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.prune(time.Now())
+	progress := make([]jobs.Progress, 0, len(r.latest))
+	for _, p := range r.latest {
+		if client.wants(p) {
+			progress = append(progress, p)
+		}
+	}
+	return progress
+}
+
+func (r *ProgressRelay) clientFor(c *gin.Context) (*progressClient, bool) {
+	client := &progressClient{
+		jobType: models.JobType(c.Query("type")),
+		region:  models.Region(c.Query("region")),
+		send:    make(chan []byte, progressSendBuffer),
+	}
+	if client.jobType != "" && !client.jobType.IsValid() {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, "invalid job type")
+		return nil, false
+	}
+	if client.region != "" && !client.region.IsValid() {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, "invalid region")
+		return nil, false
+	}
+	return client, true
+}
+
+// snapshot returns the latest progress of recent attempts, filtered by the
+// optional type and region query parameters.
+func (r *ProgressRelay) snapshot(c *gin.Context) {
+	client, ok := r.clientFor(c)
+	if !ok {
+		return
+	}
+	respondOK(c, r.current(client))
+}
+
+// stream upgrades to a WebSocket that first receives the current snapshot,
+// one message per attempt, and then every progress event as it arrives.
+func (r *ProgressRelay) stream(c *gin.Context) {
+	client, ok := r.clientFor(c)
+	if !ok {
+		return
+	}
+
+	conn, err := r.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// Upgrade already wrote the error response.
+		r.logger.Debug("websocket upgrade failed", zap.Error(err))
+		return
+	}
+
+	// Queue the snapshot before registering so it is sent ahead of live
+	// events; anything that does not fit the buffer is only live.
+	for _, p := range r.current(client) {
+		data, err := json.Marshal(p)
+		if err != nil {
+			continue
+		}
+		select {
+		case client.send <- data:
+		default:
+		}
+	}
+
+	r.mu.Lock()
+	if r.closed {
+		close(client.send)
+	} else {
+		r.clients[client] = struct{}{}
+	}
+	r.mu.Unlock()
+
+	go r.readPump(conn, client)
+	r.writePump(conn, client)
+}
+
+// readPump discards client messages and detects closed connections.
+func (r *ProgressRelay) readPump(conn *websocket.Conn, client *progressClient) {
+	defer r.remove(client)
+
+	conn.SetReadLimit(512)
+	conn.SetReadDeadline(time.Now().Add(progressPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(progressPongWait))
+	})
+
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+func (r *ProgressRelay) writePump(conn *websocket.Conn, client *progressClient) {
+	ticker := time.NewTicker(progressPingPeriod)
+	defer func() {
+		ticker.Stop()
+		conn.Close()
+	}()
+
+	for {
+		select {
+		case data, ok := <-client.send:
+			conn.SetWriteDeadline(time.Now().Add(progressWriteWait))
+			if !ok {
+				conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""))
+				return
+			}
+			if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				r.remove(client)
+				return
+			}
+		case <-ticker.C:
+			conn.SetWriteDeadline(time.Now().Add(progressWriteWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				r.remove(client)
+				return
+			}
+		}
+	}
+}
+
+func (r *ProgressRelay) remove(client *progressClient) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.clients[client]; ok {
+		close(client.send)
+		delete(r.clients, client)
+	}
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ProgressTicketKeyPrefix holds single-use tickets for the progress
+// WebSocket. Browsers cannot set headers on a WebSocket upgrade, so a
+// dashboard fetches a ticket with its usual credentials and passes it as
+// the ticket query parameter. Each ticket stores the principal it was
+// issued to and authenticates one upgrade within progressTicketTTL.
+const (
+	ProgressTicketKeyPrefix = "api:progress-ticket:"
+
+	progressTicketTTL = 30 * time.Second
+)
+
+var errInvalidProgressTicket = errors.New("invalid or expired progress ticket")
+
+type ProgressTicket struct {
+	Ticket    string    `json:"ticket"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type progressTickets struct {
+	client *redis.Client
+}
+
+func (t *progressTickets) issue(ctx context.Context, principal *Principal) (*ProgressTicket, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate progress ticket: %w", err)
+	}
+	ticket := base64.RawURLEncoding.EncodeToString(secret)
+
+	data, err := json.Marshal(principal)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode progress ticket: %w", err)
+	}
+	if err := t.client.Set(ctx, ProgressTicketKeyPrefix+ticket, data, progressTicketTTL).Err(); err != nil {
+		return nil, fmt.Errorf("failed to store progress ticket: %w", err)
+	}
+	return &ProgressTicket{Ticket: ticket, ExpiresAt: time.Now().Add(progressTicketTTL)}, nil
+}
+
+// redeem returns the principal of ticket and deletes it, so a ticket that
+// leaked through a log cannot be used again.
+func (t *progressTickets) redeem(ctx context.Context, ticket string) (*Principal, error) {
+	var get *redis.StringCmd
+	_, err := t.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		get = pipe.Get(ctx, ProgressTicketKeyPrefix+ticket)
+		pipe.Del(ctx, ProgressTicketKeyPrefix+ticket)
+		return nil
+	})
+	if err == redis.Nil {
+		return nil, errInvalidProgressTicket
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to redeem progress ticket: %w", err)
+	}
+
+	var principal Principal
+	if err := json.Unmarshal([]byte(get.Val()), &principal); err != nil {
+		return nil, fmt.Errorf("failed to decode progress ticket: %w", err)
+	}
+	return &principal, nil
+}
+
+// checkOrigin accepts upgrades without an Origin header, which only
+// non-browser clients omit, from the API's own host, and from allowed.
+func checkOrigin(allowed []string) func(*http.Request) bool {
+	return func(req *http.Request) bool {
+		origin := req.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+		if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, req.Host) {
+			return true
+		}
+		return slices.Contains(allowed, origin)
+	}
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/rsdlab-dk/tft-api/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProgressTicketIsSingleUse(t *testing.T) {
+	mr := miniredis.RunT(t)
+	tickets := &progressTickets{client: redis.NewClient(&redis.Options{Addr: mr.Addr()})}
+	ctx := context.Background()
+
+	issued, err := tickets.issue(ctx, &Principal{Role: models.RoleAdmin, UserID: "user-1"})
+	require.NoError(t, err)
+
+	principal, err := tickets.redeem(ctx, issued.Ticket)
+	require.NoError(t, err)
+	assert.Equal(t, models.RoleAdmin, principal.Role)
+	assert.Equal(t, "user-1", principal.UserID)
+
+	_, err = tickets.redeem(ctx, issued.Ticket)
+	assert.ErrorIs(t, err, errInvalidProgressTicket)
+}
+
+func TestProgressTicketExpires(t *testing.T) {
+	mr := miniredis.RunT(t)
+	tickets := &progressTickets{client: redis.NewClient(&redis.Options{Addr: mr.Addr()})}
+	ctx := context.Background()
+
+	issued, err := tickets.issue(ctx, &Principal{Role: models.RoleAdmin})
+	require.NoError(t, err)
+	mr.FastForward(progressTicketTTL)
+
+	_, err = tickets.redeem(ctx, issued.Ticket)
+	assert.ErrorIs(t, err, errInvalidProgressTicket)
+}
+
+func TestCheckOrigin(t *testing.T) {
+	check := checkOrigin([]string{"https://dashboard.example.com"})
+
+	tests := []struct {
+		name   string
+		origin string
+		want   bool
+	}{
+		{"no origin", "", true},
+		{"same host", "https://api.example.com", true},
+		{"allowed", "https://dashboard.example.com", true},
+		{"other site", "https://evil.example.net", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "https://api.example.com/admin/jobs/progress/ws", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			assert.Equal(t, tt.want, check(req))
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rsdlab-dk/tft-api/internal/models"
+)
+
+const (
+	ErrCodeInvalidRequest = "INVALID_REQUEST"
+	ErrCodeUnauthorized   = "UNAUTHORIZED"
+	ErrCodeForbidden      = "FORBIDDEN"
+	ErrCodeRateLimited    = "RATE_LIMITED"
+	ErrCodeNotFound       = "NOT_FOUND"
+	ErrCodeConflict       = "CONFLICT"
+	ErrCodeInternal       = "INTERNAL_ERROR"
+	ErrCodeUpstream       = "UPSTREAM_ERROR"
+	ErrCodeUnavailable    = "SERVICE_UNAVAILABLE"
+)
+
+func respondOK(c *gin.Context, data interface{}) {
+	c.JSON(http.StatusOK, models.NewSuccessResponse(data))
+}
+
+func respondError(c *gin.Context, status int, code, message string) {
+	c.AbortWithStatusJSON(status, models.NewErrorResponse(code, message))
+}
@@ -0,0 +1,117 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rsdlab-dk/tft-api/internal/auth"
+	"github.com/rsdlab-dk/tft-api/internal/models"
+	"github.com/rsdlab-dk/tft-api/internal/repository"
+	"github.com/rsdlab-dk/tft-api/internal/riot"
+	"go.uber.org/zap"
+)
+
+// RiotAccountsHandler lets users claim their Riot accounts: request a
+// challenge, set the profile icon it names in the client, then verify.
+type RiotAccountsHandler struct {
+	links  *auth.RiotLinks
+	users  *AuthHandler
+	logger *zap.Logger
+}
+
+func NewRiotAccountsHandler(links *auth.RiotLinks, users *AuthHandler, logger *zap.Logger) *RiotAccountsHandler {
+	return &RiotAccountsHandler{links: links, users: users, logger: logger}
+}
+
+func (h *RiotAccountsHandler) Register(group *gin.RouterGroup) {
+	accounts := group.Group("/me/riot-accounts", h.users.RequireAuth())
+	accounts.GET("", h.listAccounts)
+	accounts.POST("/challenge", h.startChallenge)
+	accounts.POST("/verify", h.verify)
+	accounts.DELETE("/:puuid", h.unlink)
+}
+
+func (h *RiotAccountsHandler) listAccounts(c *gin.Context) {
+	userID := claimsOf(c).UserID()
+	links, err := h.links.List(c.Request.Context(), userID)
+	if err != nil {
+		h.logger.Error("failed to list riot accounts", zap.String("user_id", userID), zap.Error(err))
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "failed to list riot accounts")
+		return
+	}
+
+	respondOK(c, links)
+}
+
+func (h *RiotAccountsHandler) startChallenge(c *gin.Context) {
+	var req models.LinkRiotAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, "invalid request body")
+		return
+	}
+
+	userID := claimsOf(c).UserID()
+	challenge, err := h.links.Start(c.Request.Context(), userID, req)
+	var validationErr *models.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, err.Error())
+		return
+	case err != nil:
+		h.respondRiotError(c, userID, err, "failed to start verification")
+		return
+	}
+
+	respondOK(c, challenge)
+}
+
+func (h *RiotAccountsHandler) verify(c *gin.Context) {
+	userID := claimsOf(c).UserID()
+	link, err := h.links.Verify(c.Request.Context(), userID)
+	var mismatch *auth.IconMismatchError
+	switch {
+	case errors.Is(err, auth.ErrNoRiotChallenge):
+		respondError(c, http.StatusNotFound, ErrCodeNotFound, err.Error())
+		return
+	case errors.As(err, &mismatch), errors.Is(err, repository.ErrRiotAccountClaimed):
+		respondError(c, http.StatusConflict, ErrCodeConflict, err.Error())
+		return
+	case err != nil:
+		h.respondRiotError(c, userID, err, "failed to verify riot account")
+		return
+	}
+
+	respondOK(c, link)
+}
+
+func (h *RiotAccountsHandler) unlink(c *gin.Context) {
+	userID := claimsOf(c).UserID()
+	puuid := c.Param("puuid")
+	err := h.links.Unlink(c.Request.Context(), userID, puuid)
+	if errors.Is(err, repository.ErrRiotLinkNotFound) {
+		respondError(c, http.StatusNotFound, ErrCodeNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to unlink riot account", zap.String("user_id", userID), zap.Error(err))
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "failed to unlink riot account")
+		return
+	}
+
+	respondOK(c, gin.H{"puuid": puuid, "linked": false})
+}
+
+// respondRiotError maps failures of calls that go through the Riot API.
+func (h *RiotAccountsHandler) respondRiotError(c *gin.Context, userID string, err error, message string) {
+	switch {
+	case errors.Is(err, riot.ErrNotFound):
+		respondError(c, http.StatusNotFound, ErrCodeNotFound, "riot account not found")
+	case errors.Is(err, riot.ErrUnavailable):
+		h.logger.Warn("riot api call failed", zap.String("user_id", userID), zap.Error(err))
+		respondError(c, http.StatusBadGateway, ErrCodeUpstream, "riot api unavailable, try again later")
+	default:
+		h.logger.Error(message, zap.String("user_id", userID), zap.Error(err))
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, message)
+	}
+}
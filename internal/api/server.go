@@ -0,0 +1,84 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rsdlab-dk/tft-api/internal/config"
+	"go.uber.org/zap"
+)
+
+type Server struct {
+	cfg    *config.Config
+	engine *gin.Engine
+	logger *zap.Logger
+}
+
+func NewServer(cfg *config.Config, logger *zap.Logger) (*Server, error) {
+	if cfg.IsProduction() {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	engine := gin.New()
+	engine.Use(gin.Recovery())
+
+	if err := engine.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("failed to set trusted proxies: %w", err)
+	}
+
+	engine.GET("/health", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	})
+
+	return &Server{
+		cfg:    cfg,
+		engine: engine,
+		logger: logger,
+	}, nil
+}
+
+func (s *Server) Engine() *gin.Engine {
+	return s.engine
+}
+
+// Run serves HTTP until ctx is cancelled, then drains in-flight requests
+// for up to ServerConfig.ShutdownTimeout.
+func (s *Server) Run(ctx context.Context) error {
+	srv := &http.Server{
+		Addr:           s.cfg.ServerAddr(),
+		Handler:        s.engine,
+		ReadTimeout:    s.cfg.Server.ReadTimeout,
+		WriteTimeout:   s.cfg.Server.WriteTimeout,
+		IdleTimeout:    s.cfg.Server.IdleTimeout,
+		MaxHeaderBytes: s.cfg.Server.MaxHeaderBytes,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		s.logger.Info("starting http server", zap.String("addr", srv.Addr))
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errCh <- err
+		}
+		close(errCh)
+	}()
+
+	select {
+	case err := <-errCh:
+		if err != nil {
+			return fmt.Errorf("http server failed: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to shut down http server: %w", err)
+	}
+	return nil
+}
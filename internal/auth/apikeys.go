@@ -0,0 +1,149 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rsdlab-dk/tft-api/internal/models"
+	"github.com/rsdlab-dk/tft-api/internal/repository"
+)
+
+// APIKeyPrefix starts every key, so leaked keys are easy to recognise.
+const APIKeyPrefix = "tft_"
+
+const (
+	apiKeyBytes = 32
+
+	// apiKeyCacheTTL bounds how long a revoked key keeps working on other
+	// replicas.
+	apiKeyCacheTTL = 30 * time.Second
+)
+
+var ErrInvalidAPIKey = errors.New("invalid, expired or revoked api key")
+
+// APIKeys creates and verifies API keys. Verified keys, and unknown ones,
+// are remembered for a short while so requests do not each hit Postgres.
+type APIKeys struct {
+	repo *repository.APIKeyRepository
+
+	mu     sync.Mutex
+	cached map[string]cachedAPIKey
+}
+
+type cachedAPIKey struct {
+	key       *models.APIKey
+	expiresAt time.Time
+}
+
+func NewAPIKeys(repo *repository.APIKeyRepository) *APIKeys {
+	return &APIKeys{repo: repo, cached: make(map[string]cachedAPIKey)}
+}
+
+func hashAPIKey(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// Create generates a key. The plain key is only part of the result.
+func (k *APIKeys) Create(ctx context.Context, req models.CreateAPIKeyRequest) (*models.CreatedAPIKey, error) {
+	req.SetDefaults()
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	secret := make([]byte, apiKeyBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate api key: %w", err)
+	}
+	raw := APIKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	key := &models.APIKey{
+		ID:                uuid.NewString(),
+		UserID:            req.UserID,
+		Name:              req.Name,
+		Prefix:            raw[:len(APIKeyPrefix)+8],
+		Scopes:            req.Scopes,
+		Role:              req.Role,
+		RequestsPerMinute: req.RequestsPerMinute,
+		ExpiresAt:         req.ExpiresAt,
+	}
+	if err := k.repo.Create(ctx, key, hashAPIKey(raw)); err != nil {
+		return nil, err
+	}
+	return &models.CreatedAPIKey{APIKey: key, Key: raw}, nil
+}
+
+// Authenticate returns the active key for raw.
+func (k *APIKeys) Authenticate(ctx context.Context, raw string) (*models.APIKey, error) {
+	if !strings.HasPrefix(raw, APIKeyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	hash := hashAPIKey(raw)
+	now := time.Now()
+
+	k.mu.Lock()
+	entry, ok := k.cached[hash]
+	k.mu.Unlock()
+
+	if !ok || now.After(entry.expiresAt) {
+		key, err := k.repo.GetByHash(ctx, hash)
+		if err != nil && !errors.Is(err, repository.ErrAPIKeyNotFound) {
+			return nil, err
+		}
+
+		entry = cachedAPIKey{key: key, expiresAt: now.Add(apiKeyCacheTTL)}
+		k.mu.Lock()
+		k.prune(now)
+		k.cached[hash] = entry
+		k.mu.Unlock()
+	}
+
+	if entry.key == nil || !entry.key.IsActive(now) {
+		return nil, ErrInvalidAPIKey
+	}
+	return entry.key, nil
+}
+
+// prune drops expired entries; callers hold mu.
+func (k *APIKeys) prune(now time.Time) {
+	for hash, entry := range k.cached {
+		if now.After(entry.expiresAt) {
+			delete(k.cached, hash)
+		}
+	}
+}
+
+func (k *APIKeys) List(ctx context.Context) ([]models.APIKey, error) {
+	return k.repo.List(ctx)
+}
+
+// Revoke disables the key at once on this replica and within
+// apiKeyCacheTTL on the others.
+func (k *APIKeys) Revoke(ctx context.Context, id string) error {
+	if err := k.repo.Revoke(ctx, id); err != nil {
+		return err
+	}
+
+	k.mu.Lock()
+	for hash, entry := range k.cached {
+		if entry.key != nil && entry.key.ID == id {
+			delete(k.cached, hash)
+		}
+	}
+	k.mu.Unlock()
+	return nil
+}
+
+func (k *APIKeys) Usage(ctx context.Context, id string, days int) ([]models.APIKeyUsage, error) {
+	return k.repo.Usage(ctx, id, days)
+}
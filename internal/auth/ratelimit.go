@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/rsdlab-dk/tft-api/internal/models"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+)
+
+// RateLimitKeyPrefix holds one sorted set per API key with the request
+// times of the current window.
+const RateLimitKeyPrefix = "ratelimit:apikey:"
+
+const rateLimitWindow = time.Minute
+
+// slidingWindowScript admits a request if fewer than limit were admitted in
+// the last window and returns {admitted, remaining, reset ms}.
+var slidingWindowScript = redis.NewScript(`
+	local now = tonumber(ARGV[1])
+	local window = tonumber(ARGV[2])
+	local limit = tonumber(ARGV[3])
+
+	redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now - window)
+	local count = redis.call("ZCARD", KEYS[1])
+	local admitted = 0
+	if count < limit then
+		redis.call("ZADD", KEYS[1], now, ARGV[4])
+		count = count + 1
+		admitted = 1
+	end
+	redis.call("PEXPIRE", KEYS[1], window)
+
+	local reset = now + window
+	local oldest = redis.call("ZRANGE", KEYS[1], 0, 0, "WITHSCORES")
+	if oldest[2] then
+		reset = tonumber(oldest[2]) + window
+	end
+	return {admitted, limit - count, reset}`)
+
+// Quota is the outcome of one rate limit check, for the X-RateLimit-*
+// headers.
+type Quota struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+// RateLimiter enforces RequestsPerMinute per API key. A token bucket per
+// replica turns away bursts without a Redis round trip; the sliding window
+// in Redis enforces the quota across replicas. If Redis is unavailable
+// only the local bucket applies.
+type RateLimiter struct {
+	client *redis.Client
+	logger *zap.Logger
+
+	mu    sync.Mutex
+	local map[string]*rate.Limiter
+}
+
+func NewRateLimiter(client *redis.Client, logger *zap.Logger) *RateLimiter {
+	return &RateLimiter{client: client, logger: logger, local: make(map[string]*rate.Limiter)}
+}
+
+func (l *RateLimiter) Allow(ctx context.Context, key *models.APIKey) (*Quota, error) {
+	now := time.Now()
+	limit := key.RequestsPerMinute
+
+	if r := l.reserve(key, now); r.DelayFrom(now) > 0 {
+		r.CancelAt(now)
+		return &Quota{Limit: limit, Reset: now.Add(r.DelayFrom(now))}, nil
+	}
+
+	result, err := slidingWindowScript.Run(ctx, l.client, []string{RateLimitKeyPrefix + key.ID},
+		now.UnixMilli(), rateLimitWindow.Milliseconds(), limit, uuid.NewString(),
+	).Int64Slice()
+	if err != nil {
+		l.logger.Warn("rate limit check failed, using local limit only", zap.String("api_key_id", key.ID), zap.Error(err))
+		return &Quota{Allowed: true, Limit: limit, Remaining: -1, Reset: now.Add(rateLimitWindow)}, nil
+	}
+	if len(result) != 3 {
+		return nil, fmt.Errorf("unexpected rate limit result %v", result)
+	}
+
+	return &Quota{
+		Allowed:   result[0] == 1,
+		Limit:     limit,
+		Remaining: int(result[1]),
+		Reset:     time.UnixMilli(result[2]),
+	}, nil
+}
+
+func (l *RateLimiter) reserve(key *models.APIKey, now time.Time) *rate.Reservation {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	limiter, ok := l.local[key.ID]
+	if !ok {
+		limiter = rate.NewLimiter(rate.Every(rateLimitWindow/time.Duration(key.RequestsPerMinute)), key.RequestsPerMinute)
+		l.local[key.ID] = limiter
+	}
+	return limiter.ReserveN(now, 1)
+}
@@ -0,0 +1,190 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/rsdlab-dk/tft-api/internal/config"
+	"github.com/rsdlab-dk/tft-api/internal/models"
+	"github.com/rsdlab-dk/tft-api/internal/repository"
+	"github.com/rsdlab-dk/tft-api/internal/riot"
+	"go.uber.org/zap"
+)
+
+// RiotChallengeKeyPrefix holds the pending verification of a user, one at
+// a time, until RiotChallengeTTL passes.
+const RiotChallengeKeyPrefix = "auth:riot-challenge:"
+
+// baseProfileIcons are icons 0 to 28, which every account owns, so any of
+// them can be asked for.
+const baseProfileIcons = 29
+
+var ErrNoRiotChallenge = errors.New("no pending riot account verification")
+
+// IconMismatchError is returned when the account does not show the
+// profile icon the challenge asked for (yet).
+type IconMismatchError struct {
+	Expected int
+	Actual   int
+}
+
+func (e *IconMismatchError) Error() string {
+	return fmt.Sprintf("profile icon is %d, expected %d", e.Actual, e.Expected)
+}
+
+// RiotLinks verifies that users own the Riot accounts they claim. Start
+// asks for a profile icon; Verify checks the summoner endpoint for it and
+// links the account for RiotLinkTTL.
+type RiotLinks struct {
+	links     *repository.RiotLinkRepository
+	summoners *repository.SummonerRepository
+	riot      *riot.Client
+	client    *redis.Client
+	cfg       config.AuthConfig
+	logger    *zap.Logger
+}
+
+func NewRiotLinks(links *repository.RiotLinkRepository, summoners *repository.SummonerRepository, riotClient *riot.Client, client *redis.Client, cfg config.AuthConfig, logger *zap.Logger) *RiotLinks {
+	return &RiotLinks{
+		links:     links,
+		summoners: summoners,
+		riot:      riotClient,
+		client:    client,
+		cfg:       cfg,
+		logger:    logger,
+	}
+}
+
+func riotChallengeKey(userID string) string {
+	return RiotChallengeKeyPrefix + userID
+}
+
+// Start looks up the account and replaces any pending challenge of userID
+// with one for it. The icon asked for always differs from the current one,
+// so only someone who can change it passes.
+func (l *RiotLinks) Start(ctx context.Context, userID string, req models.LinkRiotAccountRequest) (*models.RiotLinkChallenge, error) {
+	req.Normalize()
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	account, err := l.riot.AccountByRiotID(ctx, req.Region, req.GameName, req.TagLine)
+	if err != nil {
+		return nil, err
+	}
+	summoner, err := l.riot.SummonerByPUUID(ctx, req.Region, account.PUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	icon := rand.IntN(baseProfileIcons - 1)
+	if icon >= summoner.ProfileIconID {
+		icon++
+	}
+
+	challenge := &models.RiotLinkChallenge{
+		PUUID:         account.PUUID,
+		Region:        req.Region,
+		GameName:      account.GameName,
+		TagLine:       account.TagLine,
+		ProfileIconID: icon,
+		ExpiresAt:     time.Now().Add(l.cfg.RiotChallengeTTL),
+	}
+	data, err := json.Marshal(challenge)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode riot link challenge: %w", err)
+	}
+	if err := l.client.Set(ctx, riotChallengeKey(userID), data, l.cfg.RiotChallengeTTL).Err(); err != nil {
+		return nil, fmt.Errorf("failed to store riot link challenge: %w", err)
+	}
+	return challenge, nil
+}
+
+// Verify fetches the summoner of the pending challenge, records it in
+// players.summoners and links the account if the stored profile icon is
+// the one asked for. The challenge stays pending after a mismatch, so the
+// user can retry once the icon change shows up.
+func (l *RiotLinks) Verify(ctx context.Context, userID string) (*models.RiotAccountLink, error) {
+	data, err := l.client.Get(ctx, riotChallengeKey(userID)).Bytes()
+	if err == redis.Nil {
+		return nil, ErrNoRiotChallenge
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get riot link challenge: %w", err)
+	}
+	var challenge models.RiotLinkChallenge
+	if err := json.Unmarshal(data, &challenge); err != nil {
+		return nil, fmt.Errorf("failed to decode riot link challenge: %w", err)
+	}
+
+	summoner, err := l.riot.SummonerByPUUID(ctx, challenge.Region, challenge.PUUID)
+	if err != nil {
+		return nil, err
+	}
+	profileIconID, err := l.summoners.Upsert(ctx, &models.Summoner{
+		PUUID:         summoner.PUUID,
+		SummonerID:    summoner.ID,
+		AccountID:     summoner.AccountID,
+		Name:          challenge.GameName,
+		GameName:      challenge.GameName,
+		TagLine:       challenge.TagLine,
+		ProfileIconID: summoner.ProfileIconID,
+		SummonerLevel: summoner.SummonerLevel,
+		Region:        challenge.Region,
+		RevisionDate:  summoner.RevisionDate,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if profileIconID != challenge.ProfileIconID {
+		return nil, &IconMismatchError{Expected: challenge.ProfileIconID, Actual: profileIconID}
+	}
+
+	now := time.Now()
+	link := &models.RiotAccountLink{
+		PUUID:      challenge.PUUID,
+		Region:     challenge.Region,
+		GameName:   challenge.GameName,
+		TagLine:    challenge.TagLine,
+		VerifiedAt: now,
+		ExpiresAt:  now.Add(l.cfg.RiotLinkTTL),
+		Verified:   true,
+	}
+	if err := l.links.Link(ctx, userID, link.PUUID, link.VerifiedAt, link.ExpiresAt); err != nil {
+		return nil, err
+	}
+	if err := l.client.Del(ctx, riotChallengeKey(userID)).Err(); err != nil {
+		l.logger.Warn("failed to clear riot link challenge", zap.String("user_id", userID), zap.Error(err))
+	}
+
+	l.logger.Info("riot account linked", zap.String("user_id", userID), zap.String("puuid", link.PUUID))
+	return link, nil
+}
+
+// List returns the accounts linked to userID. Expired links are included
+// with Verified false; they count again once verified anew.
+func (l *RiotLinks) List(ctx context.Context, userID string) ([]models.RiotAccountLink, error) {
+	links, err := l.links.List(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for i := range links {
+		links[i].Verified = links[i].IsVerified(now)
+	}
+	return links, nil
+}
+
+func (l *RiotLinks) Unlink(ctx context.Context, userID, puuid string) error {
+	if err := l.links.Unlink(ctx, userID, puuid); err != nil {
+		return err
+	}
+	l.logger.Info("riot account unlinked", zap.String("user_id", userID), zap.String("puuid", puuid))
+	return nil
+}
@@ -0,0 +1,215 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/rsdlab-dk/tft-api/internal/config"
+	"github.com/rsdlab-dk/tft-api/internal/models"
+	"github.com/rsdlab-dk/tft-api/internal/repository"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrInvalidCredentials is returned for unknown emails, wrong passwords
+// and locked accounts alike, so logins do not reveal which accounts exist.
+var ErrInvalidCredentials = errors.New("invalid email or password")
+
+type Service struct {
+	users    *repository.UserRepository
+	tokens   *Tokens
+	sessions *Sessions
+	cfg      config.AuthConfig
+	logger   *zap.Logger
+
+	// dummyHash is compared against on unknown emails and locked accounts
+	// so they take as long as wrong passwords.
+	dummyHash []byte
+}
+
+func NewService(users *repository.UserRepository, rdb *redis.Client, cfg config.AuthConfig, logger *zap.Logger) (*Service, error) {
+	dummyHash, err := bcrypt.GenerateFromPassword([]byte(uuid.NewString()), cfg.BcryptCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare password hashing: %w", err)
+	}
+
+	return &Service{
+		users:     users,
+		tokens:    NewTokens(cfg),
+		sessions:  NewSessions(rdb, cfg.SessionTimeout),
+		cfg:       cfg,
+		logger:    logger,
+		dummyHash: dummyHash,
+	}, nil
+}
+
+// Register creates an account. It returns a *models.ValidationError for
+// invalid input and repository.ErrUserExists for taken emails or names.
+func (s *Service) Register(ctx context.Context, req models.RegisterRequest) (*models.User, error) {
+	req.Normalize()
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), s.cfg.BcryptCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	user := &models.User{
+		ID:           uuid.NewString(),
+		Email:        req.Email,
+		Username:     req.Username,
+		PasswordHash: string(hash),
+	}
+	if err := s.users.Create(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// Login checks the credentials and opens a session. Every failed attempt
+// counts towards MaxLoginAttempts; reaching it locks the account for
+// LockoutDuration. A locked account fails like an unknown email, even with
+// the right password: telling the owner it is locked would tell anyone
+// else that it exists.
+func (s *Service) Login(ctx context.Context, req models.LoginRequest) (*models.TokenPair, *models.User, error) {
+	req.Normalize()
+
+	user, err := s.users.GetByEmail(ctx, req.Email)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		bcrypt.CompareHashAndPassword(s.dummyHash, []byte(req.Password))
+		return nil, nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	if user.IsLocked(now) {
+		bcrypt.CompareHashAndPassword(s.dummyHash, []byte(req.Password))
+		return nil, nil, ErrInvalidCredentials
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+		lockedUntil, err := s.users.RecordFailedLogin(ctx, user.ID, s.cfg.MaxLoginAttempts, s.cfg.LockoutDuration)
+		if err != nil {
+			return nil, nil, err
+		}
+		if lockedUntil != nil {
+			s.logger.Warn("account locked after failed logins", zap.String("user_id", user.ID), zap.Time("until", *lockedUntil))
+		}
+		return nil, nil, ErrInvalidCredentials
+	}
+
+	if err := s.users.RecordLogin(ctx, user.ID); err != nil {
+		return nil, nil, err
+	}
+
+	refreshID := uuid.NewString()
+	sessionID, err := s.sessions.Start(ctx, user.ID, refreshID, req.UserAgent, req.IP)
+	if err != nil {
+		return nil, nil, err
+	}
+	tokens, err := s.tokens.Issue(user.ID, sessionID, refreshID, now)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tokens, user, nil
+}
+
+// Refresh exchanges refreshToken for a new token pair, as long as the
+// session has not been idle for longer than SessionTimeout. Every refresh
+// token can be exchanged once; presenting one again revokes its session
+// and returns ErrRefreshTokenReused.
+func (s *Service) Refresh(ctx context.Context, refreshToken string) (*models.TokenPair, error) {
+	claims, err := s.tokens.Parse(refreshToken, TokenTypeRefresh)
+	if err != nil {
+		return nil, err
+	}
+
+	// The account may have been deleted since the session started.
+	if _, err := s.users.GetByID(ctx, claims.UserID()); err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	// Sign before rotating, so a rotated session always has a token out.
+	nextID := uuid.NewString()
+	tokens, err := s.tokens.Issue(claims.UserID(), claims.SessionID, nextID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.sessions.Rotate(ctx, claims.SessionID, claims.UserID(), claims.ID, nextID)
+	if errors.Is(err, ErrRefreshTokenReused) {
+		s.logger.Warn("refresh token reused, session revoked",
+			zap.String("user_id", claims.UserID()),
+			zap.String("session_id", claims.SessionID),
+		)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// Authenticate verifies an access token and extends its session.
+func (s *Service) Authenticate(ctx context.Context, accessToken string) (*Claims, error) {
+	claims, err := s.tokens.Parse(accessToken, TokenTypeAccess)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.sessions.Touch(ctx, claims.SessionID, claims.UserID()); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func (s *Service) User(ctx context.Context, id string) (*models.User, error) {
+	return s.users.GetByID(ctx, id)
+}
+
+// Logout ends the session of claims.
+func (s *Service) Logout(ctx context.Context, claims *Claims) error {
+	return s.sessions.End(ctx, claims.SessionID, claims.UserID())
+}
+
+// EndSession ends one session of userID. It returns ErrSessionNotFound
+// for sessions of other users.
+func (s *Service) EndSession(ctx context.Context, userID, sessionID string) error {
+	return s.sessions.End(ctx, sessionID, userID)
+}
+
+// LogoutAll ends every session of userID and returns how many there were.
+func (s *Service) LogoutAll(ctx context.Context, userID string) (int, error) {
+	return s.sessions.EndAll(ctx, userID)
+}
+
+// Sessions lists the active sessions of claims' user, marking the one
+// claims belong to.
+func (s *Service) Sessions(ctx context.Context, claims *Claims) ([]models.Session, error) {
+	sessions, err := s.sessions.List(ctx, claims.UserID())
+	if err != nil {
+		return nil, err
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == claims.SessionID
+	}
+	return sessions, nil
+}
+
+// SetRole changes the role of a user. It takes effect on their next
+// request to an admin route.
+func (s *Service) SetRole(ctx context.Context, id string, req models.UpdateRoleRequest) error {
+	if err := req.Validate(); err != nil {
+		return err
+	}
+	return s.users.SetRole(ctx, id, req.Role)
+}
@@ -0,0 +1,250 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/rsdlab-dk/tft-api/internal/models"
+)
+
+// SessionKeyPrefix holds one hash per login session. A session is also the
+// family of the refresh tokens issued for it: the hash stores the ID of
+// the only refresh token that may still be used. It expires after
+// SessionTimeout without activity.
+//
+// UserSessionsKeyPrefix holds the set of session IDs of a user, for
+// listing and ending them. Members whose session expired are pruned when
+// the set is read.
+const (
+	SessionKeyPrefix      = "auth:family:"
+	UserSessionsKeyPrefix = "auth:user-sessions:"
+
+	maxUserAgentLength = 256
+)
+
+var (
+	ErrSessionExpired  = errors.New("session expired")
+	ErrSessionNotFound = errors.New("session not found")
+	// ErrRefreshTokenReused is returned when a refresh token that was
+	// already exchanged is presented again. Either the client or an
+	// attacker holds a stolen copy, so the whole session is revoked.
+	ErrRefreshTokenReused = errors.New("refresh token already used; session revoked")
+)
+
+// KEYS: session, user sessions. ARGV: user ID, idle ms, now.
+var touchSessionScript = redis.NewScript(`
+	if redis.call("HGET", KEYS[1], "user") ~= ARGV[1] then
+		return 0
+	end
+	redis.call("HSET", KEYS[1], "last_seen", ARGV[3])
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+	redis.call("PEXPIRE", KEYS[2], ARGV[2])
+	return 1`)
+
+// KEYS: session, user sessions. ARGV: user ID, idle ms, now, session ID,
+// presented refresh token ID, next refresh token ID.
+var rotateSessionScript = redis.NewScript(`
+	if redis.call("HGET", KEYS[1], "user") ~= ARGV[1] then
+		return 0
+	end
+	if redis.call("HGET", KEYS[1], "refresh") ~= ARGV[5] then
+		redis.call("DEL", KEYS[1])
+		redis.call("SREM", KEYS[2], ARGV[4])
+		return -1
+	end
+	redis.call("HSET", KEYS[1], "refresh", ARGV[6], "last_seen", ARGV[3])
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+	redis.call("PEXPIRE", KEYS[2], ARGV[2])
+	return 1`)
+
+// KEYS: session, user sessions. ARGV: user ID, session ID.
+var endSessionScript = redis.NewScript(`
+	if redis.call("HGET", KEYS[1], "user") ~= ARGV[1] then
+		return 0
+	end
+	redis.call("DEL", KEYS[1])
+	redis.call("SREM", KEYS[2], ARGV[2])
+	return 1`)
+
+// Sessions enforces the idle timeout and refresh-token rotation. Access
+// tokens stay stateless; every authenticated request only extends the
+// session they belong to.
+type Sessions struct {
+	client *redis.Client
+	idle   time.Duration
+}
+
+func NewSessions(client *redis.Client, idle time.Duration) *Sessions {
+	return &Sessions{client: client, idle: idle}
+}
+
+func sessionKey(sessionID string) string {
+	return SessionKeyPrefix + sessionID
+}
+
+func userSessionsKey(userID string) string {
+	return UserSessionsKeyPrefix + userID
+}
+
+// Start opens a session for userID whose first refresh token is refreshID
+// and returns the session ID.
+func (s *Sessions) Start(ctx context.Context, userID, refreshID, userAgent, ip string) (string, error) {
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+
+	sessionID := uuid.NewString()
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, sessionKey(sessionID),
+			"user", userID,
+			"refresh", refreshID,
+			"created", now,
+			"last_seen", now,
+			"user_agent", userAgent,
+			"ip", ip,
+		)
+		pipe.PExpire(ctx, sessionKey(sessionID), s.idle)
+		pipe.SAdd(ctx, userSessionsKey(userID), sessionID)
+		pipe.PExpire(ctx, userSessionsKey(userID), s.idle)
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to start session: %w", err)
+	}
+	return sessionID, nil
+}
+
+// Touch extends the session, or returns ErrSessionExpired if it timed out,
+// was ended or belongs to someone else.
+func (s *Sessions) Touch(ctx context.Context, sessionID, userID string) error {
+	touched, err := touchSessionScript.Run(ctx, s.client,
+		[]string{sessionKey(sessionID), userSessionsKey(userID)},
+		userID, s.idle.Milliseconds(), time.Now().Unix(),
+	).Int()
+	if err != nil {
+		return fmt.Errorf("failed to touch session: %w", err)
+	}
+	if touched == 0 {
+		return ErrSessionExpired
+	}
+	return nil
+}
+
+// Rotate replaces the session's refresh token refreshID with nextID. If
+// refreshID is not the current one it was used before, and the session is
+// ended with ErrRefreshTokenReused.
+func (s *Sessions) Rotate(ctx context.Context, sessionID, userID, refreshID, nextID string) error {
+	rotated, err := rotateSessionScript.Run(ctx, s.client,
+		[]string{sessionKey(sessionID), userSessionsKey(userID)},
+		userID, s.idle.Milliseconds(), time.Now().Unix(), sessionID, refreshID, nextID,
+	).Int()
+	if err != nil {
+		return fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+	switch rotated {
+	case 0:
+		return ErrSessionExpired
+	case -1:
+		return ErrRefreshTokenReused
+	}
+	return nil
+}
+
+// End ends a session of userID, or returns ErrSessionNotFound if there is
+// no such session.
+func (s *Sessions) End(ctx context.Context, sessionID, userID string) error {
+	ended, err := endSessionScript.Run(ctx, s.client,
+		[]string{sessionKey(sessionID), userSessionsKey(userID)},
+		userID, sessionID,
+	).Int()
+	if err != nil {
+		return fmt.Errorf("failed to end session: %w", err)
+	}
+	if ended == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// EndAll ends every session of userID and returns how many there were.
+func (s *Sessions) EndAll(ctx context.Context, userID string) (int, error) {
+	sessionIDs, err := s.client.SMembers(ctx, userSessionsKey(userID)).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	keys := make([]string, 0, len(sessionIDs)+1)
+	for _, id := range sessionIDs {
+		keys = append(keys, sessionKey(id))
+	}
+	keys = append(keys, userSessionsKey(userID))
+
+	// The set entry is removed with the session, so a count above one
+	// means live sessions were ended.
+	deleted, err := s.client.Del(ctx, keys...).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to end sessions: %w", err)
+	}
+	if len(sessionIDs) > 0 {
+		deleted--
+	}
+	return int(deleted), nil
+}
+
+// List returns the active sessions of userID, most recently used first.
+func (s *Sessions) List(ctx context.Context, userID string) ([]models.Session, error) {
+	sessionIDs, err := s.client.SMembers(ctx, userSessionsKey(userID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	cmds := make([]*redis.StringStringMapCmd, len(sessionIDs))
+	_, err = s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, id := range sessionIDs {
+			cmds[i] = pipe.HGetAll(ctx, sessionKey(id))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sessions: %w", err)
+	}
+
+	sessions := make([]models.Session, 0, len(sessionIDs))
+	var expired []interface{}
+	for i, cmd := range cmds {
+		fields := cmd.Val()
+		if fields["user"] != userID {
+			expired = append(expired, sessionIDs[i])
+			continue
+		}
+		sessions = append(sessions, models.Session{
+			ID:         sessionIDs[i],
+			CreatedAt:  unixField(fields["created"]),
+			LastSeenAt: unixField(fields["last_seen"]),
+			UserAgent:  fields["user_agent"],
+			IP:         fields["ip"],
+		})
+	}
+	if len(expired) > 0 {
+		if err := s.client.SRem(ctx, userSessionsKey(userID), expired...).Err(); err != nil {
+			return nil, fmt.Errorf("failed to prune sessions: %w", err)
+		}
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt)
+	})
+	return sessions, nil
+}
+
+func unixField(value string) time.Time {
+	seconds, _ := strconv.ParseInt(value, 10, 64)
+	return time.Unix(seconds, 0).UTC()
+}
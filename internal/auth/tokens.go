@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/rsdlab-dk/tft-api/internal/config"
+	"github.com/rsdlab-dk/tft-api/internal/models"
+)
+
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+
+	tokenIssuer = "tft-api"
+)
+
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+// Claims are carried by both token types. Type keeps a refresh token from
+// being accepted as an access token and the other way around; SessionID
+// ties both to the session whose idle timeout they are subject to. The ID
+// of a refresh token is what the session rotates.
+type Claims struct {
+	jwt.RegisteredClaims
+	Type      string `json:"typ"`
+	SessionID string `json:"sid"`
+}
+
+// UserID is the subject of the token.
+func (c *Claims) UserID() string {
+	return c.Subject
+}
+
+// Tokens issues and verifies HS256-signed JWTs.
+type Tokens struct {
+	secret     []byte
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+}
+
+func NewTokens(cfg config.AuthConfig) *Tokens {
+	return &Tokens{
+		secret:     []byte(cfg.JWTSecret),
+		accessTTL:  cfg.JWTExpiration,
+		refreshTTL: cfg.RefreshExpiration,
+	}
+}
+
+// Issue returns a new access token and the refresh token refreshID for
+// the session.
+func (t *Tokens) Issue(userID, sessionID, refreshID string, now time.Time) (*models.TokenPair, error) {
+	access, err := t.sign(uuid.NewString(), userID, sessionID, TokenTypeAccess, now, t.accessTTL)
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := t.sign(refreshID, userID, sessionID, TokenTypeRefresh, now, t.refreshTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TokenPair{
+		AccessToken:      access,
+		RefreshToken:     refresh,
+		TokenType:        "Bearer",
+		ExpiresIn:        int64(t.accessTTL.Seconds()),
+		RefreshExpiresAt: now.Add(t.refreshTTL),
+	}, nil
+}
+
+func (t *Tokens) sign(id, userID, sessionID, tokenType string, now time.Time, ttl time.Duration) (string, error) {
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        id,
+			Issuer:    tokenIssuer,
+			Subject:   userID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+		Type:      tokenType,
+		SessionID: sessionID,
+	}
+
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(t.secret)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign %s token: %w", tokenType, err)
+	}
+	return signed, nil
+}
+
+// Parse verifies token and returns its claims if it is of tokenType.
+func (t *Tokens) Parse(token, tokenType string) (*Claims, error) {
+	var claims Claims
+	_, err := jwt.ParseWithClaims(token, &claims, func(*jwt.Token) (interface{}, error) {
+		return t.secret, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(tokenIssuer),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil || claims.Type != tokenType || claims.Subject == "" || claims.SessionID == "" || claims.ID == "" {
+		return nil, ErrInvalidToken
+	}
+	return &claims, nil
+}
@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/rsdlab-dk/tft-api/internal/repository"
+	"go.uber.org/zap"
+)
+
+const usageFlushInterval = 30 * time.Second
+
+type usageKey struct {
+	keyID string
+	day   time.Time
+}
+
+// Usage counts requests per API key and day and writes them to Postgres
+// in batches, off the request path. A nil Usage records nothing.
+type Usage struct {
+	repo   *repository.APIKeyRepository
+	logger *zap.Logger
+
+	mu     sync.Mutex
+	counts map[usageKey]*repository.APIKeyUsageCount
+}
+
+func NewUsage(repo *repository.APIKeyRepository, logger *zap.Logger) *Usage {
+	return &Usage{repo: repo, logger: logger, counts: make(map[usageKey]*repository.APIKeyUsageCount)}
+}
+
+// Record counts one request, rejected if it was over quota.
+func (u *Usage) Record(keyID string, rejected bool) {
+	if u == nil {
+		return
+	}
+
+	now := time.Now().UTC()
+	k := usageKey{keyID: keyID, day: now.Truncate(24 * time.Hour)}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	c, ok := u.counts[k]
+	if !ok {
+		c = &repository.APIKeyUsageCount{KeyID: keyID, Day: k.day}
+		u.counts[k] = c
+	}
+	if rejected {
+		c.Rejected++
+	} else {
+		c.Requests++
+	}
+	c.LastUsed = now
+}
+
+// Run flushes the counts until ctx is done, then flushes once more.
+func (u *Usage) Run(ctx context.Context) {
+	ticker := time.NewTicker(usageFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), usageFlushInterval)
+			u.flush(flushCtx)
+			cancel()
+			return
+		case <-ticker.C:
+			u.flush(ctx)
+		}
+	}
+}
+
+func (u *Usage) flush(ctx context.Context) {
+	u.mu.Lock()
+	counts := u.counts
+	u.counts = make(map[usageKey]*repository.APIKeyUsageCount, len(counts))
+	u.mu.Unlock()
+
+	if len(counts) == 0 {
+		return
+	}
+
+	batch := make([]repository.APIKeyUsageCount, 0, len(counts))
+	for _, c := range counts {
+		batch = append(batch, *c)
+	}
+	if err := u.repo.RecordUsage(ctx, batch); err != nil {
+		u.logger.Warn("failed to record api key usage", zap.Int("keys", len(batch)), zap.Error(err))
+	}
+}
@@ -0,0 +1,312 @@
+package cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/rsdlab-dk/tft-api/internal/config"
+	"github.com/rsdlab-dk/tft-api/internal/models"
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+)
+
+var ErrMiss = errors.New("cache miss")
+
+// KeyPrefix namespaces cached values in Redis, apart from job state such
+// as dedupe keys and leader locks.
+const KeyPrefix = "cache:"
+
+// Every stored value starts with one of these so compressed and plain
+// values can be mixed, e.g. after CompressionThreshold changed.
+const (
+	encodingPlain byte = 0
+	encodingGzip  byte = 1
+)
+
+// Cache stores JSON values in Redis under models.CacheKey. The TTL comes
+// from the key when set with WithTTL, otherwise from the CacheConfig field
+// matching the key prefix. Values stay in Redis for StaleTTL past their TTL
+// so Fetch can serve them while refreshing.
+//
+// Reads go through an in-process LRU first when LocalMaxEntries is set; it
+// keeps values for at most LocalTTL.
+type Cache struct {
+	rdb    *redis.Client
+	cfg    config.CacheConfig
+	logger *zap.Logger
+	loads  singleflight.Group
+	local  *local
+
+	// tagTTL is the longest an entry stays in Redis and the length of a
+	// tag set window.
+	tagTTL time.Duration
+
+	localHits, localMisses atomic.Uint64
+	redisHits, redisMisses atomic.Uint64
+}
+
+// TierStats counts the lookups answered by one cache tier since start.
+type TierStats struct {
+	Hits      uint64  `json:"hits"`
+	Misses    uint64  `json:"misses"`
+	HitRatio  float64 `json:"hit_ratio"`
+	Entries   int     `json:"entries,omitempty"`
+	Evictions uint64  `json:"evictions,omitempty"`
+}
+
+type Stats struct {
+	LocalEnabled bool      `json:"local_enabled"`
+	Local        TierStats `json:"local"`
+	Redis        TierStats `json:"redis"`
+}
+
+func newTierStats(hits, misses uint64) TierStats {
+	stats := TierStats{Hits: hits, Misses: misses}
+	if total := hits + misses; total > 0 {
+		stats.HitRatio = float64(hits) / float64(total)
+	}
+	return stats
+}
+
+// entry is what is stored per key. FreshUntil and LoadMs are unix and
+// duration milliseconds; LoadMs is what the value took to compute and
+// scales the early refresh window.
+type entry struct {
+	Value      json.RawMessage `json:"v"`
+	FreshUntil int64           `json:"f"`
+	LoadMs     int64           `json:"l,omitempty"`
+}
+
+func (e *entry) fresh(now time.Time) bool {
+	return now.UnixMilli() < e.FreshUntil
+}
+
+func New(rdb *redis.Client, cfg config.CacheConfig, logger *zap.Logger) *Cache {
+	longest := cfg.DefaultTTL
+	for _, ttl := range []time.Duration{cfg.LeaderboardTTL, cfg.PlayerTTL, cfg.CompositionTTL, cfg.MetaTTL, cfg.MatchTTL} {
+		if ttl > longest {
+			longest = ttl
+		}
+	}
+	return &Cache{
+		rdb:    rdb,
+		cfg:    cfg,
+		logger: logger,
+		local:  newLocal(cfg.LocalMaxEntries, cfg.LocalTTL),
+		tagTTL: longest + cfg.StaleTTL,
+	}
+}
+
+// StaleTTL is how long values are served past their TTL while refreshing.
+func (c *Cache) StaleTTL() time.Duration {
+	return c.cfg.StaleTTL
+}
+
+// Stats reports hits and misses per tier. Redis lookups only happen on
+// local misses, so the Redis counters cover what the local tier let
+// through.
+func (c *Cache) Stats() Stats {
+	stats := Stats{
+		LocalEnabled: c.local != nil,
+		Local:        newTierStats(c.localHits.Load(), c.localMisses.Load()),
+		Redis:        newTierStats(c.redisHits.Load(), c.redisMisses.Load()),
+	}
+	if c.local != nil {
+		stats.Local.Entries = c.local.len()
+		stats.Local.Evictions = c.local.evictions.Load()
+	}
+	return stats
+}
+
+// TTL returns how long values under key are kept.
+func (c *Cache) TTL(key *models.CacheKey) time.Duration {
+	if key.TTL > 0 {
+		return key.TTL
+	}
+
+	switch key.Prefix {
+	case models.CacheKeyComposition, models.CacheKeyCompositionDetail:
+		return c.cfg.CompositionTTL
+	case models.CacheKeyLeaderboard:
+		return c.cfg.LeaderboardTTL
+	case models.CacheKeyPlayer:
+		return c.cfg.PlayerTTL
+	case models.CacheKeyMeta:
+		return c.cfg.MetaTTL
+	case models.CacheKeyMatch:
+		return c.cfg.MatchTTL
+	default:
+		return c.cfg.DefaultTTL
+	}
+}
+
+// Get decodes the value stored under key into dst. It returns ErrMiss if
+// there is none or it is past its TTL.
+func (c *Cache) Get(ctx context.Context, key *models.CacheKey, dst interface{}) error {
+	e, err := c.getEntry(ctx, key)
+	if err != nil {
+		return err
+	}
+	if !e.fresh(time.Now()) {
+		return ErrMiss
+	}
+
+	if err := json.Unmarshal(e.Value, dst); err != nil {
+		return fmt.Errorf("failed to decode cached %s: %w", key, err)
+	}
+	return nil
+}
+
+func (c *Cache) Set(ctx context.Context, key *models.CacheKey, value interface{}) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s for cache: %w", key, err)
+	}
+	return c.setEntry(ctx, key, raw, 0)
+}
+
+func (c *Cache) getEntry(ctx context.Context, key *models.CacheKey) (*entry, error) {
+	name := redisKey(key)
+	now := time.Now()
+
+	if c.local != nil {
+		if e := c.local.get(name, now); e != nil {
+			c.localHits.Add(1)
+			return e, nil
+		}
+		c.localMisses.Add(1)
+	}
+
+	data, err := c.rdb.Get(ctx, name).Bytes()
+	if err == redis.Nil {
+		c.redisMisses.Add(1)
+		return nil, ErrMiss
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s from cache: %w", key, err)
+	}
+	c.redisHits.Add(1)
+
+	var e entry
+	if err := decode(data, &e); err != nil {
+		return nil, fmt.Errorf("failed to decode cached %s: %w", key, err)
+	}
+	c.local.set(name, &e, tagsOf(key), now)
+	return &e, nil
+}
+
+func (c *Cache) setEntry(ctx context.Context, key *models.CacheKey, raw json.RawMessage, load time.Duration) error {
+	ttl := c.TTL(key)
+	now := time.Now()
+	e := &entry{
+		Value:      raw,
+		FreshUntil: now.Add(ttl).UnixMilli(),
+		LoadMs:     load.Milliseconds(),
+	}
+	data, err := c.encode(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s for cache: %w", key, err)
+	}
+
+	name := redisKey(key)
+	window := tagWindow(now, c.tagTTL)
+	_, err = c.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, name, data, ttl+c.cfg.StaleTTL)
+		for _, tag := range tagsOf(key) {
+			set := tagKey(tag, window)
+			pipe.SAdd(ctx, set, name)
+			pipe.ExpireAt(ctx, set, tagSetExpiry(window, c.tagTTL))
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set %s in cache: %w", key, err)
+	}
+
+	c.local.set(name, e, tagsOf(key), now)
+	return nil
+}
+
+func (c *Cache) Delete(ctx context.Context, keys ...*models.CacheKey) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	names := make([]string, len(keys))
+	for i, key := range keys {
+		names[i] = redisKey(key)
+	}
+	c.local.delete(names...)
+	if err := c.rdb.Del(ctx, names...).Err(); err != nil {
+		return fmt.Errorf("failed to delete cache keys: %w", err)
+	}
+	return nil
+}
+
+func redisKey(key *models.CacheKey) string {
+	return KeyPrefix + key.String()
+}
+
+var gzipWriters = sync.Pool{
+	New: func() interface{} { return gzip.NewWriter(nil) },
+}
+
+func (c *Cache) encode(value interface{}) ([]byte, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	if !c.cfg.CompressionEnabled || len(data) <= c.cfg.CompressionThreshold {
+		return append([]byte{encodingPlain}, data...), nil
+	}
+
+	var buf bytes.Buffer
+	buf.Grow(len(data)/4 + 1)
+	buf.WriteByte(encodingGzip)
+
+	zw := gzipWriters.Get().(*gzip.Writer)
+	defer gzipWriters.Put(zw)
+	zw.Reset(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decode(data []byte, dst interface{}) error {
+	if len(data) == 0 {
+		return errors.New("empty value")
+	}
+
+	switch data[0] {
+	case encodingPlain:
+		return json.Unmarshal(data[1:], dst)
+	case encodingGzip:
+		zr, err := gzip.NewReader(bytes.NewReader(data[1:]))
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+
+		plain, err := io.ReadAll(zr)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(plain, dst)
+	default:
+		return fmt.Errorf("unknown encoding %d", data[0])
+	}
+}
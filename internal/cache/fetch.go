@@ -0,0 +1,129 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/rsdlab-dk/tft-api/internal/models"
+	"go.uber.org/zap"
+)
+
+const (
+	// loadTimeout bounds a load once it no longer belongs to a single
+	// request: a shared miss or a background refresh.
+	loadTimeout = 30 * time.Second
+
+	// earlyRefreshBeta scales how far ahead of expiry refreshes may start;
+	// above 1 favours earlier refreshes.
+	earlyRefreshBeta = 1.0
+)
+
+// LoadFunc computes the value for a key on a miss.
+type LoadFunc func(ctx context.Context) (interface{}, error)
+
+// Fetch decodes the value for key into dst, calling load on a miss.
+//
+// Concurrent misses for one key share a single load. A value past its TTL
+// but within StaleTTL is returned as is while one goroutine refreshes it in
+// the background, and fresh values are refreshed early with a probability
+// that grows towards expiry and with the cost of the last load, so popular
+// keys are rarely seen expired at all.
+func (c *Cache) Fetch(ctx context.Context, key *models.CacheKey, dst interface{}, load LoadFunc) error {
+	e, err := c.getEntry(ctx, key)
+	if err != nil && err != ErrMiss {
+		// Redis trouble must not take reads down with it.
+		c.logger.Warn("cache read failed, loading directly", zap.String("key", key.String()), zap.Error(err))
+	}
+
+	if e != nil {
+		now := time.Now()
+		if !e.fresh(now) || refreshEarly(e, now) {
+			c.refresh(ctx, key, load)
+		}
+		if err := json.Unmarshal(e.Value, dst); err != nil {
+			return fmt.Errorf("failed to decode cached %s: %w", key, err)
+		}
+		return nil
+	}
+
+	result := c.loads.DoChan(redisKey(key), func() (interface{}, error) {
+		return c.load(context.WithoutCancel(ctx), key, load)
+	})
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case res := <-result:
+		if res.Err != nil {
+			return res.Err
+		}
+		return json.Unmarshal(res.Val.(json.RawMessage), dst)
+	}
+}
+
+// Warm loads key unless a fresh value is cached, sharing the load with
+// concurrent misses, and reports whether it loaded.
+func (c *Cache) Warm(ctx context.Context, key *models.CacheKey, load LoadFunc) (bool, error) {
+	if e, err := c.getEntry(ctx, key); err == nil && e.fresh(time.Now()) {
+		return false, nil
+	}
+
+	_, err, _ := c.loads.Do(redisKey(key), func() (interface{}, error) {
+		return c.load(ctx, key, load)
+	})
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// refresh reloads key in the background unless a load is already running.
+func (c *Cache) refresh(ctx context.Context, key *models.CacheKey, load LoadFunc) {
+	detached := context.WithoutCancel(ctx)
+	// DoChan runs the load in its own goroutine; callers that join an
+	// existing load just drop the buffered result.
+	c.loads.DoChan(redisKey(key), func() (interface{}, error) {
+		raw, err := c.load(detached, key, load)
+		if err != nil {
+			c.logger.Warn("cache refresh failed", zap.String("key", key.String()), zap.Error(err))
+		}
+		return raw, err
+	})
+}
+
+func (c *Cache) load(ctx context.Context, key *models.CacheKey, load LoadFunc) (json.RawMessage, error) {
+	ctx, cancel := context.WithTimeout(ctx, loadTimeout)
+	defer cancel()
+
+	started := time.Now()
+	value, err := load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	elapsed := time.Since(started)
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s for cache: %w", key, err)
+	}
+
+	if err := c.setEntry(ctx, key, raw, elapsed); err != nil {
+		c.logger.Warn("cache write failed", zap.String("key", key.String()), zap.Error(err))
+	}
+	return raw, nil
+}
+
+// refreshEarly implements probabilistic early expiration: the chance of
+// refreshing rises as expiry approaches and is higher for values that are
+// slow to compute.
+func refreshEarly(e *entry, now time.Time) bool {
+	if e.LoadMs <= 0 {
+		return false
+	}
+	gap := float64(e.LoadMs) * earlyRefreshBeta * -math.Log(1-rand.Float64())
+	return float64(now.UnixMilli())+gap >= float64(e.FreshUntil)
+}
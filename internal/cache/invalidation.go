@@ -0,0 +1,194 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/nats-io/nats.go"
+	"github.com/rsdlab-dk/tft-api/internal/models"
+	"go.uber.org/zap"
+)
+
+// InvalidationSubject carries Invalidation events to every API replica.
+const InvalidationSubject = "cache.invalidate"
+
+// Entries are indexed in one Redis set per tag and window, e.g.
+// cache-tag:patch=15.1:<window>, and always under their prefix, so
+// invalidation intersects a few sets instead of scanning the keyspace.
+//
+// A window is as long as the longest an entry stays in Redis. Entries are
+// added to the sets of the window they are written in, and a set expires
+// one window after its own ends, when every entry in it has expired too.
+// Sets are never refreshed past that, so they hold at most two windows'
+// writes however long traffic continues, and invalidation only looks at
+// the current and the previous window.
+const (
+	TagKeyPrefix = "cache-tag:"
+	tagPrefix    = "prefix"
+)
+
+// Invalidation evicts the entries under Prefixes that carry all of the
+// given tags; empty fields match everything. Entries without a tag of a
+// dimension, such as composition details that have no patch tag, only
+// match events that leave that dimension empty.
+type Invalidation struct {
+	Prefixes []string `json:"prefixes"`
+	Patch    string   `json:"patch,omitempty"`
+	Region   string   `json:"region,omitempty"`
+	Tier     string   `json:"tier,omitempty"`
+}
+
+func (inv Invalidation) tags() []string {
+	tags := make([]string, 0, 3)
+	if inv.Patch != "" {
+		tags = append(tags, models.CacheTag(models.CacheTagPatch, inv.Patch))
+	}
+	if inv.Region != "" {
+		tags = append(tags, models.CacheTag(models.CacheTagRegion, inv.Region))
+	}
+	if inv.Tier != "" {
+		tags = append(tags, models.CacheTag(models.CacheTagTier, inv.Tier))
+	}
+	return tags
+}
+
+func tagWindow(t time.Time, length time.Duration) int64 {
+	return t.UnixNano() / int64(length)
+}
+
+func tagKey(tag string, window int64) string {
+	return TagKeyPrefix + tag + ":" + strconv.FormatInt(window, 10)
+}
+
+func tagSetExpiry(window int64, length time.Duration) time.Time {
+	return time.Unix(0, (window+2)*int64(length))
+}
+
+func tagsOf(key *models.CacheKey) []string {
+	return append([]string{models.CacheTag(tagPrefix, key.Prefix)}, key.Tags...)
+}
+
+// Invalidate deletes the matching entries from this process and from Redis
+// and returns how many Redis keys were removed.
+func (c *Cache) Invalidate(ctx context.Context, inv Invalidation) (int, error) {
+	filters := inv.tags()
+	removed := 0
+
+	if c.local != nil {
+		match := make([][]string, len(inv.Prefixes))
+		for i, prefix := range inv.Prefixes {
+			match[i] = append([]string{models.CacheTag(tagPrefix, prefix)}, filters...)
+		}
+		c.local.invalidate(match)
+	}
+
+	current := tagWindow(time.Now(), c.tagTTL)
+	for _, prefix := range inv.Prefixes {
+		for _, window := range []int64{current - 1, current} {
+			n, err := c.evict(ctx, append([]string{models.CacheTag(tagPrefix, prefix)}, filters...), window)
+			if err != nil {
+				return removed, fmt.Errorf("failed to evict %s cache entries: %w", prefix, err)
+			}
+			removed += n
+		}
+	}
+
+	return removed, nil
+}
+
+// evict deletes the entries written in window that carry every tag.
+func (c *Cache) evict(ctx context.Context, tags []string, window int64) (int, error) {
+	sets := make([]string, len(tags))
+	for i, tag := range tags {
+		sets[i] = tagKey(tag, window)
+	}
+
+	keys, err := c.rdb.SInter(ctx, sets...).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to resolve cache tags: %w", err)
+	}
+	if len(keys) == 0 {
+		return 0, nil
+	}
+
+	members := make([]interface{}, len(keys))
+	for i, k := range keys {
+		members[i] = k
+	}
+
+	var deleted *redis.IntCmd
+	_, err = c.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		deleted = pipe.Del(ctx, keys...)
+		for _, set := range sets {
+			pipe.SRem(ctx, set, members...)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int(deleted.Val()), nil
+}
+
+// RunInvalidations applies invalidation events from NATS until ctx is
+// done.
+func (c *Cache) RunInvalidations(ctx context.Context, nc *nats.Conn) error {
+	sub, err := nc.Subscribe(InvalidationSubject, func(msg *nats.Msg) {
+		var inv Invalidation
+		if err := json.Unmarshal(msg.Data, &inv); err != nil {
+			c.logger.Warn("dropping undecodable cache invalidation", zap.Error(err))
+			return
+		}
+
+		removed, err := c.Invalidate(ctx, inv)
+		if err != nil {
+			c.logger.Error("cache invalidation failed", zap.Strings("prefixes", inv.Prefixes), zap.Error(err))
+			return
+		}
+		c.logger.Debug("cache invalidated",
+			zap.Strings("prefixes", inv.Prefixes),
+			zap.String("patch", inv.Patch),
+			zap.String("region", inv.Region),
+			zap.String("tier", inv.Tier),
+			zap.Int("removed", removed),
+		)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to cache invalidations: %w", err)
+	}
+	defer sub.Unsubscribe()
+
+	<-ctx.Done()
+	return nil
+}
+
+// Invalidator publishes invalidation events. A nil Invalidator drops
+// them, for processes that do not serve cached reads.
+type Invalidator struct {
+	nc     *nats.Conn
+	logger *zap.Logger
+}
+
+func NewInvalidator(nc *nats.Conn, logger *zap.Logger) *Invalidator {
+	return &Invalidator{nc: nc, logger: logger}
+}
+
+// Publish announces inv. Failures are logged rather than returned: the
+// entries still expire with their TTL.
+func (i *Invalidator) Publish(inv Invalidation) {
+	if i == nil || len(inv.Prefixes) == 0 {
+		return
+	}
+
+	data, err := json.Marshal(inv)
+	if err != nil {
+		return
+	}
+	if err := i.nc.Publish(InvalidationSubject, data); err != nil {
+		i.logger.Warn("failed to publish cache invalidation", zap.Strings("prefixes", inv.Prefixes), zap.Error(err))
+	}
+}
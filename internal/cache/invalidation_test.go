@@ -0,0 +1,124 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/rsdlab-dk/tft-api/internal/config"
+	"github.com/rsdlab-dk/tft-api/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func newTestCache(t *testing.T) *Cache {
+	t.Helper()
+
+	c, _ := newTestCacheWithRedis(t)
+	return c
+}
+
+func newTestCacheWithRedis(t *testing.T) (*Cache, *miniredis.Miniredis) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	return New(redis.NewClient(&redis.Options{Addr: mr.Addr()}), config.CacheConfig{
+		DefaultTTL:      time.Minute,
+		LeaderboardTTL:  time.Minute,
+		PlayerTTL:       time.Minute,
+		CompositionTTL:  time.Minute,
+		MetaTTL:         time.Minute,
+		MatchTTL:        time.Minute,
+		LocalMaxEntries: 100,
+		LocalTTL:        time.Minute,
+	}, zap.NewNop()), mr
+}
+
+func TestInvalidateEvictsOnlyMatchingTags(t *testing.T) {
+	c := newTestCache(t)
+	ctx := context.Background()
+
+	metaKR := (&models.MetaFilters{Patch: "15.1", Region: "kr", Tier: "CHALLENGER"}).CacheKey()
+	metaEUW := (&models.MetaFilters{Patch: "15.1", Region: "euw1", Tier: "CHALLENGER"}).CacheKey()
+	metaOldPatch := (&models.MetaFilters{Patch: "15.0", Region: "kr", Tier: "CHALLENGER"}).CacheKey()
+	leaderboardKR := (&models.CompositionFilters{Patch: "15.1", Region: "kr", Tier: "CHALLENGER"}).CacheKey()
+
+	keys := []*models.CacheKey{metaKR, metaEUW, metaOldPatch, leaderboardKR}
+	for _, key := range keys {
+		require.NoError(t, c.Set(ctx, key, key.String()))
+	}
+
+	removed, err := c.Invalidate(ctx, Invalidation{
+		Prefixes: []string{models.CacheKeyMeta},
+		Patch:    "15.1",
+		Region:   "kr",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+
+	var value string
+	assert.ErrorIs(t, c.Get(ctx, metaKR, &value), ErrMiss)
+	for _, key := range []*models.CacheKey{metaEUW, metaOldPatch, leaderboardKR} {
+		require.NoError(t, c.Get(ctx, key, &value), key.String())
+		assert.Equal(t, key.String(), value)
+	}
+}
+
+func TestInvalidateWithoutTagsEvictsWholePrefix(t *testing.T) {
+	c := newTestCache(t)
+	ctx := context.Background()
+
+	metaKR := (&models.MetaFilters{Patch: "15.1", Region: "kr", Tier: "CHALLENGER"}).CacheKey()
+	metaEUW := (&models.MetaFilters{Patch: "15.0", Region: "euw1", Tier: "MASTER"}).CacheKey()
+	for _, key := range []*models.CacheKey{metaKR, metaEUW} {
+		require.NoError(t, c.Set(ctx, key, key.String()))
+	}
+
+	removed, err := c.Invalidate(ctx, Invalidation{Prefixes: []string{models.CacheKeyMeta}})
+	require.NoError(t, err)
+	assert.Equal(t, 2, removed)
+
+	var value string
+	assert.ErrorIs(t, c.Get(ctx, metaKR, &value), ErrMiss)
+	assert.ErrorIs(t, c.Get(ctx, metaEUW, &value), ErrMiss)
+}
+
+func TestTagSetsExpireDespiteRewrites(t *testing.T) {
+	c, mr := newTestCacheWithRedis(t)
+	ctx := context.Background()
+
+	key := (&models.MetaFilters{Patch: "15.1", Region: "kr", Tier: "CHALLENGER"}).CacheKey()
+	require.NoError(t, c.Set(ctx, key, key.String()))
+
+	sets := mr.Keys()
+	var tagSets []string
+	for _, name := range sets {
+		if strings.HasPrefix(name, TagKeyPrefix) {
+			tagSets = append(tagSets, name)
+		}
+	}
+	require.NotEmpty(t, tagSets)
+
+	expiries := make(map[string]time.Duration, len(tagSets))
+	for _, set := range tagSets {
+		ttl := mr.TTL(set)
+		require.Positive(t, ttl, set)
+		assert.LessOrEqual(t, ttl, 2*c.tagTTL, set)
+		expiries[set] = ttl
+	}
+
+	// Rewriting the entry must not push the sets' expiry out.
+	require.NoError(t, c.Set(ctx, key, key.String()))
+	for _, set := range tagSets {
+		assert.LessOrEqual(t, mr.TTL(set), expiries[set], set)
+	}
+
+	mr.FastForward(2 * c.tagTTL)
+	for _, set := range tagSets {
+		assert.False(t, mr.Exists(set), set)
+	}
+}
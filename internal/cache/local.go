@@ -0,0 +1,162 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// local is a bounded in-process LRU in front of Redis. It holds entries for
+// at most LocalTTL and never past their freshness, so stale values and
+// early refreshes are still decided on the Redis copy.
+type local struct {
+	mu         sync.Mutex
+	maxEntries int
+	ttl        time.Duration
+	order      *list.List
+	items      map[string]*list.Element
+
+	evictions atomic.Uint64
+}
+
+type localItem struct {
+	name      string
+	entry     *entry
+	tags      []string
+	expiresAt time.Time
+}
+
+// newLocal returns nil when the tier is disabled; all methods accept a nil
+// receiver.
+func newLocal(maxEntries int, ttl time.Duration) *local {
+	if maxEntries <= 0 || ttl <= 0 {
+		return nil
+	}
+	return &local{
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		order:      list.New(),
+		items:      make(map[string]*list.Element, maxEntries),
+	}
+}
+
+func (l *local) get(name string, now time.Time) *entry {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	el, ok := l.items[name]
+	if !ok {
+		return nil
+	}
+	item := el.Value.(*localItem)
+	if !now.Before(item.expiresAt) {
+		l.remove(el)
+		return nil
+	}
+	l.order.MoveToFront(el)
+	return item.entry
+}
+
+func (l *local) set(name string, e *entry, tags []string, now time.Time) {
+	if l == nil {
+		return
+	}
+
+	expiresAt := now.Add(l.ttl)
+	if fresh := time.UnixMilli(e.FreshUntil); fresh.Before(expiresAt) {
+		expiresAt = fresh
+	}
+	if !now.Before(expiresAt) {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	item := &localItem{name: name, entry: e, tags: tags, expiresAt: expiresAt}
+	if el, ok := l.items[name]; ok {
+		el.Value = item
+		l.order.MoveToFront(el)
+		return
+	}
+
+	l.items[name] = l.order.PushFront(item)
+	for l.order.Len() > l.maxEntries {
+		l.remove(l.order.Back())
+		l.evictions.Add(1)
+	}
+}
+
+func (l *local) delete(names ...string) {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, name := range names {
+		if el, ok := l.items[name]; ok {
+			l.remove(el)
+		}
+	}
+}
+
+// invalidate drops the entries carrying every tag of any of the tag sets.
+// The tier is small enough for a full walk.
+func (l *local) invalidate(match [][]string) {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for el := l.order.Front(); el != nil; {
+		next := el.Next()
+		item := el.Value.(*localItem)
+		for _, tags := range match {
+			if hasAll(item.tags, tags) {
+				l.remove(el)
+				break
+			}
+		}
+		el = next
+	}
+}
+
+func (l *local) len() int {
+	if l == nil {
+		return 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.order.Len()
+}
+
+func (l *local) remove(el *list.Element) {
+	l.order.Remove(el)
+	delete(l.items, el.Value.(*localItem).name)
+}
+
+func hasAll(have, want []string) bool {
+	for _, w := range want {
+		found := false
+		for _, h := range have {
+			if h == w {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,140 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+)
+
+// PopularityKeyPrefix holds one sorted set per cache key prefix, scoring
+// how often each read was requested.
+const PopularityKeyPrefix = "cache-popular:"
+
+const (
+	popularityFlushInterval = 10 * time.Second
+
+	// popularityMaxMembers bounds each set; the least requested reads are
+	// trimmed on Decay.
+	popularityMaxMembers = 10000
+)
+
+// Popularity counts requests per read, e.g. per CompositionFilters, so the
+// warm-up job can pre-compute what users actually open. Counts are kept in
+// memory and flushed periodically to stay off the request path. A nil
+// Popularity records nothing.
+type Popularity struct {
+	rdb    *redis.Client
+	logger *zap.Logger
+
+	mu     sync.Mutex
+	counts map[string]map[string]float64
+}
+
+func NewPopularity(rdb *redis.Client, logger *zap.Logger) *Popularity {
+	return &Popularity{rdb: rdb, logger: logger, counts: make(map[string]map[string]float64)}
+}
+
+func popularityKey(prefix string) string {
+	return PopularityKeyPrefix + prefix
+}
+
+// Record counts one request for read under prefix. read is stored as JSON
+// and handed back by Top, so it must hold everything needed to redo the
+// read, such as the request filters.
+func (p *Popularity) Record(prefix string, read interface{}) {
+	if p == nil {
+		return
+	}
+
+	raw, err := json.Marshal(read)
+	if err != nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	counts, ok := p.counts[prefix]
+	if !ok {
+		counts = make(map[string]float64)
+		p.counts[prefix] = counts
+	}
+	counts[string(raw)]++
+}
+
+// Run flushes recorded counts until ctx is done, then flushes once more.
+func (p *Popularity) Run(ctx context.Context) {
+	ticker := time.NewTicker(popularityFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), popularityFlushInterval)
+			p.flush(flushCtx)
+			cancel()
+			return
+		case <-ticker.C:
+			p.flush(ctx)
+		}
+	}
+}
+
+func (p *Popularity) flush(ctx context.Context) {
+	p.mu.Lock()
+	counts := p.counts
+	p.counts = make(map[string]map[string]float64, len(counts))
+	p.mu.Unlock()
+
+	if len(counts) == 0 {
+		return
+	}
+
+	_, err := p.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for prefix, reads := range counts {
+			for read, n := range reads {
+				pipe.ZIncrBy(ctx, popularityKey(prefix), n, read)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		p.logger.Warn("failed to record cache popularity", zap.Error(err))
+	}
+}
+
+// Top returns up to n reads recorded under prefix, most requested first.
+func (p *Popularity) Top(ctx context.Context, prefix string, n int) ([]json.RawMessage, error) {
+	members, err := p.rdb.ZRevRange(ctx, popularityKey(prefix), 0, int64(n)-1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get popular %s reads: %w", prefix, err)
+	}
+
+	reads := make([]json.RawMessage, len(members))
+	for i, member := range members {
+		reads[i] = json.RawMessage(member)
+	}
+	return reads, nil
+}
+
+// Decay halves every score under prefix, drops reads that fall below one
+// request and trims the set, so the ranking follows what users open now
+// rather than since the set was created.
+func (p *Popularity) Decay(ctx context.Context, prefix string) error {
+	key := popularityKey(prefix)
+	_, err := p.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZUnionStore(ctx, key, &redis.ZStore{Keys: []string{key}, Weights: []float64{0.5}})
+		pipe.ZRemRangeByScore(ctx, key, "-inf", "(1")
+		pipe.ZRemRangeByRank(ctx, key, 0, -popularityMaxMembers-1)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to decay popular %s reads: %w", prefix, err)
+	}
+	return nil
+}
@@ -12,15 +12,16 @@ import (
 )
 
 type Config struct {
-	Server   ServerConfig   `validate:"required"`
-	Database DatabaseConfig `validate:"required"`
-	Redis    RedisConfig    `validate:"required"`
-	NATS     NATSConfig     `validate:"required"`
-	Riot     RiotConfig     `validate:"required"`
-	Cache    CacheConfig    `validate:"required"`
-	Jobs     JobsConfig     `validate:"required"`
-	Auth     AuthConfig     `validate:"required"`
-	Metrics  MetricsConfig  `validate:"required"`
+	Server    ServerConfig    `validate:"required"`
+	Database  DatabaseConfig  `validate:"required"`
+	Redis     RedisConfig     `validate:"required"`
+	NATS      NATSConfig      `validate:"required"`
+	Riot      RiotConfig      `validate:"required"`
+	Cache     CacheConfig     `validate:"required"`
+	Jobs      JobsConfig      `validate:"required"`
+	Retention RetentionConfig `validate:"required"`
+	Auth      AuthConfig      `validate:"required"`
+	Metrics   MetricsConfig   `validate:"required"`
 }
 
 type ServerConfig struct {
@@ -33,6 +34,8 @@ type ServerConfig struct {
 	ShutdownTimeout time.Duration `validate:"required" env:"SERVER_SHUTDOWN_TIMEOUT"`
 	MaxHeaderBytes  int           `validate:"required,min=1" env:"SERVER_MAX_HEADER_BYTES"`
 	TrustedProxies  []string      `env:"SERVER_TRUSTED_PROXIES"`
+	AllowedOrigins  []string      `env:"SERVER_ALLOWED_ORIGINS"`
+	AdminToken      string        `validate:"omitempty,min=32" env:"ADMIN_TOKEN"`
 }
 
 type DatabaseConfig struct {
@@ -69,6 +72,23 @@ type NATSConfig struct {
 	DrainTimeout  time.Duration `validate:"required" env:"NATS_DRAIN_TIMEOUT"`
 	PingInterval  time.Duration `validate:"required" env:"NATS_PING_INTERVAL"`
 	MaxPingsOut   int           `validate:"required,min=1" env:"NATS_MAX_PINGS_OUT"`
+
+	// Embedded starts an in-process nats-server with JetStream on
+	// EmbeddedHost:EmbeddedPort and connects to it instead of URLs.
+	Embedded         bool   `env:"NATS_EMBEDDED"`
+	EmbeddedHost     string `validate:"required_if=Embedded true" env:"NATS_EMBEDDED_HOST"`
+	EmbeddedPort     int    `validate:"min=-1,max=65535" env:"NATS_EMBEDDED_PORT"`
+	EmbeddedStoreDir string `env:"NATS_EMBEDDED_STORE_DIR"`
+}
+
+// ClientURLs returns the servers to connect to: the embedded server's
+// address when it is enabled, URLs otherwise. With EmbeddedPort -1 only
+// the process running the server knows its port.
+func (c NATSConfig) ClientURLs() []string {
+	if c.Embedded {
+		return []string{fmt.Sprintf("nats://%s:%d", c.EmbeddedHost, c.EmbeddedPort)}
+	}
+	return c.URLs
 }
 
 type RiotConfig struct {
@@ -76,6 +96,7 @@ type RiotConfig struct {
 	RateLimit         int           `validate:"required,min=1" env:"RIOT_RATE_LIMIT"`
 	RateLimitWindow   time.Duration `validate:"required" env:"RIOT_RATE_LIMIT_WINDOW"`
 	DefaultRegion     string        `validate:"required" env:"RIOT_DEFAULT_REGION"`
+	DefaultPatch      string        `env:"RIOT_DEFAULT_PATCH"`
 	RequestTimeout    time.Duration `validate:"required" env:"RIOT_REQUEST_TIMEOUT"`
 	MaxRetries        int           `validate:"min=0" env:"RIOT_MAX_RETRIES"`
 	RetryBackoff      time.Duration `validate:"required" env:"RIOT_RETRY_BACKOFF"`
@@ -92,19 +113,37 @@ type CacheConfig struct {
 	CleanupInterval         time.Duration `validate:"required" env:"CACHE_CLEANUP_INTERVAL"`
 	CompressionEnabled      bool          `env:"CACHE_COMPRESSION_ENABLED"`
 	CompressionThreshold    int           `validate:"min=0" env:"CACHE_COMPRESSION_THRESHOLD"`
+	StaleTTL                time.Duration `validate:"min=0" env:"CACHE_STALE_TTL"`
+	LocalMaxEntries         int           `validate:"min=0" env:"CACHE_LOCAL_MAX_ENTRIES"`
+	LocalTTL                time.Duration `validate:"min=0" env:"CACHE_LOCAL_TTL"`
+	WarmKeys                int           `validate:"min=0" env:"CACHE_WARM_KEYS"`
 }
 
 type JobsConfig struct {
 	WorkerCount                    int           `validate:"required,min=1" env:"JOBS_WORKER_COUNT"`
 	QueueBuffer                    int           `validate:"required,min=1" env:"JOBS_QUEUE_BUFFER"`
+	MaxAckPending                  int           `validate:"required,min=1" env:"JOBS_MAX_ACK_PENDING"`
 	ProcessingTimeout              time.Duration `validate:"required" env:"JOBS_PROCESSING_TIMEOUT"`
 	CollectionInterval             time.Duration `validate:"required" env:"JOBS_COLLECTION_INTERVAL"`
 	AnalysisInterval               time.Duration `validate:"required" env:"JOBS_ANALYSIS_INTERVAL"`
 	LeaderboardRefreshInterval     time.Duration `validate:"required" env:"JOBS_LEADERBOARD_REFRESH_INTERVAL"`
 	MetaUpdateInterval             time.Duration `validate:"required" env:"JOBS_META_UPDATE_INTERVAL"`
 	CleanupInterval                time.Duration `validate:"required" env:"JOBS_CLEANUP_INTERVAL"`
+	CacheWarmInterval              time.Duration `validate:"required" env:"JOBS_CACHE_WARM_INTERVAL"`
 	MaxRetries                     int           `validate:"min=0" env:"JOBS_MAX_RETRIES"`
 	RetryBackoff                   time.Duration `validate:"required" env:"JOBS_RETRY_BACKOFF"`
+	DedupeWindow                   time.Duration `validate:"required" env:"JOBS_DEDUPE_WINDOW"`
+}
+
+type RetentionConfig struct {
+	MatchesWindow        time.Duration `validate:"required" env:"RETENTION_MATCHES_WINDOW"`
+	TimelineEventsWindow time.Duration `validate:"required" env:"RETENTION_TIMELINE_EVENTS_WINDOW"`
+	CompGamesWindow      time.Duration `validate:"required" env:"RETENTION_COMP_GAMES_WINDOW"`
+	CompositionsWindow   time.Duration `validate:"required" env:"RETENTION_COMPOSITIONS_WINDOW"`
+	CompositionsMinGames int           `validate:"min=0" env:"RETENTION_COMPOSITIONS_MIN_GAMES"`
+	BatchSize            int           `validate:"required,min=1" env:"RETENTION_BATCH_SIZE"`
+	BatchPause           time.Duration `validate:"min=0" env:"RETENTION_BATCH_PAUSE"`
+	DryRun               bool          `env:"RETENTION_DRY_RUN"`
 }
 
 type AuthConfig struct {
@@ -115,6 +154,8 @@ type AuthConfig struct {
 	SessionTimeout     time.Duration `validate:"required" env:"SESSION_TIMEOUT"`
 	MaxLoginAttempts   int           `validate:"min=3" env:"MAX_LOGIN_ATTEMPTS"`
 	LockoutDuration    time.Duration `validate:"required" env:"LOCKOUT_DURATION"`
+	RiotLinkTTL        time.Duration `validate:"required" env:"RIOT_LINK_TTL"`
+	RiotChallengeTTL   time.Duration `validate:"required" env:"RIOT_LINK_CHALLENGE_TTL"`
 }
 
 type MetricsConfig struct {
@@ -146,6 +187,8 @@ func Load(envFiles ...string) (*Config, error) {
 			ShutdownTimeout: getEnvDuration("SERVER_SHUTDOWN_TIMEOUT", 30*time.Second),
 			MaxHeaderBytes:  getEnvInt("SERVER_MAX_HEADER_BYTES", 1048576),
 			TrustedProxies:  getEnvStringSlice("SERVER_TRUSTED_PROXIES"),
+			AllowedOrigins:  getEnvStringSlice("SERVER_ALLOWED_ORIGINS"),
+			AdminToken:      getEnvString("ADMIN_TOKEN", ""),
 		},
 		Database: DatabaseConfig{
 			Host:         getEnvString("DB_HOST", "localhost"),
@@ -179,12 +222,18 @@ func Load(envFiles ...string) (*Config, error) {
 			DrainTimeout:  getEnvDuration("NATS_DRAIN_TIMEOUT", 30*time.Second),
 			PingInterval:  getEnvDuration("NATS_PING_INTERVAL", 2*time.Minute),
 			MaxPingsOut:   getEnvInt("NATS_MAX_PINGS_OUT", 2),
+
+			Embedded:         getEnvBool("NATS_EMBEDDED", false),
+			EmbeddedHost:     getEnvString("NATS_EMBEDDED_HOST", "127.0.0.1"),
+			EmbeddedPort:     getEnvInt("NATS_EMBEDDED_PORT", 4222),
+			EmbeddedStoreDir: getEnvString("NATS_EMBEDDED_STORE_DIR", ""),
 		},
 		Riot: RiotConfig{
 			APIKey:          getEnvString("RIOT_API_KEY", ""),
 			RateLimit:       getEnvInt("RIOT_RATE_LIMIT", 100),
 			RateLimitWindow: getEnvDuration("RIOT_RATE_LIMIT_WINDOW", 2*time.Minute),
 			DefaultRegion:   getEnvString("RIOT_DEFAULT_REGION", "kr"),
+			DefaultPatch:    getEnvString("RIOT_DEFAULT_PATCH", ""),
 			RequestTimeout:  getEnvDuration("RIOT_REQUEST_TIMEOUT", 30*time.Second),
 			MaxRetries:      getEnvInt("RIOT_MAX_RETRIES", 3),
 			RetryBackoff:    getEnvDuration("RIOT_RETRY_BACKOFF", time.Second),
@@ -200,18 +249,35 @@ func Load(envFiles ...string) (*Config, error) {
 			CleanupInterval:      getEnvDuration("CACHE_CLEANUP_INTERVAL", 10*time.Minute),
 			CompressionEnabled:   getEnvBool("CACHE_COMPRESSION_ENABLED", true),
 			CompressionThreshold: getEnvInt("CACHE_COMPRESSION_THRESHOLD", 1024),
+			StaleTTL:             getEnvDuration("CACHE_STALE_TTL", 5*time.Minute),
+			LocalMaxEntries:      getEnvInt("CACHE_LOCAL_MAX_ENTRIES", 1000),
+			LocalTTL:             getEnvDuration("CACHE_LOCAL_TTL", 30*time.Second),
+			WarmKeys:             getEnvInt("CACHE_WARM_KEYS", 50),
 		},
 		Jobs: JobsConfig{
 			WorkerCount:                getEnvInt("JOBS_WORKER_COUNT", 5),
 			QueueBuffer:                getEnvInt("JOBS_QUEUE_BUFFER", 1000),
+			MaxAckPending:              getEnvInt("JOBS_MAX_ACK_PENDING", 1000),
 			ProcessingTimeout:          getEnvDuration("JOBS_PROCESSING_TIMEOUT", 10*time.Minute),
 			CollectionInterval:         getEnvDuration("JOBS_COLLECTION_INTERVAL", 5*time.Minute),
 			AnalysisInterval:           getEnvDuration("JOBS_ANALYSIS_INTERVAL", 30*time.Minute),
 			LeaderboardRefreshInterval: getEnvDuration("JOBS_LEADERBOARD_REFRESH_INTERVAL", 15*time.Minute),
 			MetaUpdateInterval:         getEnvDuration("JOBS_META_UPDATE_INTERVAL", time.Hour),
 			CleanupInterval:            getEnvDuration("JOBS_CLEANUP_INTERVAL", 24*time.Hour),
+			CacheWarmInterval:          getEnvDuration("JOBS_CACHE_WARM_INTERVAL", 15*time.Minute),
 			MaxRetries:                 getEnvInt("JOBS_MAX_RETRIES", 3),
 			RetryBackoff:               getEnvDuration("JOBS_RETRY_BACKOFF", 30*time.Second),
+			DedupeWindow:               getEnvDuration("JOBS_DEDUPE_WINDOW", time.Hour),
+		},
+		Retention: RetentionConfig{
+			MatchesWindow:        getEnvDuration("RETENTION_MATCHES_WINDOW", 180*24*time.Hour),
+			TimelineEventsWindow: getEnvDuration("RETENTION_TIMELINE_EVENTS_WINDOW", 30*24*time.Hour),
+			CompGamesWindow:      getEnvDuration("RETENTION_COMP_GAMES_WINDOW", 90*24*time.Hour),
+			CompositionsWindow:   getEnvDuration("RETENTION_COMPOSITIONS_WINDOW", 30*24*time.Hour),
+			CompositionsMinGames: getEnvInt("RETENTION_COMPOSITIONS_MIN_GAMES", 10),
+			BatchSize:            getEnvInt("RETENTION_BATCH_SIZE", 500),
+			BatchPause:           getEnvDuration("RETENTION_BATCH_PAUSE", 100*time.Millisecond),
+			DryRun:               getEnvBool("RETENTION_DRY_RUN", false),
 		},
 		Auth: AuthConfig{
 			JWTSecret:         getEnvString("JWT_SECRET", ""),
@@ -221,6 +287,8 @@ func Load(envFiles ...string) (*Config, error) {
 			SessionTimeout:    getEnvDuration("SESSION_TIMEOUT", 30*time.Minute),
 			MaxLoginAttempts:  getEnvInt("MAX_LOGIN_ATTEMPTS", 5),
 			LockoutDuration:   getEnvDuration("LOCKOUT_DURATION", 15*time.Minute),
+			RiotLinkTTL:       getEnvDuration("RIOT_LINK_TTL", 90*24*time.Hour),
+			RiotChallengeTTL:  getEnvDuration("RIOT_LINK_CHALLENGE_TTL", 15*time.Minute),
 		},
 		Metrics: MetricsConfig{
 			Enabled:         getEnvBool("METRICS_ENABLED", false),
@@ -275,9 +343,9 @@ func (c *Config) Validate() error {
 }
 
 func validate(config *Config) error {
-	validator := validator.New()
+	validate := validator.New()
 	
-	validator.RegisterValidation("required_if", func(fl validator.FieldLevel) bool {
+	validate.RegisterValidation("required_if", func(fl validator.FieldLevel) bool {
 		param := fl.Param()
 		parts := strings.Split(param, " ")
 		if len(parts) != 2 {
@@ -300,7 +368,7 @@ func validate(config *Config) error {
 		return true
 	})
 	
-	return validator.Struct(config)
+	return validate.Struct(config)
 }
 
 func getEnvString(key, fallback string) string {
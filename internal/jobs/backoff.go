@@ -0,0 +1,28 @@
+package jobs
+
+import (
+	"math/rand"
+	"time"
+)
+
+const maxRetryBackoff = time.Hour
+
+// Backoff returns the delay before retry number attempt (1-based): base
+// doubled per attempt, capped at maxRetryBackoff, with equal jitter so
+// that jobs failing together do not retry together.
+func Backoff(base time.Duration, attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	delay := base
+	for i := 1; i < attempt && delay < maxRetryBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryBackoff {
+		delay = maxRetryBackoff
+	}
+
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
@@ -0,0 +1,144 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/rsdlab-dk/tft-api/internal/models"
+)
+
+const (
+	// ControlCancelSubject carries the IDs of running jobs to cancel. It is
+	// a plain NATS subject outside the JOBS stream: every pool receives the
+	// request and the one running the job cancels its context.
+	ControlCancelSubject = SubjectPrefix + ".control.cancel"
+
+	// ControlBucket holds which job types are paused, one key per type, so
+	// the state survives restarts and reaches pools started later.
+	ControlBucket = "JOBS_CONTROL"
+
+	pausedKeyPrefix = "paused."
+)
+
+var ErrHistoryDisabled = errors.New("job history is not enabled")
+
+// Cancel stops a job. A queued job is marked cancelled and skipped when it
+// is delivered; a running job has its context cancelled. It returns the
+// status the job had when the request was made.
+func (q *Queue) Cancel(ctx context.Context, jobID string) (models.JobStatus, error) {
+	if q.history == nil {
+		return "", ErrHistoryDisabled
+	}
+
+	status, err := q.history.repo.RequestCancel(ctx, jobID)
+	if err != nil {
+		return status, err
+	}
+
+	if status == models.JobStatusRunning {
+		if err := q.nc.Publish(ControlCancelSubject, []byte(jobID)); err != nil {
+			return status, fmt.Errorf("failed to publish cancel for job %s: %w", jobID, err)
+		}
+	}
+
+	return status, nil
+}
+
+// Rerun enqueues a new job with the type and payload of an earlier one.
+func (q *Queue) Rerun(ctx context.Context, jobID string) (*Job, error) {
+	if q.history == nil {
+		return nil, ErrHistoryDisabled
+	}
+
+	executions, err := q.history.repo.History(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	latest := executions[len(executions)-1]
+
+	payload, err := NewPayload(latest.Type)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(latest.Payload, payload); err != nil {
+		return nil, fmt.Errorf("failed to decode payload of job %s: %w", jobID, err)
+	}
+
+	return q.Enqueue(ctx, payload)
+}
+
+func pausedKey(jobType models.JobType) string {
+	return pausedKeyPrefix + jobType.String()
+}
+
+func (q *Queue) setupControl(ctx context.Context) error {
+	kv, err := q.js.CreateOrUpdateKeyValue(ctx, jetstream.KeyValueConfig{
+		Bucket:      ControlBucket,
+		Description: "Runtime control state for job consumers",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create %s bucket: %w", ControlBucket, err)
+	}
+	q.control = kv
+	return nil
+}
+
+// Pause stops every pool from starting new jobs of jobType. Jobs already
+// running finish; queued jobs stay in the stream until Resume.
+func (q *Queue) Pause(ctx context.Context, jobType models.JobType) error {
+	at := time.Now().UTC().Format(time.RFC3339)
+	if _, err := q.control.Put(ctx, pausedKey(jobType), []byte(at)); err != nil {
+		return fmt.Errorf("failed to pause %s: %w", jobType, err)
+	}
+	return nil
+}
+
+func (q *Queue) Resume(ctx context.Context, jobType models.JobType) error {
+	if err := q.control.Delete(ctx, pausedKey(jobType)); err != nil {
+		return fmt.Errorf("failed to resume %s: %w", jobType, err)
+	}
+	return nil
+}
+
+// Paused returns the paused job types and when they were paused.
+func (q *Queue) Paused(ctx context.Context) (map[models.JobType]time.Time, error) {
+	paused := make(map[models.JobType]time.Time)
+
+	keys, err := q.control.Keys(ctx)
+	if errors.Is(err, jetstream.ErrNoKeysFound) {
+		return paused, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list paused job types: %w", err)
+	}
+
+	for _, key := range keys {
+		if !strings.HasPrefix(key, pausedKeyPrefix) {
+			continue
+		}
+		entry, err := q.control.Get(ctx, key)
+		if errors.Is(err, jetstream.ErrKeyNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", key, err)
+		}
+		at, _ := time.Parse(time.RFC3339, string(entry.Value()))
+		paused[models.JobType(strings.TrimPrefix(key, pausedKeyPrefix))] = at
+	}
+
+	return paused, nil
+}
+
+func (q *Queue) watchPaused(ctx context.Context) (jetstream.KeyWatcher, error) {
+	watcher, err := q.control.Watch(ctx, pausedKeyPrefix+"*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch paused job types: %w", err)
+	}
+	return watcher, nil
+}
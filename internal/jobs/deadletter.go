@@ -0,0 +1,193 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/rsdlab-dk/tft-api/internal/models"
+)
+
+const (
+	DeadLetterStreamName    = "JOBS_DEAD_LETTER"
+	DeadLetterSubjectPrefix = "deadletter.jobs"
+
+	deadLetterRetention = 30 * 24 * time.Hour
+	deadLetterFetchWait = 2 * time.Second
+)
+
+var ErrDeadLetterNotFound = errors.New("dead letter not found")
+
+func DeadLetterSubject(jobType models.JobType) string {
+	return DeadLetterSubjectPrefix + "." + jobType.String()
+}
+
+type DeadLetter struct {
+	Sequence       uint64    `json:"sequence"`
+	Job            *Job      `json:"job"`
+	DeadLetteredAt time.Time `json:"dead_lettered_at"`
+}
+
+func (q *Queue) setupDeadLetters(ctx context.Context) error {
+	_, err := q.js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:        DeadLetterStreamName,
+		Description: "Jobs that failed permanently or ran out of retries",
+		Subjects:    []string{DeadLetterSubjectPrefix + ".*"},
+		Retention:   jetstream.LimitsPolicy,
+		Storage:     jetstream.FileStorage,
+		MaxAge:      deadLetterRetention,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create %s stream: %w", DeadLetterStreamName, err)
+	}
+	return nil
+}
+
+func (q *Queue) DeadLetter(ctx context.Context, job *Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to encode job %s: %w", job.ID, err)
+	}
+
+	if _, err := q.js.Publish(ctx, DeadLetterSubject(job.Type), data); err != nil {
+		return fmt.Errorf("failed to dead-letter %s job %s: %w", job.Type, job.ID, err)
+	}
+	return nil
+}
+
+// ListDeadLetters returns up to limit dead-lettered jobs, oldest first. An
+// empty jobType lists every type.
+func (q *Queue) ListDeadLetters(ctx context.Context, jobType models.JobType, limit int) ([]DeadLetter, error) {
+	filter := DeadLetterSubjectPrefix + ".*"
+	if jobType != "" {
+		filter = DeadLetterSubject(jobType)
+	}
+
+	consumer, err := q.js.OrderedConsumer(ctx, DeadLetterStreamName, jetstream.OrderedConsumerConfig{
+		FilterSubjects: []string{filter},
+		DeliverPolicy:  jetstream.DeliverAllPolicy,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dead letters: %w", err)
+	}
+
+	info, err := consumer.Info(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dead letters: %w", err)
+	}
+
+	pending := int(info.NumPending)
+	if pending > limit {
+		pending = limit
+	}
+
+	letters := make([]DeadLetter, 0, pending)
+	if pending == 0 {
+		return letters, nil
+	}
+
+	batch, err := consumer.Fetch(pending, jetstream.FetchMaxWait(deadLetterFetchWait))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch dead letters: %w", err)
+	}
+
+	for msg := range batch.Messages() {
+		letter, err := decodeDeadLetter(msg)
+		if err != nil {
+			return nil, err
+		}
+		letters = append(letters, letter)
+	}
+
+	if err := batch.Error(); err != nil && !errors.Is(err, jetstream.ErrNoMessages) {
+		return nil, fmt.Errorf("failed to fetch dead letters: %w", err)
+	}
+
+	return letters, nil
+}
+
+// ReplayDeadLetter re-enqueues a dead-lettered job with a fresh retry
+// budget and removes it from the dead-letter stream. The attempt counter
+// and error history are kept so repeated failures remain visible.
+func (q *Queue) ReplayDeadLetter(ctx context.Context, sequence uint64) (*Job, error) {
+	stream, err := q.js.Stream(ctx, DeadLetterStreamName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s stream: %w", DeadLetterStreamName, err)
+	}
+
+	raw, err := stream.GetMsg(ctx, sequence)
+	if errors.Is(err, jetstream.ErrMsgNotFound) {
+		return nil, fmt.Errorf("%w: sequence %d", ErrDeadLetterNotFound, sequence)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load dead letter %d: %w", sequence, err)
+	}
+
+	job, err := decodeJob(raw.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	job.Attempt++
+	job.RetryFrom = job.Attempt
+	job.NotBefore = time.Time{}
+
+	// An equivalent job enqueued since the failure already covers the
+	// replay; drop the dead letter without publishing.
+	pendingID, err := q.deduper.claim(ctx, job)
+	if err != nil {
+		return nil, err
+	}
+	if pendingID != job.ID {
+		job.ID = pendingID
+		job.Merged = true
+	} else if err := q.Publish(ctx, job); err != nil {
+		q.deduper.release(context.WithoutCancel(ctx), job)
+		return nil, err
+	}
+
+	if err := stream.DeleteMsg(ctx, sequence); err != nil {
+		return nil, fmt.Errorf("failed to remove replayed dead letter %d: %w", sequence, err)
+	}
+
+	return job, nil
+}
+
+func (q *Queue) ReplayDeadLetters(ctx context.Context, jobType models.JobType, limit int) ([]*Job, error) {
+	letters, err := q.ListDeadLetters(ctx, jobType, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	replayed := make([]*Job, 0, len(letters))
+	for _, letter := range letters {
+		job, err := q.ReplayDeadLetter(ctx, letter.Sequence)
+		if err != nil {
+			return replayed, err
+		}
+		replayed = append(replayed, job)
+	}
+
+	return replayed, nil
+}
+
+func decodeDeadLetter(msg jetstream.Msg) (DeadLetter, error) {
+	meta, err := msg.Metadata()
+	if err != nil {
+		return DeadLetter{}, fmt.Errorf("failed to read dead letter metadata: %w", err)
+	}
+
+	job, err := decodeJob(msg.Data())
+	if err != nil {
+		return DeadLetter{}, err
+	}
+
+	return DeadLetter{
+		Sequence:       meta.Sequence.Stream,
+		Job:            job,
+		DeadLetteredAt: meta.Timestamp,
+	}, nil
+}
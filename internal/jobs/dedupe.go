@@ -0,0 +1,79 @@
+package jobs
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/rsdlab-dk/tft-api/internal/models"
+)
+
+const dedupeKeyPrefix = "jobs:dedupe:"
+
+// IdempotencyKey identifies the work a job does: two jobs with the same
+// type and payload are duplicates.
+func IdempotencyKey(jobType models.JobType, payload []byte) string {
+	sum := sha256.New()
+	sum.Write([]byte(jobType))
+	sum.Write([]byte{0})
+	sum.Write(payload)
+	return hex.EncodeToString(sum.Sum(nil))
+}
+
+// Deduper holds a Redis claim per idempotency key while a job is pending.
+// The claim is released when the job reaches a final outcome; the window
+// only bounds how long a claim can outlive a lost worker.
+type Deduper struct {
+	client *redis.Client
+	window time.Duration
+}
+
+func NewDeduper(client *redis.Client, window time.Duration) *Deduper {
+	return &Deduper{client: client, window: window}
+}
+
+// claim registers job as the pending job for its key. If another job
+// already holds the key its ID is returned instead.
+func (d *Deduper) claim(ctx context.Context, job *Job) (string, error) {
+	if d == nil || job.Key == "" {
+		return job.ID, nil
+	}
+
+	key := dedupeKeyPrefix + job.Key
+	for i := 0; i < 2; i++ {
+		claimed, err := d.client.SetNX(ctx, key, job.ID, d.window).Result()
+		if err != nil {
+			return "", fmt.Errorf("failed to claim idempotency key: %w", err)
+		}
+		if claimed {
+			return job.ID, nil
+		}
+
+		existing, err := d.client.Get(ctx, key).Result()
+		if errors.Is(err, redis.Nil) {
+			// Released between SETNX and GET; try again.
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read idempotency key: %w", err)
+		}
+		return existing, nil
+	}
+
+	return "", errors.New("failed to claim idempotency key: claim kept changing")
+}
+
+// release drops the claim if job still holds it.
+func (d *Deduper) release(ctx context.Context, job *Job) error {
+	if d == nil || job.Key == "" {
+		return nil
+	}
+	if err := releaseLockScript.Run(ctx, d.client, []string{dedupeKeyPrefix + job.Key}, job.ID).Err(); err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
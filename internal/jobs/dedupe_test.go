@@ -0,0 +1,39 @@
+package jobs
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/rsdlab-dk/tft-api/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnqueueMergesDuplicateCollectPlayer(t *testing.T) {
+	ctx := context.Background()
+	queue, err := NewQueue(ctx, connectTestServer(t), testJobsConfig())
+	require.NoError(t, err)
+	mr := miniredis.RunT(t)
+	queue.SetDeduper(NewDeduper(redis.NewClient(&redis.Options{Addr: mr.Addr()}), time.Minute))
+
+	puuid := strings.Repeat("a", 78)
+	first, err := queue.Enqueue(ctx, CollectPlayerPayload{PUUID: puuid, Region: models.RegionKR})
+	require.NoError(t, err)
+	assert.False(t, first.Merged)
+
+	// The ladder collector and the crawler both asking for the player.
+	second, err := queue.Enqueue(ctx, CollectPlayerPayload{PUUID: puuid, Region: models.RegionKR})
+	require.NoError(t, err)
+	assert.True(t, second.Merged)
+	assert.Equal(t, first.ID, second.ID)
+
+	other, err := queue.Enqueue(ctx, CollectPlayerPayload{PUUID: strings.Repeat("b", 78), Region: models.RegionKR})
+	require.NoError(t, err)
+	assert.False(t, other.Merged)
+
+	assert.Equal(t, uint64(2), depthOf(t, queue, models.JobTypeCollectPlayer).Pending)
+}
@@ -0,0 +1,90 @@
+package jobs
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/lib/pq"
+)
+
+var ErrInvalidPayload = errors.New("invalid job payload")
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent marks err as not worth retrying; the job goes straight to the
+// dead-letter stream.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// StatusError carries the HTTP status of a failed upstream call, such as a
+// Riot API request, so it can be classified.
+type StatusError struct {
+	Code int
+	Err  error
+}
+
+func NewStatusError(code int, err error) *StatusError {
+	return &StatusError{Code: code, Err: err}
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("upstream returned %d: %v", e.Code, e.Err)
+}
+
+func (e *StatusError) Unwrap() error {
+	return e.Err
+}
+
+func (e *StatusError) StatusCode() int {
+	return e.Code
+}
+
+// IsRetryable classifies a handler error. Rate limits, upstream 5xx,
+// timeouts and database serialization failures are retried; missing
+// resources, bad requests, constraint violations and malformed payloads are
+// not. Unknown errors are retried.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var permanent *permanentError
+	if errors.As(err, &permanent) || errors.Is(err, ErrInvalidPayload) {
+		return false
+	}
+
+	var status interface{ StatusCode() int }
+	if errors.As(err, &status) {
+		code := status.StatusCode()
+		return code == http.StatusTooManyRequests || code == http.StatusRequestTimeout || code >= 500
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Class() {
+		case "40", "53", "57", "08":
+			// transaction rollback (serialization, deadlock), insufficient
+			// resources, operator intervention, connection exceptions
+			return true
+		default:
+			return false
+		}
+	}
+
+	return true
+}
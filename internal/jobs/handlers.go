@@ -0,0 +1,272 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+
+	"github.com/rsdlab-dk/tft-api/internal/cache"
+	"github.com/rsdlab-dk/tft-api/internal/models"
+	"github.com/rsdlab-dk/tft-api/internal/repository"
+	"github.com/rsdlab-dk/tft-api/internal/retention"
+	"github.com/rsdlab-dk/tft-api/internal/riot"
+	"github.com/rsdlab-dk/tft-api/internal/warmup"
+	"go.uber.org/zap"
+)
+
+// leaderboardCachePrefixes are the cached reads derived from the
+// composition leaderboard, which meta snapshots embed as well.
+var leaderboardCachePrefixes = []string{
+	models.CacheKeyLeaderboard,
+	models.CacheKeyComposition,
+	models.CacheKeyCompositionDetail,
+	models.CacheKeyMeta,
+}
+
+// reattributeBatchSize caps the matches retried for rank attribution per
+// analyze run.
+const reattributeBatchSize = 1000
+
+// NewCollectChallengerHandler enqueues a collect_player job per player on
+// the challenger ladder. A retry after a partial run enqueues the ladder
+// again; the deduper merges the players already queued.
+func NewCollectChallengerHandler(client *riot.Client, queue *Queue, logger *zap.Logger) Handler {
+	return HandlerFunc(func(ctx context.Context, job *Job) error {
+		var payload CollectChallengerPayload
+		if err := job.Decode(&payload); err != nil {
+			return err
+		}
+
+		league, err := client.ChallengerLeague(ctx, payload.Region)
+		if err != nil {
+			return riotError(err)
+		}
+
+		progress := ProgressFrom(ctx)
+		progress.SetTotal(len(league.Entries))
+
+		merged := 0
+		for _, entry := range league.Entries {
+			player, err := queue.Enqueue(ctx, CollectPlayerPayload{PUUID: entry.PUUID, Region: payload.Region})
+			if err != nil {
+				return err
+			}
+			if player.Merged {
+				merged++
+			}
+			progress.Add(1)
+		}
+
+		logger.Info("challenger ladder collected",
+			zap.String("region", payload.Region.String()),
+			zap.Int("players", len(league.Entries)),
+			zap.Int("merged", merged),
+		)
+		return nil
+	})
+}
+
+// NewCollectPlayerHandler stores the current Riot ID and summoner profile
+// of a player in players.summoners.
+func NewCollectPlayerHandler(client *riot.Client, summoners *repository.SummonerRepository) Handler {
+	return HandlerFunc(func(ctx context.Context, job *Job) error {
+		var payload CollectPlayerPayload
+		if err := job.Decode(&payload); err != nil {
+			return err
+		}
+
+		account, err := client.AccountByPUUID(ctx, payload.Region, payload.PUUID)
+		if err != nil {
+			return riotError(err)
+		}
+		summoner, err := client.SummonerByPUUID(ctx, payload.Region, payload.PUUID)
+		if err != nil {
+			return riotError(err)
+		}
+
+		_, err = summoners.Upsert(ctx, &models.Summoner{
+			PUUID:         summoner.PUUID,
+			SummonerID:    summoner.ID,
+			AccountID:     summoner.AccountID,
+			Name:          account.GameName,
+			GameName:      account.GameName,
+			TagLine:       account.TagLine,
+			ProfileIconID: summoner.ProfileIconID,
+			SummonerLevel: summoner.SummonerLevel,
+			Region:        payload.Region,
+			RevisionDate:  summoner.RevisionDate,
+		})
+		return err
+	})
+}
+
+// riotError marks lookups of players or ladders the Riot API does not know
+// as permanent; retrying them cannot succeed.
+func riotError(err error) error {
+	if errors.Is(err, riot.ErrNotFound) {
+		return Permanent(err)
+	}
+	return err
+}
+
+func NewAnalyzeCompositionsHandler(comps *repository.CompositionRepository, matches *repository.MatchRepository, invalidator *cache.Invalidator, logger *zap.Logger) Handler {
+	return HandlerFunc(func(ctx context.Context, job *Job) error {
+		var payload AnalyzeCompositionsPayload
+		if err := job.Decode(&payload); err != nil {
+			return err
+		}
+
+		// Ranks found since the last run move boards into their tier
+		// before pick rates are computed.
+		reattributed, err := matches.ReattributeUnranked(ctx, reattributeBatchSize)
+		if err != nil {
+			return err
+		}
+
+		// The counters are kept by the comp_games triggers; a rebuild only
+		// fixes drift, so it runs when asked for.
+		var corrected int
+		if payload.Repair {
+			n, err := comps.RebuildStats(ctx)
+			if err != nil {
+				return err
+			}
+			corrected = n
+		}
+
+		updated, err := comps.RecalculatePickRates(ctx, payload.Patch.String(), payload.Region.String())
+		if err != nil {
+			return err
+		}
+
+		logger.Info("compositions analyzed",
+			zap.Int("matches_reattributed", reattributed),
+			zap.Bool("repair", payload.Repair),
+			zap.Int("stats_corrected", corrected),
+			zap.Int("pick_rates_updated", updated),
+		)
+
+		inv := cache.Invalidation{
+			Prefixes: leaderboardCachePrefixes,
+			Patch:    payload.Patch.String(),
+			Region:   payload.Region.String(),
+		}
+		// Reattribution and repair are not limited to the payload's patch
+		// and region.
+		if reattributed > 0 || corrected > 0 {
+			inv = cache.Invalidation{Prefixes: leaderboardCachePrefixes}
+		}
+		if reattributed > 0 || corrected > 0 || updated > 0 {
+			invalidator.Publish(inv)
+		}
+		return nil
+	})
+}
+
+func NewRefreshLeaderboardHandler(comps *repository.CompositionRepository, invalidator *cache.Invalidator) Handler {
+	return HandlerFunc(func(ctx context.Context, job *Job) error {
+		var payload RefreshLeaderboardPayload
+		if err := job.Decode(&payload); err != nil {
+			return err
+		}
+		if err := comps.RefreshLeaderboard(ctx); err != nil {
+			return err
+		}
+
+		invalidator.Publish(cache.Invalidation{Prefixes: leaderboardCachePrefixes})
+		return nil
+	})
+}
+
+func NewUpdateMetaDataHandler(analytics *repository.AnalyticsRepository, invalidator *cache.Invalidator, logger *zap.Logger) Handler {
+	return HandlerFunc(func(ctx context.Context, job *Job) error {
+		var payload UpdateMetaDataPayload
+		if err := job.Decode(&payload); err != nil {
+			return err
+		}
+
+		buckets, err := analytics.UpdateMetaFromMatches(ctx, payload.Patch.String(), payload.Region.String(), payload.Full)
+		if err != nil {
+			return err
+		}
+
+		logger.Info("meta updated",
+			zap.String("patch", payload.Patch.String()),
+			zap.String("region", payload.Region.String()),
+			zap.Bool("full", payload.Full),
+			zap.Int("buckets", buckets),
+		)
+
+		if buckets > 0 {
+			invalidator.Publish(cache.Invalidation{
+				Prefixes: []string{models.CacheKeyMeta},
+				Patch:    payload.Patch.String(),
+				Region:   payload.Region.String(),
+			})
+		}
+		return nil
+	})
+}
+
+func NewCleanupHandler(runner *retention.Runner, invalidator *cache.Invalidator, logger *zap.Logger) Handler {
+	return HandlerFunc(func(ctx context.Context, job *Job) error {
+		var payload CleanupCachePayload
+		if err := job.Decode(&payload); err != nil {
+			return err
+		}
+
+		progress := ProgressFrom(ctx)
+		report, err := runner.Run(ctx, payload.DryRun, func(done, total int, target retention.TargetReport) {
+			progress.SetTotal(total)
+			progress.Add(1)
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, target := range report.Targets {
+			logger.Info("retention applied",
+				zap.String("target", target.Name),
+				zap.Duration("window", target.Window),
+				zap.Int("deleted", target.Deleted),
+				zap.Int("batches", target.Batches),
+				zap.Duration("duration", target.Duration),
+				zap.Bool("dry_run", report.DryRun),
+			)
+		}
+
+		if !report.DryRun && report.Deleted() > 0 {
+			invalidator.Publish(cache.Invalidation{Prefixes: leaderboardCachePrefixes})
+		}
+		return nil
+	})
+}
+
+func NewWarmCacheHandler(runner *warmup.Runner, logger *zap.Logger) Handler {
+	return HandlerFunc(func(ctx context.Context, job *Job) error {
+		var payload WarmCachePayload
+		if err := job.Decode(&payload); err != nil {
+			return err
+		}
+
+		progress := ProgressFrom(ctx)
+		report, err := runner.Run(ctx, payload.Limit, func(done, total int) {
+			progress.SetTotal(total)
+			progress.Add(1)
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, target := range report.Targets {
+			logger.Info("cache warmed",
+				zap.String("prefix", target.Prefix),
+				zap.Int("candidates", target.Candidates),
+				zap.Int("warmed", target.Warmed),
+				zap.Int("fresh", target.Fresh),
+				zap.Int("failed", target.Failed),
+				zap.Duration("duration", target.Duration),
+			)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,69 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/rsdlab-dk/tft-api/internal/models"
+	"github.com/rsdlab-dk/tft-api/internal/repository"
+	"go.uber.org/zap"
+)
+
+// History records job executions in jobs.executions. Recording is best
+// effort: a database outage is logged but never blocks or fails a job.
+type History struct {
+	repo   *repository.JobRepository
+	logger *zap.Logger
+}
+
+func NewHistory(repo *repository.JobRepository, logger *zap.Logger) *History {
+	return &History{repo: repo, logger: logger}
+}
+
+func (h *History) queued(ctx context.Context, job *Job) {
+	if h == nil {
+		return
+	}
+	if err := h.repo.RecordQueued(ctx, executionOf(job)); err != nil {
+		h.logger.Warn("failed to record queued job", zap.String("job_id", job.ID), zap.Error(err))
+	}
+}
+
+// started reports whether the attempt should run.
+func (h *History) started(ctx context.Context, job *Job) bool {
+	if h == nil {
+		return true
+	}
+	run, err := h.repo.RecordStarted(ctx, executionOf(job))
+	if err != nil {
+		h.logger.Warn("failed to record started job", zap.String("job_id", job.ID), zap.Error(err))
+		return true
+	}
+	return run
+}
+
+func (h *History) finished(ctx context.Context, job *Job, status models.JobStatus, jobErr error, retryable *bool, duration time.Duration) {
+	if h == nil {
+		return
+	}
+	// Record even when the worker is shutting down.
+	ctx = context.WithoutCancel(ctx)
+	if err := h.repo.RecordFinished(ctx, job.ID, job.Attempt, status, jobErr, retryable, duration); err != nil {
+		h.logger.Warn("failed to record finished job", zap.String("job_id", job.ID), zap.Error(err))
+	}
+}
+
+func executionOf(job *Job) *models.JobExecution {
+	exec := &models.JobExecution{
+		JobID:      job.ID,
+		Type:       job.Type,
+		Payload:    job.Payload,
+		Attempt:    job.Attempt,
+		EnqueuedAt: job.EnqueuedAt,
+	}
+	if !job.NotBefore.IsZero() {
+		notBefore := job.NotBefore
+		exec.NotBefore = &notBefore
+	}
+	return exec
+}
@@ -0,0 +1,92 @@
+package jobs
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rsdlab-dk/tft-api/internal/models"
+)
+
+type Job struct {
+	ID         string          `json:"id"`
+	Type       models.JobType  `json:"type"`
+	Key        string          `json:"key,omitempty"`
+	Payload    json.RawMessage `json:"payload"`
+	EnqueuedAt time.Time       `json:"enqueued_at"`
+	Attempt    int             `json:"attempt"`
+	RetryFrom  int             `json:"retry_from,omitempty"`
+	NotBefore  time.Time       `json:"not_before,omitempty"`
+	Errors     []JobError      `json:"errors,omitempty"`
+
+	// Merged is set by Enqueue when the job duplicated a pending one; ID
+	// is then the pending job's.
+	Merged bool `json:"merged,omitempty"`
+}
+
+type JobError struct {
+	Attempt   int       `json:"attempt"`
+	Message   string    `json:"message"`
+	Retryable bool      `json:"retryable"`
+	At        time.Time `json:"at"`
+}
+
+func NewJob(payload Payload) (*Job, error) {
+	jobType := payload.JobType()
+	if err := payload.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid %s payload: %w", jobType, err)
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s payload: %w", jobType, err)
+	}
+
+	return &Job{
+		ID:         uuid.NewString(),
+		Type:       jobType,
+		Key:        IdempotencyKey(jobType, data),
+		Payload:    data,
+		EnqueuedAt: time.Now().UTC(),
+	}, nil
+}
+
+func (j *Job) Decode(payload Payload) error {
+	if err := json.Unmarshal(j.Payload, payload); err != nil {
+		return fmt.Errorf("%w: failed to decode %s payload: %v", ErrInvalidPayload, j.Type, err)
+	}
+	if payload.JobType() != j.Type {
+		return fmt.Errorf("%w: payload for %s decoded from %s job", ErrInvalidPayload, payload.JobType(), j.Type)
+	}
+	if err := payload.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPayload, err)
+	}
+	return nil
+}
+
+// retries returns how many retries the job has used since it was enqueued
+// or last replayed from the dead-letter stream.
+func (j *Job) retries() int {
+	return j.Attempt - j.RetryFrom
+}
+
+func (j *Job) recordError(err error, retryable bool) {
+	j.Errors = append(j.Errors, JobError{
+		Attempt:   j.Attempt,
+		Message:   err.Error(),
+		Retryable: retryable,
+		At:        time.Now().UTC(),
+	})
+}
+
+func decodeJob(data []byte) (*Job, error) {
+	var job Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, fmt.Errorf("failed to decode job: %w", err)
+	}
+	if !job.Type.IsValid() {
+		return nil, fmt.Errorf("invalid job type: %s", job.Type)
+	}
+	return &job, nil
+}
@@ -0,0 +1,71 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+var (
+	renewLockScript = redis.NewScript(`
+		if redis.call("GET", KEYS[1]) == ARGV[1] then
+			return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+		end
+		return 0`)
+
+	releaseLockScript = redis.NewScript(`
+		if redis.call("GET", KEYS[1]) == ARGV[1] then
+			return redis.call("DEL", KEYS[1])
+		end
+		return 0`)
+)
+
+// Leader is a Redis lease: the holder must renew it before ttl elapses or
+// another replica may take over.
+type Leader struct {
+	client *redis.Client
+	key    string
+	id     string
+	ttl    time.Duration
+}
+
+func NewLeader(client *redis.Client, key string, ttl time.Duration) *Leader {
+	return &Leader{
+		client: client,
+		key:    key,
+		id:     uuid.NewString(),
+		ttl:    ttl,
+	}
+}
+
+func (l *Leader) ID() string {
+	return l.id
+}
+
+// Campaign acquires the lease if it is free or renews it if already held,
+// and reports whether this instance is the leader afterwards.
+func (l *Leader) Campaign(ctx context.Context) (bool, error) {
+	renewed, err := renewLockScript.Run(ctx, l.client, []string{l.key}, l.id, l.ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to renew leader lease: %w", err)
+	}
+	if renewed == 1 {
+		return true, nil
+	}
+
+	acquired, err := l.client.SetNX(ctx, l.key, l.id, l.ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire leader lease: %w", err)
+	}
+	return acquired, nil
+}
+
+func (l *Leader) Resign(ctx context.Context) error {
+	if err := releaseLockScript.Run(ctx, l.client, []string{l.key}, l.id).Err(); err != nil {
+		return fmt.Errorf("failed to release leader lease: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package jobs
+
+import (
+	"fmt"
+
+	"github.com/rsdlab-dk/tft-api/internal/models"
+)
+
+type Payload interface {
+	JobType() models.JobType
+	Validate() error
+}
+
+// CollectChallengerPayload fetches the challenger ladder of Region and
+// enqueues a collect_player job for every player on it.
+type CollectChallengerPayload struct {
+	Region models.Region `json:"region"`
+}
+
+func (p CollectChallengerPayload) JobType() models.JobType {
+	return models.JobTypeCollectChallenger
+}
+
+func (p CollectChallengerPayload) Validate() error {
+	if !p.Region.IsValid() {
+		return fmt.Errorf("invalid region: %s", p.Region)
+	}
+	return nil
+}
+
+// CollectPlayerPayload refreshes the summoner of PUUID on Region.
+type CollectPlayerPayload struct {
+	PUUID  string        `json:"puuid"`
+	Region models.Region `json:"region"`
+}
+
+func (p CollectPlayerPayload) JobType() models.JobType {
+	return models.JobTypeCollectPlayer
+}
+
+func (p CollectPlayerPayload) Validate() error {
+	if len(p.PUUID) != 78 {
+		return fmt.Errorf("invalid puuid: %q", p.PUUID)
+	}
+	if !p.Region.IsValid() {
+		return fmt.Errorf("invalid region: %s", p.Region)
+	}
+	return nil
+}
+
+// AnalyzeCompositionsPayload recalculates pick rates. Repair also rebuilds
+// every comp's counters from comp_games first, which scans the whole table.
+type AnalyzeCompositionsPayload struct {
+	Patch  models.Patch  `json:"patch,omitempty"`
+	Region models.Region `json:"region,omitempty"`
+	Repair bool          `json:"repair,omitempty"`
+}
+
+func (p AnalyzeCompositionsPayload) JobType() models.JobType {
+	return models.JobTypeAnalyzeCompositions
+}
+
+func (p AnalyzeCompositionsPayload) Validate() error {
+	if p.Patch != "" && !p.Patch.IsValid() {
+		return fmt.Errorf("invalid patch: %s", p.Patch)
+	}
+	if p.Region != "" && !p.Region.IsValid() {
+		return fmt.Errorf("invalid region: %s", p.Region)
+	}
+	return nil
+}
+
+// RefreshLeaderboardPayload rebuilds the leaderboard view, which covers
+// every region at once.
+type RefreshLeaderboardPayload struct{}
+
+func (p RefreshLeaderboardPayload) JobType() models.JobType {
+	return models.JobTypeRefreshLeaderboard
+}
+
+func (p RefreshLeaderboardPayload) Validate() error {
+	return nil
+}
+
+// CleanupCachePayload runs the retention policy. DryRun only counts what
+// would be deleted.
+type CleanupCachePayload struct {
+	DryRun bool `json:"dry_run,omitempty"`
+}
+
+func (p CleanupCachePayload) JobType() models.JobType {
+	return models.JobTypeCleanupCache
+}
+
+func (p CleanupCachePayload) Validate() error {
+	return nil
+}
+
+// UpdateMetaDataPayload recomputes the meta buckets whose source data
+// changed, limited to Patch and Region when they are set. Full recomputes
+// every bucket of Patch, or of all active patches.
+type UpdateMetaDataPayload struct {
+	Patch  models.Patch  `json:"patch,omitempty"`
+	Region models.Region `json:"region,omitempty"`
+	Full   bool          `json:"full,omitempty"`
+}
+
+func (p UpdateMetaDataPayload) JobType() models.JobType {
+	return models.JobTypeUpdateMetaData
+}
+
+func (p UpdateMetaDataPayload) Validate() error {
+	if p.Patch != "" && !p.Patch.IsValid() {
+		return fmt.Errorf("invalid patch: %s", p.Patch)
+	}
+	if p.Region != "" && !p.Region.IsValid() {
+		return fmt.Errorf("invalid region: %s", p.Region)
+	}
+	return nil
+}
+
+// WarmCachePayload pre-computes the most requested cached reads. Limit
+// overrides CACHE_WARM_KEYS, the number of reads per cache key prefix.
+type WarmCachePayload struct {
+	Limit int `json:"limit,omitempty"`
+}
+
+func (p WarmCachePayload) JobType() models.JobType {
+	return models.JobTypeWarmCache
+}
+
+func (p WarmCachePayload) Validate() error {
+	if p.Limit < 0 {
+		return fmt.Errorf("invalid limit: %d", p.Limit)
+	}
+	return nil
+}
+
+// NewPayload returns an empty payload for the job type, for decoding.
+func NewPayload(jobType models.JobType) (Payload, error) {
+	switch jobType {
+	case models.JobTypeCollectChallenger:
+		return &CollectChallengerPayload{}, nil
+	case models.JobTypeCollectPlayer:
+		return &CollectPlayerPayload{}, nil
+	case models.JobTypeAnalyzeCompositions:
+		return &AnalyzeCompositionsPayload{}, nil
+	case models.JobTypeRefreshLeaderboard:
+		return &RefreshLeaderboardPayload{}, nil
+	case models.JobTypeCleanupCache:
+		return &CleanupCachePayload{}, nil
+	case models.JobTypeUpdateMetaData:
+		return &UpdateMetaDataPayload{}, nil
+	case models.JobTypeWarmCache:
+		return &WarmCachePayload{}, nil
+	default:
+		return nil, fmt.Errorf("invalid job type: %s", jobType)
+	}
+}
@@ -0,0 +1,183 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/rsdlab-dk/tft-api/internal/models"
+	"go.uber.org/zap"
+)
+
+// ProgressSubjectPrefix is where running jobs publish progress, on
+// jobs.progress.<type>.<region>. Jobs without a region use "all". Like
+// results these are core NATS messages for live views only.
+const ProgressSubjectPrefix = SubjectPrefix + ".progress"
+
+const (
+	progressAllRegions = "all"
+
+	// progressInterval throttles updates from tight loops; status changes
+	// are always published.
+	progressInterval = time.Second
+)
+
+func ProgressSubject(jobType models.JobType, region models.Region) string {
+	r := region.String()
+	if r == "" {
+		r = progressAllRegions
+	}
+	return ProgressSubjectPrefix + "." + jobType.String() + "." + r
+}
+
+type Progress struct {
+	JobID     string           `json:"job_id"`
+	Type      models.JobType   `json:"type"`
+	Region    models.Region    `json:"region,omitempty"`
+	Attempt   int              `json:"attempt"`
+	Status    models.JobStatus `json:"status"`
+	Processed int              `json:"processed"`
+	Total     int              `json:"total,omitempty"`
+	ETAMs     int64            `json:"eta_ms,omitempty"`
+	LastError string           `json:"last_error,omitempty"`
+	StartedAt time.Time        `json:"started_at"`
+	UpdatedAt time.Time        `json:"updated_at"`
+}
+
+// Finished reports whether the attempt is over.
+func (p Progress) Finished() bool {
+	return p.Status != models.JobStatusRunning && p.Status != models.JobStatusQueued
+}
+
+// ProgressReporter publishes the progress of one job attempt. Handlers get
+// it with ProgressFrom; a nil reporter ignores all calls, so handlers can
+// report unconditionally.
+type ProgressReporter struct {
+	nc     *nats.Conn
+	logger *zap.Logger
+
+	mu       sync.Mutex
+	progress Progress
+	lastSent time.Time
+}
+
+type progressKey struct{}
+
+func newProgressReporter(nc *nats.Conn, job *Job, logger *zap.Logger) *ProgressReporter {
+	now := time.Now().UTC()
+	return &ProgressReporter{
+		nc:     nc,
+		logger: logger,
+		progress: Progress{
+			JobID:     job.ID,
+			Type:      job.Type,
+			Region:    regionOf(job),
+			Attempt:   job.Attempt,
+			Status:    models.JobStatusRunning,
+			StartedAt: now,
+			UpdatedAt: now,
+		},
+	}
+}
+
+func withProgress(ctx context.Context, r *ProgressReporter) context.Context {
+	return context.WithValue(ctx, progressKey{}, r)
+}
+
+// ProgressFrom returns the reporter of the job running under ctx, or nil.
+func ProgressFrom(ctx context.Context) *ProgressReporter {
+	r, _ := ctx.Value(progressKey{}).(*ProgressReporter)
+	return r
+}
+
+// SetTotal sets the number of items the job will process, enabling ETAs.
+func (r *ProgressReporter) SetTotal(total int) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	r.progress.Total = total
+	r.mu.Unlock()
+	r.publish(false)
+}
+
+// Add marks n more items as processed.
+func (r *ProgressReporter) Add(n int) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	r.progress.Processed += n
+	r.mu.Unlock()
+	r.publish(false)
+}
+
+// RecordError reports an error the job recovered from, such as one item
+// that failed and was skipped.
+func (r *ProgressReporter) RecordError(err error) {
+	if r == nil || err == nil {
+		return
+	}
+	r.mu.Lock()
+	r.progress.LastError = err.Error()
+	r.mu.Unlock()
+	r.publish(false)
+}
+
+func (r *ProgressReporter) start() {
+	r.publish(true)
+}
+
+func (r *ProgressReporter) finish(status models.JobStatus, err error) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	r.progress.Status = status
+	if err != nil {
+		r.progress.LastError = err.Error()
+	}
+	r.mu.Unlock()
+	r.publish(true)
+}
+
+func (r *ProgressReporter) publish(force bool) {
+	r.mu.Lock()
+	now := time.Now()
+	if !force && now.Sub(r.lastSent) < progressInterval {
+		r.mu.Unlock()
+		return
+	}
+	r.lastSent = now
+
+	p := r.progress
+	p.UpdatedAt = now.UTC()
+	p.ETAMs = 0
+	if p.Status == models.JobStatusRunning && p.Total > 0 && p.Processed > 0 && p.Processed < p.Total {
+		elapsed := now.Sub(p.StartedAt)
+		remaining := time.Duration(float64(elapsed) / float64(p.Processed) * float64(p.Total-p.Processed))
+		p.ETAMs = remaining.Milliseconds()
+	}
+	r.mu.Unlock()
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		return
+	}
+	if err := r.nc.Publish(ProgressSubject(p.Type, p.Region), data); err != nil {
+		r.logger.Warn("failed to publish job progress", zap.String("job_id", p.JobID), zap.Error(err))
+	}
+}
+
+// regionOf extracts the region of payloads that have one.
+func regionOf(job *Job) models.Region {
+	var payload struct {
+		Region models.Region `json:"region"`
+	}
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return ""
+	}
+	return payload.Region
+}
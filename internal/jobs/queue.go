@@ -0,0 +1,224 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/rsdlab-dk/tft-api/internal/config"
+	"github.com/rsdlab-dk/tft-api/internal/models"
+)
+
+const (
+	StreamName    = "JOBS"
+	SubjectPrefix = "jobs"
+
+	publishDedupeWindow = 2 * time.Minute
+)
+
+func Subject(jobType models.JobType) string {
+	return SubjectPrefix + "." + jobType.String()
+}
+
+func ConsumerName(jobType models.JobType) string {
+	return "jobs-" + strings.ReplaceAll(jobType.String(), "_", "-")
+}
+
+type Queue struct {
+	nc      *nats.Conn
+	js      jetstream.JetStream
+	cfg     config.JobsConfig
+	history *History
+	deduper *Deduper
+	control jetstream.KeyValue
+	retries jetstream.KeyValue
+}
+
+func NewQueue(ctx context.Context, nc *nats.Conn, cfg config.JobsConfig) (*Queue, error) {
+	js, err := jetstream.New(nc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create jetstream context: %w", err)
+	}
+
+	q := &Queue{nc: nc, js: js, cfg: cfg}
+	if err := q.setup(ctx); err != nil {
+		return nil, err
+	}
+
+	return q, nil
+}
+
+// OpenQueue opens a queue that NewQueue has set up. It only looks the
+// stream and control bucket up, so clients such as jobctl never change
+// their configuration.
+func OpenQueue(ctx context.Context, nc *nats.Conn, cfg config.JobsConfig) (*Queue, error) {
+	js, err := jetstream.New(nc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create jetstream context: %w", err)
+	}
+
+	if _, err := js.Stream(ctx, StreamName); err != nil {
+		return nil, fmt.Errorf("failed to open %s stream: %w", StreamName, err)
+	}
+	control, err := js.KeyValue(ctx, ControlBucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s bucket: %w", ControlBucket, err)
+	}
+
+	return &Queue{nc: nc, js: js, cfg: cfg, control: control}, nil
+}
+
+func (q *Queue) setup(ctx context.Context) error {
+	_, err := q.js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:        StreamName,
+		Description: "Background jobs, one subject per job type",
+		Subjects:    []string{SubjectPrefix + ".*"},
+		Retention:   jetstream.WorkQueuePolicy,
+		Storage:     jetstream.FileStorage,
+		Duplicates:  publishDedupeWindow,
+		// Cap the backlog of each job type; publishing past it fails
+		// instead of dropping queued jobs.
+		MaxMsgsPerSubject:    int64(q.cfg.QueueBuffer),
+		Discard:              jetstream.DiscardNew,
+		DiscardNewPerSubject: true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create %s stream: %w", StreamName, err)
+	}
+
+	for _, jobType := range models.AllJobTypes() {
+		_, err := q.js.CreateOrUpdateConsumer(ctx, StreamName, jetstream.ConsumerConfig{
+			Durable:       ConsumerName(jobType),
+			FilterSubject: Subject(jobType),
+			AckPolicy:     jetstream.AckExplicitPolicy,
+			// Leave headroom past the processing timeout so a job that is
+			// still being cancelled is not redelivered to another worker.
+			AckWait: q.cfg.ProcessingTimeout + time.Minute,
+			// Jobs waiting out a retry delay stay pending on the
+			// consumer, so this must leave room for them beyond what
+			// the workers run; PullMaxMessages and the workers bound
+			// concurrency instead.
+			MaxAckPending: q.cfg.MaxAckPending,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create consumer for %s: %w", jobType, err)
+		}
+	}
+
+	if err := q.setupDeadLetters(ctx); err != nil {
+		return err
+	}
+	if err := q.setupRetries(ctx); err != nil {
+		return err
+	}
+	return q.setupControl(ctx)
+}
+
+// QueueDepth is the backlog of one job type. InFlight counts the jobs
+// being run as well as those waiting out a retry delay.
+type QueueDepth struct {
+	Type        models.JobType `json:"type"`
+	Pending     uint64         `json:"pending"`
+	InFlight    int            `json:"in_flight"`
+	Redelivered int            `json:"redelivered"`
+	Paused      bool           `json:"paused"`
+}
+
+// Depth reports the backlog of every job type's consumer.
+func (q *Queue) Depth(ctx context.Context) ([]QueueDepth, error) {
+	paused, err := q.Paused(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	depths := make([]QueueDepth, 0, len(models.AllJobTypes()))
+	for _, jobType := range models.AllJobTypes() {
+		consumer, err := q.Consumer(ctx, jobType)
+		if err != nil {
+			return nil, err
+		}
+
+		info, err := consumer.Info(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get consumer info for %s: %w", jobType, err)
+		}
+
+		_, isPaused := paused[jobType]
+		depths = append(depths, QueueDepth{
+			Type:        jobType,
+			Pending:     info.NumPending,
+			InFlight:    info.NumAckPending,
+			Redelivered: info.NumRedelivered,
+			Paused:      isPaused,
+		})
+	}
+
+	return depths, nil
+}
+
+// SetHistory enables execution recording for jobs published through and
+// processed from this queue.
+func (q *Queue) SetHistory(history *History) {
+	q.history = history
+}
+
+// SetDeduper enables merging of duplicate jobs on Enqueue.
+func (q *Queue) SetDeduper(deduper *Deduper) {
+	q.deduper = deduper
+}
+
+// Enqueue publishes a new job. If a job with the same idempotency key is
+// still pending, nothing is published and the returned job carries the
+// pending job's ID with Merged set.
+func (q *Queue) Enqueue(ctx context.Context, payload Payload) (*Job, error) {
+	job, err := NewJob(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	pendingID, err := q.deduper.claim(ctx, job)
+	if err != nil {
+		return nil, err
+	}
+	if pendingID != job.ID {
+		job.ID = pendingID
+		job.Merged = true
+		return job, nil
+	}
+
+	if err := q.Publish(ctx, job); err != nil {
+		q.deduper.release(context.WithoutCancel(ctx), job)
+		return nil, err
+	}
+
+	return job, nil
+}
+
+func (q *Queue) Publish(ctx context.Context, job *Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to encode job %s: %w", job.ID, err)
+	}
+
+	// The message ID makes a re-sent publish of the same attempt a no-op
+	// within the stream's duplicate window.
+	msgID := fmt.Sprintf("%s-%d", job.ID, job.Attempt)
+	if _, err := q.js.Publish(ctx, Subject(job.Type), data, jetstream.WithMsgID(msgID)); err != nil {
+		return fmt.Errorf("failed to publish %s job %s: %w", job.Type, job.ID, err)
+	}
+
+	q.history.queued(ctx, job)
+	return nil
+}
+
+func (q *Queue) Consumer(ctx context.Context, jobType models.JobType) (jetstream.Consumer, error) {
+	consumer, err := q.js.Consumer(ctx, StreamName, ConsumerName(jobType))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get consumer for %s: %w", jobType, err)
+	}
+	return consumer, nil
+}
@@ -0,0 +1,26 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenQueueDoesNotCreateStream(t *testing.T) {
+	nc := connectTestServer(t)
+	ctx := context.Background()
+
+	_, err := OpenQueue(ctx, nc, testJobsConfig())
+	assert.Error(t, err)
+
+	_, err = NewQueue(ctx, nc, testJobsConfig())
+	require.NoError(t, err)
+
+	queue, err := OpenQueue(ctx, nc, testJobsConfig())
+	require.NoError(t, err)
+	depths, err := queue.Depth(ctx)
+	require.NoError(t, err)
+	assert.NotEmpty(t, depths)
+}
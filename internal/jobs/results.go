@@ -0,0 +1,66 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/rsdlab-dk/tft-api/internal/models"
+	"go.uber.org/zap"
+)
+
+// ResultSubjectPrefix is where pools announce finished attempts. Results
+// are fire-and-forget core NATS messages for live tailing; jobs.executions
+// is the durable record.
+const ResultSubjectPrefix = SubjectPrefix + ".results"
+
+func ResultSubject(jobType models.JobType) string {
+	return ResultSubjectPrefix + "." + jobType.String()
+}
+
+type Result struct {
+	JobID      string           `json:"job_id"`
+	Type       models.JobType   `json:"type"`
+	Attempt    int              `json:"attempt"`
+	Status     models.JobStatus `json:"status"`
+	Error      string           `json:"error,omitempty"`
+	DurationMs int64            `json:"duration_ms"`
+	FinishedAt time.Time        `json:"finished_at"`
+}
+
+// finish records the outcome of an attempt and announces it, both as a
+// result and as the final progress update.
+func (p *Pool) finish(ctx context.Context, job *Job, status models.JobStatus, jobErr error, retryable *bool, duration time.Duration) {
+	p.queue.history.finished(ctx, job, status, jobErr, retryable, duration)
+	p.progressOf(job.ID).finish(status, jobErr)
+
+	if status != models.JobStatusRetrying {
+		// Final outcome: later enqueues of the same work run again.
+		if err := p.queue.deduper.release(context.WithoutCancel(ctx), job); err != nil {
+			p.logger.Warn("failed to release job", zap.String("job_id", job.ID), zap.Error(err))
+		}
+		if err := p.queue.clearRetry(context.WithoutCancel(ctx), job); err != nil {
+			p.logger.Warn("failed to clear job retry state", zap.String("job_id", job.ID), zap.Error(err))
+		}
+	}
+
+	result := Result{
+		JobID:      job.ID,
+		Type:       job.Type,
+		Attempt:    job.Attempt,
+		Status:     status,
+		DurationMs: duration.Milliseconds(),
+		FinishedAt: time.Now().UTC(),
+	}
+	if jobErr != nil {
+		result.Error = jobErr.Error()
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return
+	}
+	if err := p.queue.nc.Publish(ResultSubject(job.Type), data); err != nil {
+		p.logger.Warn("failed to publish job result", zap.String("job_id", job.ID), zap.Error(err))
+	}
+}
@@ -0,0 +1,88 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+const (
+	// RetryBucket holds the attempt and error history of jobs waiting out
+	// a retry delay. A failed job is nak'd with the delay rather than
+	// published again, so its message cannot carry the new state; the next
+	// delivery picks it up from here instead.
+	RetryBucket = "JOBS_RETRIES"
+
+	// retryStateTTL outlives the longest retry delay by far, so state
+	// only expires for jobs that were purged from the stream.
+	retryStateTTL = 24 * time.Hour
+)
+
+type retryState struct {
+	Attempt   int        `json:"attempt"`
+	NotBefore time.Time  `json:"not_before"`
+	Errors    []JobError `json:"errors"`
+}
+
+func (q *Queue) setupRetries(ctx context.Context) error {
+	kv, err := q.js.CreateOrUpdateKeyValue(ctx, jetstream.KeyValueConfig{
+		Bucket:      RetryBucket,
+		Description: "Attempt and error history of jobs waiting to be retried",
+		TTL:         retryStateTTL,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create %s bucket: %w", RetryBucket, err)
+	}
+	q.retries = kv
+	return nil
+}
+
+// saveRetry records job's attempt and errors for its next delivery.
+func (q *Queue) saveRetry(ctx context.Context, job *Job) error {
+	data, err := json.Marshal(retryState{Attempt: job.Attempt, NotBefore: job.NotBefore, Errors: job.Errors})
+	if err != nil {
+		return fmt.Errorf("failed to encode retry state of job %s: %w", job.ID, err)
+	}
+	if _, err := q.retries.Put(ctx, job.ID, data); err != nil {
+		return fmt.Errorf("failed to save retry state of job %s: %w", job.ID, err)
+	}
+	return nil
+}
+
+// loadRetry applies the retry state saved for job, if any, to the job as
+// decoded from its message.
+func (q *Queue) loadRetry(ctx context.Context, job *Job) error {
+	entry, err := q.retries.Get(ctx, job.ID)
+	if errors.Is(err, jetstream.ErrKeyNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load retry state of job %s: %w", job.ID, err)
+	}
+
+	var state retryState
+	if err := json.Unmarshal(entry.Value(), &state); err != nil {
+		return fmt.Errorf("failed to decode retry state of job %s: %w", job.ID, err)
+	}
+	// State left over from before a dead-letter replay is older than the
+	// replayed message.
+	if state.Attempt > job.Attempt {
+		job.Attempt = state.Attempt
+		job.NotBefore = state.NotBefore
+		job.Errors = state.Errors
+	}
+	return nil
+}
+
+// clearRetry drops the retry state of a job that reached a final outcome.
+// A failure only leaves the state to expire.
+func (q *Queue) clearRetry(ctx context.Context, job *Job) error {
+	if err := q.retries.Delete(ctx, job.ID); err != nil && !errors.Is(err, jetstream.ErrKeyNotFound) {
+		return fmt.Errorf("failed to clear retry state of job %s: %w", job.ID, err)
+	}
+	return nil
+}
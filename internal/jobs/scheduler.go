@@ -0,0 +1,146 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/rsdlab-dk/tft-api/internal/config"
+	"github.com/rsdlab-dk/tft-api/internal/models"
+	"go.uber.org/zap"
+)
+
+const (
+	schedulerTick     = 5 * time.Second
+	schedulerLeaseTTL = 3 * schedulerTick
+
+	schedulerLeaderKey  = "jobs:scheduler:leader"
+	schedulerLastRunKey = "jobs:scheduler:last_run:"
+)
+
+type Schedule struct {
+	Interval time.Duration
+	Payload  func() Payload
+}
+
+// Scheduler enqueues periodic jobs. Every replica runs one, but only the
+// holder of the Redis leader lease enqueues. Last run times live in Redis
+// so a new leader continues the previous leader's cadence instead of
+// firing everything on takeover.
+type Scheduler struct {
+	queue     *Queue
+	leader    *Leader
+	client    *redis.Client
+	schedules []Schedule
+	logger    *zap.Logger
+}
+
+func NewScheduler(queue *Queue, client *redis.Client, cfg *config.Config, logger *zap.Logger) *Scheduler {
+	region := models.Region(cfg.Riot.DefaultRegion)
+
+	return &Scheduler{
+		queue:  queue,
+		leader: NewLeader(client, schedulerLeaderKey, schedulerLeaseTTL),
+		client: client,
+		logger: logger,
+		schedules: []Schedule{
+			{
+				Interval: cfg.Jobs.CollectionInterval,
+				Payload:  func() Payload { return CollectChallengerPayload{Region: region} },
+			},
+			{
+				Interval: cfg.Jobs.AnalysisInterval,
+				Payload:  func() Payload { return AnalyzeCompositionsPayload{} },
+			},
+			{
+				Interval: cfg.Jobs.LeaderboardRefreshInterval,
+				Payload:  func() Payload { return RefreshLeaderboardPayload{} },
+			},
+			{
+				Interval: cfg.Jobs.MetaUpdateInterval,
+				Payload:  func() Payload { return UpdateMetaDataPayload{} },
+			},
+			{
+				Interval: cfg.Jobs.CleanupInterval,
+				Payload:  func() Payload { return CleanupCachePayload{} },
+			},
+			{
+				Interval: cfg.Jobs.CacheWarmInterval,
+				Payload:  func() Payload { return WarmCachePayload{} },
+			},
+		},
+	}
+}
+
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(schedulerTick)
+	defer ticker.Stop()
+
+	leading := false
+	for {
+		isLeader, err := s.leader.Campaign(ctx)
+		if err != nil {
+			s.logger.Warn("scheduler leader election failed", zap.Error(err))
+			isLeader = false
+		}
+
+		if isLeader != leading {
+			leading = isLeader
+			s.logger.Info("scheduler leadership changed",
+				zap.Bool("leader", leading),
+				zap.String("instance", s.leader.ID()),
+			)
+		}
+
+		if leading {
+			s.runDue(ctx)
+		}
+
+		select {
+		case <-ctx.Done():
+			if leading {
+				resignCtx, cancel := context.WithTimeout(context.Background(), time.Second)
+				s.leader.Resign(resignCtx)
+				cancel()
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) runDue(ctx context.Context) {
+	now := time.Now()
+
+	for _, schedule := range s.schedules {
+		payload := schedule.Payload()
+		jobType := payload.JobType()
+		key := schedulerLastRunKey + jobType.String()
+
+		lastRun, err := s.client.Get(ctx, key).Time()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			s.logger.Warn("failed to read last run", zap.String("job_type", jobType.String()), zap.Error(err))
+			continue
+		}
+		if err == nil && now.Sub(lastRun) < schedule.Interval {
+			continue
+		}
+
+		job, err := s.queue.Enqueue(ctx, payload)
+		if err != nil {
+			s.logger.Error("failed to enqueue scheduled job", zap.String("job_type", jobType.String()), zap.Error(err))
+			continue
+		}
+
+		if err := s.client.Set(ctx, key, now, 0).Err(); err != nil {
+			s.logger.Warn("failed to record last run", zap.String("job_type", jobType.String()), zap.Error(err))
+		}
+
+		s.logger.Info("scheduled job enqueued",
+			zap.String("job_type", jobType.String()),
+			zap.String("job_id", job.ID),
+			zap.Bool("merged", job.Merged),
+		)
+	}
+}
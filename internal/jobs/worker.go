@@ -0,0 +1,436 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/rsdlab-dk/tft-api/internal/config"
+	"github.com/rsdlab-dk/tft-api/internal/models"
+	"go.uber.org/zap"
+)
+
+type Handler interface {
+	Handle(ctx context.Context, job *Job) error
+}
+
+type HandlerFunc func(ctx context.Context, job *Job) error
+
+func (f HandlerFunc) Handle(ctx context.Context, job *Job) error {
+	return f(ctx, job)
+}
+
+// Pool runs JobsConfig.WorkerCount workers fed by one pull consumer per
+// registered job type. Fetchers pull one message at a time and hand it to
+// an idle worker; a fetched message that waits for one is kept in progress
+// so its AckWait does not run out and redeliver it to another worker.
+type Pool struct {
+	queue    *Queue
+	cfg      config.JobsConfig
+	logger   *zap.Logger
+	handlers map[models.JobType]Handler
+	messages chan jetstream.Msg
+
+	mu      sync.Mutex
+	running map[string]*runningJob
+	paused  map[models.JobType]chan struct{}
+}
+
+const (
+	pausedRedeliveryDelay     = 30 * time.Second
+	retryStateRedeliveryDelay = 5 * time.Second
+	// inProgressInterval must stay well below the consumers' AckWait.
+	inProgressInterval = 30 * time.Second
+)
+
+type runningJob struct {
+	cancel    context.CancelFunc
+	cancelled bool
+	progress  *ProgressReporter
+}
+
+func NewPool(queue *Queue, cfg config.JobsConfig, logger *zap.Logger) *Pool {
+	return &Pool{
+		queue:    queue,
+		cfg:      cfg,
+		logger:   logger,
+		handlers: make(map[models.JobType]Handler),
+		messages: make(chan jetstream.Msg),
+		running:  make(map[string]*runningJob),
+		paused:   make(map[models.JobType]chan struct{}),
+	}
+}
+
+func (p *Pool) Register(jobType models.JobType, handler Handler) {
+	p.handlers[jobType] = handler
+}
+
+func (p *Pool) Run(ctx context.Context) error {
+	if len(p.handlers) == 0 {
+		return errors.New("no job handlers registered")
+	}
+
+	sub, err := p.queue.nc.Subscribe(ControlCancelSubject, func(msg *nats.Msg) {
+		p.cancelRunning(string(msg.Data))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %w", ControlCancelSubject, err)
+	}
+	defer sub.Unsubscribe()
+
+	watcher, err := p.queue.watchPaused(ctx)
+	if err != nil {
+		return err
+	}
+	defer watcher.Stop()
+	// Apply the stored pause state before consuming anything.
+	p.applyPaused(watcher, true)
+	go p.applyPaused(watcher, false)
+
+	var fetchers sync.WaitGroup
+	for jobType := range p.handlers {
+		consumer, err := p.queue.Consumer(ctx, jobType)
+		if err != nil {
+			return err
+		}
+
+		iter, err := consumer.Messages(jetstream.PullMaxMessages(1))
+		if err != nil {
+			return fmt.Errorf("failed to start consuming %s: %w", jobType, err)
+		}
+
+		fetchers.Add(1)
+		go func(jobType models.JobType, iter jetstream.MessagesContext) {
+			defer fetchers.Done()
+			p.fetch(ctx, jobType, iter)
+		}(jobType, iter)
+
+		p.logger.Info("consuming jobs", zap.String("job_type", jobType.String()))
+	}
+
+	var workers sync.WaitGroup
+	for i := 0; i < p.cfg.WorkerCount; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			p.work(ctx)
+		}()
+	}
+
+	fetchers.Wait()
+	close(p.messages)
+	workers.Wait()
+
+	return nil
+}
+
+func (p *Pool) fetch(ctx context.Context, jobType models.JobType, iter jetstream.MessagesContext) {
+	go func() {
+		<-ctx.Done()
+		iter.Stop()
+	}()
+
+	for {
+		if !p.waitResumed(ctx, jobType) {
+			return
+		}
+
+		msg, err := iter.Next()
+		if err != nil {
+			if errors.Is(err, jetstream.ErrMsgIteratorClosed) {
+				return
+			}
+			p.logger.Warn("failed to fetch job", zap.String("job_type", jobType.String()), zap.Error(err))
+			continue
+		}
+
+		if !p.dispatch(ctx, msg) {
+			return
+		}
+	}
+}
+
+// dispatch blocks until a worker takes msg, marking it in progress while it
+// waits. It naks msg and returns false if ctx is done first.
+func (p *Pool) dispatch(ctx context.Context, msg jetstream.Msg) bool {
+	ticker := time.NewTicker(inProgressInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case p.messages <- msg:
+			return true
+		case <-ticker.C:
+			if err := msg.InProgress(); err != nil {
+				p.logger.Warn("failed to extend job ack deadline", zap.String("subject", msg.Subject()), zap.Error(err))
+			}
+		case <-ctx.Done():
+			msg.Nak()
+			return false
+		}
+	}
+}
+
+func (p *Pool) work(ctx context.Context) {
+	for msg := range p.messages {
+		if ctx.Err() != nil {
+			msg.Nak()
+			continue
+		}
+		p.process(ctx, msg)
+	}
+}
+
+func (p *Pool) process(ctx context.Context, msg jetstream.Msg) {
+	job, err := decodeJob(msg.Data())
+	if err != nil {
+		p.logger.Error("dropping undecodable job", zap.String("subject", msg.Subject()), zap.Error(err))
+		msg.Term()
+		return
+	}
+
+	logger := p.logger.With(
+		zap.String("job_id", job.ID),
+		zap.String("job_type", job.Type.String()),
+	)
+
+	handler, ok := p.handlers[job.Type]
+	if !ok {
+		logger.Warn("no handler registered for job")
+		msg.Nak()
+		return
+	}
+
+	if p.isPaused(job.Type) {
+		// Fetched before the pause took effect.
+		msg.NakWithDelay(pausedRedeliveryDelay)
+		return
+	}
+
+	// A retry is the original message redelivered after its backoff; the
+	// attempt and errors of the earlier runs are kept next to it.
+	if err := p.queue.loadRetry(ctx, job); err != nil {
+		logger.Warn("failed to load job retry state", zap.Error(err))
+		msg.NakWithDelay(retryStateRedeliveryDelay)
+		return
+	}
+
+	jobCtx, cancel := context.WithTimeout(ctx, p.cfg.ProcessingTimeout)
+	defer cancel()
+
+	// Track the job before recording it as running so a cancel request
+	// that races the start is not lost.
+	progress := newProgressReporter(p.queue.nc, job, p.logger)
+	running := p.track(job.ID, cancel, progress)
+	defer p.untrack(job.ID)
+
+	if !p.queue.history.started(ctx, job) {
+		logger.Info("skipping cancelled job")
+		if err := p.queue.deduper.release(ctx, job); err != nil {
+			logger.Warn("failed to release job", zap.Error(err))
+		}
+		if err := p.queue.clearRetry(ctx, job); err != nil {
+			logger.Warn("failed to clear job retry state", zap.Error(err))
+		}
+		msg.Ack()
+		return
+	}
+
+	progress.start()
+	started := time.Now()
+	err = p.handle(withProgress(jobCtx, progress), handler, job)
+	duration := time.Since(started)
+	if err == nil {
+		p.finish(ctx, job, models.JobStatusSucceeded, nil, nil, duration)
+		if err := msg.Ack(); err != nil {
+			logger.Warn("failed to ack job", zap.Error(err))
+			return
+		}
+		logger.Info("job completed", zap.Duration("duration", duration))
+		return
+	}
+
+	if p.wasCancelled(running) {
+		p.finish(ctx, job, models.JobStatusCancelled, err, nil, duration)
+		logger.Info("job cancelled", zap.Duration("duration", duration))
+		msg.Ack()
+		return
+	}
+
+	if ctx.Err() != nil {
+		// Shutting down; let another worker pick the job up as-is.
+		msg.Nak()
+		return
+	}
+
+	p.fail(ctx, msg, job, err, duration, logger.With(zap.Duration("duration", duration)))
+}
+
+// fail records the error on the job and either hands the message back to
+// JetStream for redelivery after a backoff delay or moves the job to the
+// dead-letter stream. The attempt is only finished as retrying once the
+// next one's state is saved; otherwise the same attempt runs again.
+func (p *Pool) fail(ctx context.Context, msg jetstream.Msg, job *Job, err error, duration time.Duration, logger *zap.Logger) {
+	retryable := IsRetryable(err)
+	job.recordError(err, retryable)
+
+	if retryable && job.retries() < p.cfg.MaxRetries {
+		next := *job
+		next.Attempt++
+		delay := Backoff(p.cfg.RetryBackoff, next.retries())
+		next.NotBefore = time.Now().Add(delay)
+
+		if err := p.queue.saveRetry(ctx, &next); err != nil {
+			logger.Error("failed to schedule job retry", zap.Error(err))
+			msg.NakWithDelay(delay)
+			return
+		}
+		p.finish(ctx, job, models.JobStatusRetrying, err, &retryable, duration)
+		p.queue.history.queued(ctx, &next)
+
+		if err := msg.NakWithDelay(delay); err != nil {
+			// AckWait redelivers it instead.
+			logger.Warn("failed to delay job retry", zap.Error(err))
+		}
+		logger.Warn("job failed, retry scheduled",
+			zap.Int("attempt", next.Attempt),
+			zap.Duration("backoff", delay),
+			zap.Error(err),
+		)
+		return
+	}
+
+	if err := p.queue.DeadLetter(ctx, job); err != nil {
+		logger.Error("failed to dead-letter job", zap.Error(err))
+		msg.Nak()
+		return
+	}
+	p.finish(ctx, job, models.JobStatusDeadLettered, err, &retryable, duration)
+
+	logger.Error("job dead-lettered",
+		zap.Int("attempts", job.Attempt+1),
+		zap.Bool("retryable", retryable),
+		zap.Error(err),
+	)
+	msg.Ack()
+}
+
+// applyPaused applies pause state changes from watcher. With initial set it
+// returns once the stored state has been replayed; otherwise it runs until
+// the watcher stops.
+func (p *Pool) applyPaused(watcher jetstream.KeyWatcher, initial bool) {
+	for entry := range watcher.Updates() {
+		if entry == nil {
+			if initial {
+				return
+			}
+			continue
+		}
+
+		jobType := models.JobType(strings.TrimPrefix(entry.Key(), pausedKeyPrefix))
+		switch entry.Operation() {
+		case jetstream.KeyValuePut:
+			p.setPaused(jobType, true)
+		case jetstream.KeyValueDelete, jetstream.KeyValuePurge:
+			p.setPaused(jobType, false)
+		}
+	}
+}
+
+func (p *Pool) setPaused(jobType models.JobType, paused bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	resumed, isPaused := p.paused[jobType]
+	switch {
+	case paused && !isPaused:
+		p.paused[jobType] = make(chan struct{})
+		p.logger.Info("job type paused", zap.String("job_type", jobType.String()))
+	case !paused && isPaused:
+		close(resumed)
+		delete(p.paused, jobType)
+		p.logger.Info("job type resumed", zap.String("job_type", jobType.String()))
+	}
+}
+
+func (p *Pool) isPaused(jobType models.JobType) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, paused := p.paused[jobType]
+	return paused
+}
+
+// waitResumed blocks while jobType is paused. It returns false if ctx is
+// done first.
+func (p *Pool) waitResumed(ctx context.Context, jobType models.JobType) bool {
+	p.mu.Lock()
+	resumed, paused := p.paused[jobType]
+	p.mu.Unlock()
+
+	if !paused {
+		return true
+	}
+
+	select {
+	case <-resumed:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (p *Pool) track(jobID string, cancel context.CancelFunc, progress *ProgressReporter) *runningJob {
+	job := &runningJob{cancel: cancel, progress: progress}
+	p.mu.Lock()
+	p.running[jobID] = job
+	p.mu.Unlock()
+	return job
+}
+
+func (p *Pool) untrack(jobID string) {
+	p.mu.Lock()
+	delete(p.running, jobID)
+	p.mu.Unlock()
+}
+
+func (p *Pool) cancelRunning(jobID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	job, ok := p.running[jobID]
+	if !ok {
+		return
+	}
+	job.cancelled = true
+	job.cancel()
+	p.logger.Info("cancelling running job", zap.String("job_id", jobID))
+}
+
+func (p *Pool) progressOf(jobID string) *ProgressReporter {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if job, ok := p.running[jobID]; ok {
+		return job.progress
+	}
+	return nil
+}
+
+func (p *Pool) wasCancelled(job *runningJob) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return job.cancelled
+}
+
+func (p *Pool) handle(ctx context.Context, handler Handler, job *Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return handler.Handle(ctx, job)
+}
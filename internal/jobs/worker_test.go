@@ -0,0 +1,271 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/rsdlab-dk/tft-api/internal/config"
+	"github.com/rsdlab-dk/tft-api/internal/messaging"
+	"github.com/rsdlab-dk/tft-api/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const testWaitTimeout = 10 * time.Second
+
+func testJobsConfig() config.JobsConfig {
+	return config.JobsConfig{
+		WorkerCount:       1,
+		QueueBuffer:       10,
+		MaxAckPending:     100,
+		ProcessingTimeout: 5 * time.Second,
+		MaxRetries:        2,
+		RetryBackoff:      10 * time.Millisecond,
+		DedupeWindow:      time.Minute,
+	}
+}
+
+// connectTestServer starts an embedded server for the test and connects
+// to it.
+func connectTestServer(t *testing.T) *nats.Conn {
+	t.Helper()
+
+	srv, err := messaging.StartEmbedded(config.NATSConfig{
+		EmbeddedHost:     "127.0.0.1",
+		EmbeddedPort:     -1,
+		EmbeddedStoreDir: t.TempDir(),
+	}, zap.NewNop())
+	require.NoError(t, err)
+	t.Cleanup(srv.Shutdown)
+
+	nc, err := nats.Connect(srv.ClientURL())
+	require.NoError(t, err)
+	t.Cleanup(nc.Close)
+	return nc
+}
+
+// startTestPool runs a pool against an embedded server with handler
+// registered for cleanup_cache jobs.
+func startTestPool(t *testing.T, cfg config.JobsConfig, handler HandlerFunc) *Queue {
+	t.Helper()
+
+	nc := connectTestServer(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	queue, err := NewQueue(ctx, nc, cfg)
+	require.NoError(t, err)
+
+	pool := NewPool(queue, cfg, zap.NewNop())
+	pool.Register(models.JobTypeCleanupCache, handler)
+
+	done := make(chan error, 1)
+	go func() { done <- pool.Run(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		assert.NoError(t, <-done)
+	})
+
+	return queue
+}
+
+func depthOf(t *testing.T, queue *Queue, jobType models.JobType) QueueDepth {
+	t.Helper()
+
+	depths, err := queue.Depth(context.Background())
+	require.NoError(t, err)
+	for _, depth := range depths {
+		if depth.Type == jobType {
+			return depth
+		}
+	}
+	t.Fatalf("no depth reported for %s", jobType)
+	return QueueDepth{}
+}
+
+func waitDrained(t *testing.T, queue *Queue) {
+	t.Helper()
+
+	require.Eventually(t, func() bool {
+		depth := depthOf(t, queue, models.JobTypeCleanupCache)
+		return depth.Pending == 0 && depth.InFlight == 0
+	}, testWaitTimeout, 20*time.Millisecond)
+}
+
+func deadLetters(t *testing.T, queue *Queue) []DeadLetter {
+	t.Helper()
+
+	letters, err := queue.ListDeadLetters(context.Background(), models.JobTypeCleanupCache, 10)
+	require.NoError(t, err)
+	return letters
+}
+
+func TestPoolAcksSucceededJob(t *testing.T) {
+	var calls atomic.Int32
+	queue := startTestPool(t, testJobsConfig(), func(ctx context.Context, job *Job) error {
+		calls.Add(1)
+		return nil
+	})
+
+	_, err := queue.Enqueue(context.Background(), CleanupCachePayload{})
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool { return calls.Load() == 1 }, testWaitTimeout, 20*time.Millisecond)
+	waitDrained(t, queue)
+	assert.Empty(t, deadLetters(t, queue))
+}
+
+func TestPoolRetriesRetryableError(t *testing.T) {
+	var calls atomic.Int32
+	queue := startTestPool(t, testJobsConfig(), func(ctx context.Context, job *Job) error {
+		if calls.Add(1) == 1 {
+			return errors.New("temporary failure")
+		}
+		assert.Equal(t, 1, job.Attempt)
+		assert.Len(t, job.Errors, 1)
+		return nil
+	})
+
+	_, err := queue.Enqueue(context.Background(), CleanupCachePayload{})
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool { return calls.Load() == 2 }, testWaitTimeout, 20*time.Millisecond)
+	waitDrained(t, queue)
+	assert.Empty(t, deadLetters(t, queue))
+}
+
+func TestPoolRunsFreshJobWhileRetriesBackOff(t *testing.T) {
+	cfg := testJobsConfig()
+	cfg.RetryBackoff = time.Minute
+	var failing, fresh atomic.Int32
+	queue := startTestPool(t, cfg, func(ctx context.Context, job *Job) error {
+		var payload CleanupCachePayload
+		require.NoError(t, job.Decode(&payload))
+		if payload.DryRun {
+			failing.Add(1)
+			return errors.New("temporary failure")
+		}
+		fresh.Add(1)
+		return nil
+	})
+
+	// testJobsConfig has one worker; its job fails and waits out the
+	// backoff.
+	_, err := queue.Enqueue(context.Background(), CleanupCachePayload{DryRun: true})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return failing.Load() == int32(cfg.WorkerCount) }, testWaitTimeout, 20*time.Millisecond)
+
+	_, err = queue.Enqueue(context.Background(), CleanupCachePayload{})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return fresh.Load() == 1 }, testWaitTimeout, 20*time.Millisecond)
+
+	// The retry is neither redelivered early nor published again.
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(t, int32(cfg.WorkerCount), failing.Load())
+	depth := depthOf(t, queue, models.JobTypeCleanupCache)
+	assert.Equal(t, 1, depth.InFlight)
+	assert.Zero(t, depth.Pending)
+}
+
+func TestPoolDeadLettersPermanentError(t *testing.T) {
+	var calls atomic.Int32
+	queue := startTestPool(t, testJobsConfig(), func(ctx context.Context, job *Job) error {
+		calls.Add(1)
+		return Permanent(errors.New("bad input"))
+	})
+
+	job, err := queue.Enqueue(context.Background(), CleanupCachePayload{})
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool { return len(deadLetters(t, queue)) == 1 }, testWaitTimeout, 20*time.Millisecond)
+	waitDrained(t, queue)
+
+	letter := deadLetters(t, queue)[0]
+	assert.Equal(t, job.ID, letter.Job.ID)
+	assert.Equal(t, int32(1), calls.Load())
+	require.Len(t, letter.Job.Errors, 1)
+	assert.False(t, letter.Job.Errors[0].Retryable)
+}
+
+func TestPoolDeadLettersAfterMaxRetries(t *testing.T) {
+	cfg := testJobsConfig()
+	var calls atomic.Int32
+	queue := startTestPool(t, cfg, func(ctx context.Context, job *Job) error {
+		calls.Add(1)
+		return errors.New("still failing")
+	})
+
+	job, err := queue.Enqueue(context.Background(), CleanupCachePayload{})
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool { return len(deadLetters(t, queue)) == 1 }, testWaitTimeout, 20*time.Millisecond)
+	waitDrained(t, queue)
+
+	letter := deadLetters(t, queue)[0]
+	assert.Equal(t, job.ID, letter.Job.ID)
+	assert.Equal(t, int32(cfg.MaxRetries+1), calls.Load())
+	assert.Equal(t, cfg.MaxRetries, letter.Job.Attempt)
+	assert.Len(t, letter.Job.Errors, cfg.MaxRetries+1)
+}
+
+func TestPoolRunsNoMoreJobsThanItsWorkers(t *testing.T) {
+	release := make(chan struct{})
+	var calls, running, maxRunning atomic.Int32
+	queue := startTestPool(t, testJobsConfig(), func(ctx context.Context, job *Job) error {
+		calls.Add(1)
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			max := maxRunning.Load()
+			if n <= max || maxRunning.CompareAndSwap(max, n) {
+				break
+			}
+		}
+		<-release
+		return nil
+	})
+
+	for i := 0; i < 3; i++ {
+		_, err := queue.Enqueue(context.Background(), CleanupCachePayload{DryRun: i%2 == 0})
+		require.NoError(t, err)
+	}
+
+	require.Eventually(t, func() bool { return calls.Load() == 1 }, testWaitTimeout, 20*time.Millisecond)
+	// Give the pool time to start further jobs if it would.
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(t, int32(1), calls.Load())
+
+	close(release)
+	require.Eventually(t, func() bool { return calls.Load() == 3 }, testWaitTimeout, 20*time.Millisecond)
+	waitDrained(t, queue)
+	assert.Equal(t, int32(1), maxRunning.Load())
+}
+
+func TestPoolRejectsEnqueueOverQueueBuffer(t *testing.T) {
+	cfg := testJobsConfig()
+	cfg.QueueBuffer = 1
+	release := make(chan struct{})
+	queue := startTestPool(t, cfg, func(ctx context.Context, job *Job) error {
+		<-release
+		return nil
+	})
+	defer close(release)
+
+	_, err := queue.Enqueue(context.Background(), CleanupCachePayload{})
+	require.NoError(t, err)
+	_, err = queue.Enqueue(context.Background(), CleanupCachePayload{DryRun: true})
+	assert.Error(t, err)
+}
+
+func TestQueueHasConsumerForEveryJobType(t *testing.T) {
+	queue := startTestPool(t, testJobsConfig(), func(ctx context.Context, job *Job) error {
+		return nil
+	})
+
+	for _, jobType := range models.AllJobTypes() {
+		depthOf(t, queue, jobType)
+	}
+}
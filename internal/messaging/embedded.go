@@ -0,0 +1,107 @@
+package messaging
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/rsdlab-dk/tft-api/internal/config"
+	"go.uber.org/zap"
+)
+
+const embeddedReadyTimeout = 10 * time.Second
+
+// EmbeddedServer is an in-process nats-server with JetStream enabled, for
+// local development, tests and single-binary deployments. Clients connect
+// to it over TCP exactly as they would to an external server, so streams,
+// consumers and KV buckets behave the same in both modes.
+type EmbeddedServer struct {
+	srv      *server.Server
+	storeDir string
+	tempDir  bool
+}
+
+// StartEmbedded starts the server and waits until it accepts connections.
+// Without EmbeddedStoreDir, JetStream data goes to a temporary directory
+// that is removed on Shutdown.
+func StartEmbedded(cfg config.NATSConfig, logger *zap.Logger) (*EmbeddedServer, error) {
+	storeDir := cfg.EmbeddedStoreDir
+	tempDir := false
+	if storeDir == "" {
+		dir, err := os.MkdirTemp("", "tft-nats-*")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create jetstream store dir: %w", err)
+		}
+		storeDir, tempDir = dir, true
+	}
+
+	opts := &server.Options{
+		ServerName: "tft-embedded",
+		Host:       cfg.EmbeddedHost,
+		Port:       cfg.EmbeddedPort,
+		JetStream:  true,
+		StoreDir:   storeDir,
+		NoSigs:     true,
+	}
+
+	srv, err := server.NewServer(opts)
+	if err != nil {
+		if tempDir {
+			os.RemoveAll(storeDir)
+		}
+		return nil, fmt.Errorf("failed to create embedded nats server: %w", err)
+	}
+	srv.SetLogger(&serverLogger{logger: logger.Named("nats-server").Sugar()}, false, false)
+
+	go srv.Start()
+
+	if !srv.ReadyForConnections(embeddedReadyTimeout) {
+		srv.Shutdown()
+		if tempDir {
+			os.RemoveAll(storeDir)
+		}
+		return nil, fmt.Errorf("embedded nats server not ready after %s", embeddedReadyTimeout)
+	}
+
+	logger.Info("embedded nats server started",
+		zap.String("url", srv.ClientURL()),
+		zap.String("store_dir", storeDir),
+	)
+
+	return &EmbeddedServer{srv: srv, storeDir: storeDir, tempDir: tempDir}, nil
+}
+
+// ClientURL is the address the server listens on. With EmbeddedPort -1 a
+// random port is chosen, so tests connect through this instead of the
+// configured URL.
+func (s *EmbeddedServer) ClientURL() string {
+	return s.srv.ClientURL()
+}
+
+// Connect connects to the server at ClientURL, with the connection
+// settings of cfg.
+func (s *EmbeddedServer) Connect(cfg config.NATSConfig, name string, logger *zap.Logger) (*nats.Conn, error) {
+	return connect(s.ClientURL(), cfg, name, logger)
+}
+
+func (s *EmbeddedServer) Shutdown() {
+	s.srv.Shutdown()
+	s.srv.WaitForShutdown()
+	if s.tempDir {
+		os.RemoveAll(s.storeDir)
+	}
+}
+
+// serverLogger routes nats-server logs through zap.
+type serverLogger struct {
+	logger *zap.SugaredLogger
+}
+
+func (l *serverLogger) Noticef(format string, v ...interface{}) { l.logger.Infof(format, v...) }
+func (l *serverLogger) Warnf(format string, v ...interface{})   { l.logger.Warnf(format, v...) }
+func (l *serverLogger) Fatalf(format string, v ...interface{})  { l.logger.Errorf(format, v...) }
+func (l *serverLogger) Errorf(format string, v ...interface{})  { l.logger.Errorf(format, v...) }
+func (l *serverLogger) Debugf(format string, v ...interface{})  { l.logger.Debugf(format, v...) }
+func (l *serverLogger) Tracef(format string, v ...interface{})  { l.logger.Debugf(format, v...) }
@@ -0,0 +1,77 @@
+package messaging_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rsdlab-dk/tft-api/internal/config"
+	"github.com/rsdlab-dk/tft-api/internal/jobs"
+	"github.com/rsdlab-dk/tft-api/internal/messaging"
+	"github.com/rsdlab-dk/tft-api/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestPoolRunsOnEmbeddedServer(t *testing.T) {
+	logger := zap.NewNop()
+	natsCfg := config.NATSConfig{
+		MaxReconnects:    -1,
+		ReconnectWait:    time.Second,
+		Timeout:          5 * time.Second,
+		DrainTimeout:     5 * time.Second,
+		PingInterval:     time.Minute,
+		MaxPingsOut:      2,
+		Embedded:         true,
+		EmbeddedHost:     "127.0.0.1",
+		EmbeddedPort:     -1,
+		EmbeddedStoreDir: t.TempDir(),
+	}
+
+	srv, err := messaging.StartEmbedded(natsCfg, logger)
+	require.NoError(t, err)
+	defer srv.Shutdown()
+
+	nc, err := srv.Connect(natsCfg, "tft-test", logger)
+	require.NoError(t, err)
+	defer nc.Close()
+
+	jobsCfg := config.JobsConfig{
+		WorkerCount:       2,
+		QueueBuffer:       10,
+		MaxAckPending:     100,
+		ProcessingTimeout: 5 * time.Second,
+		RetryBackoff:      10 * time.Millisecond,
+		DedupeWindow:      time.Minute,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	queue, err := jobs.NewQueue(ctx, nc, jobsCfg)
+	require.NoError(t, err)
+
+	handled := make(chan string, 1)
+	pool := jobs.NewPool(queue, jobsCfg, logger)
+	pool.Register(models.JobTypeWarmCache, jobs.HandlerFunc(func(ctx context.Context, job *jobs.Job) error {
+		handled <- job.ID
+		return nil
+	}))
+
+	done := make(chan error, 1)
+	go func() { done <- pool.Run(ctx) }()
+
+	job, err := queue.Enqueue(ctx, jobs.WarmCachePayload{Limit: 5})
+	require.NoError(t, err)
+
+	select {
+	case id := <-handled:
+		assert.Equal(t, job.ID, id)
+	case <-time.After(10 * time.Second):
+		t.Fatal("job was not processed")
+	}
+
+	cancel()
+	assert.NoError(t, <-done)
+}
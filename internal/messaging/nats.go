@@ -0,0 +1,46 @@
+package messaging
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nats-io/nats.go"
+	"github.com/rsdlab-dk/tft-api/internal/config"
+	"go.uber.org/zap"
+)
+
+// Connect connects to cfg.ClientURLs(). A process that runs the embedded
+// server connects through EmbeddedServer.Connect instead.
+func Connect(cfg config.NATSConfig, name string, logger *zap.Logger) (*nats.Conn, error) {
+	return connect(strings.Join(cfg.ClientURLs(), ","), cfg, name, logger)
+}
+
+func connect(url string, cfg config.NATSConfig, name string, logger *zap.Logger) (*nats.Conn, error) {
+	opts := []nats.Option{
+		nats.Name(name),
+		nats.MaxReconnects(cfg.MaxReconnects),
+		nats.ReconnectWait(cfg.ReconnectWait),
+		nats.Timeout(cfg.Timeout),
+		nats.DrainTimeout(cfg.DrainTimeout),
+		nats.PingInterval(cfg.PingInterval),
+		nats.MaxPingsOutstanding(cfg.MaxPingsOut),
+		nats.DisconnectErrHandler(func(nc *nats.Conn, err error) {
+			if err != nil {
+				logger.Warn("nats disconnected", zap.Error(err))
+			}
+		}),
+		nats.ReconnectHandler(func(nc *nats.Conn) {
+			logger.Info("nats reconnected", zap.String("url", nc.ConnectedUrl()))
+		}),
+		nats.ErrorHandler(func(nc *nats.Conn, sub *nats.Subscription, err error) {
+			logger.Error("nats async error", zap.Error(err))
+		}),
+	}
+
+	nc, err := nats.Connect(url, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to nats: %w", err)
+	}
+
+	return nc, nil
+}
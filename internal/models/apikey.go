@@ -0,0 +1,124 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+type Scope string
+
+const (
+	ScopeReadCompositions Scope = "read:compositions"
+	ScopeReadMeta         Scope = "read:meta"
+	ScopeReadPlayers      Scope = "read:players"
+	ScopeReadMatches      Scope = "read:matches"
+	// ScopeAdmin grants every other scope. It covers the public read
+	// routes only; the admin routes go by the key's Role.
+	ScopeAdmin Scope = "admin"
+)
+
+func (s Scope) String() string {
+	return string(s)
+}
+
+func (s Scope) IsValid() bool {
+	validScopes := map[Scope]bool{
+		ScopeReadCompositions: true, ScopeReadMeta: true,
+		ScopeReadPlayers: true, ScopeReadMatches: true,
+		ScopeAdmin: true,
+	}
+	return validScopes[s]
+}
+
+const (
+	DefaultAPIKeyRequestsPerMinute = 60
+	MaxAPIKeyRequestsPerMinute     = 100000
+)
+
+type APIKey struct {
+	ID                string     `json:"id" db:"id"`
+	UserID            *string    `json:"user_id,omitempty" db:"user_id"`
+	Name              string     `json:"name" db:"name"`
+	Prefix            string     `json:"prefix" db:"key_prefix"`
+	Scopes            []Scope    `json:"scopes" db:"scopes"`
+	Role              Role       `json:"role" db:"role"`
+	RequestsPerMinute int        `json:"requests_per_minute" db:"requests_per_minute"`
+	ExpiresAt         *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+	LastUsedAt        *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+	RevokedAt         *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	CreatedAt         time.Time  `json:"created_at" db:"created_at"`
+}
+
+func (k *APIKey) HasScope(scope Scope) bool {
+	for _, s := range k.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// IsActive reports whether the key may be used at now.
+func (k *APIKey) IsActive(now time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || k.ExpiresAt.After(now)
+}
+
+// CreatedAPIKey carries the plain key, which is only ever returned once.
+type CreatedAPIKey struct {
+	*APIKey
+	Key string `json:"key"`
+}
+
+type CreateAPIKeyRequest struct {
+	Name              string     `json:"name"`
+	UserID            *string    `json:"user_id,omitempty"`
+	Scopes            []Scope    `json:"scopes"`
+	Role              Role       `json:"role"`
+	RequestsPerMinute int        `json:"requests_per_minute"`
+	ExpiresAt         *time.Time `json:"expires_at,omitempty"`
+}
+
+func (r *CreateAPIKeyRequest) SetDefaults() {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Role == "" {
+		r.Role = RoleViewer
+	}
+	if r.RequestsPerMinute == 0 {
+		r.RequestsPerMinute = DefaultAPIKeyRequestsPerMinute
+	}
+}
+
+func (r *CreateAPIKeyRequest) Validate() error {
+	if r.Name == "" || len(r.Name) > 100 {
+		return NewValidationError("name", "must be 1 to 100 characters", "len")
+	}
+	if len(r.Scopes) == 0 {
+		return NewValidationError("scopes", "at least one scope is required", "required")
+	}
+	for _, scope := range r.Scopes {
+		if !scope.IsValid() {
+			return NewValidationError("scopes", fmt.Sprintf("unknown scope %q", scope), "oneof")
+		}
+	}
+	if !r.Role.IsValid() {
+		return NewValidationError("role", fmt.Sprintf("unknown role %q", r.Role), "oneof")
+	}
+	if r.RequestsPerMinute < 1 || r.RequestsPerMinute > MaxAPIKeyRequestsPerMinute {
+		return NewValidationError("requests_per_minute",
+			fmt.Sprintf("must be between 1 and %d", MaxAPIKeyRequestsPerMinute), "range")
+	}
+	if r.ExpiresAt != nil && !r.ExpiresAt.After(time.Now()) {
+		return NewValidationError("expires_at", "must be in the future", "gt")
+	}
+	return nil
+}
+
+type APIKeyUsage struct {
+	Day      string `json:"day" db:"day"`
+	Requests int64  `json:"requests" db:"requests"`
+	Rejected int64  `json:"rejected" db:"rejected"`
+}
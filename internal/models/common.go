@@ -2,6 +2,9 @@ package models
 
 import (
 	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -90,6 +93,52 @@ func (t Tier) IsHighElo() bool {
 	return t == TierMASTER || t == TierGRANDMASTER || t == TierCHALLENGER
 }
 
+func (t Tier) AtLeast(min Tier) bool {
+	return t.IsValid() && t.GetWeight() >= min.GetWeight()
+}
+
+// Aggregate brackets used as meta buckets alongside the single tiers.
+const (
+	TierBracketDiamondPlus Tier = "DIAMOND_PLUS"
+	TierBracketMasterPlus  Tier = "MASTER_PLUS"
+)
+
+// MetaBuckets lists every tier and bracket meta is computed for.
+func MetaBuckets() []Tier {
+	return append(AllTiers(), TierBracketDiamondPlus, TierBracketMasterPlus)
+}
+
+// BucketTiers returns the tiers a meta bucket covers: all tiers from the
+// bracket's floor up, or the tier itself.
+func (t Tier) BucketTiers() []Tier {
+	var floor Tier
+	switch t {
+	case TierBracketDiamondPlus:
+		floor = TierDIAMOND
+	case TierBracketMasterPlus:
+		floor = TierMASTER
+	default:
+		return []Tier{t}
+	}
+
+	tiers := make([]Tier, 0, 4)
+	for _, tier := range AllTiers() {
+		if tier.AtLeast(floor) {
+			tiers = append(tiers, tier)
+		}
+	}
+	return tiers
+}
+
+func TierFromWeight(weight int) Tier {
+	for _, tier := range AllTiers() {
+		if tier.GetWeight() == weight {
+			return tier
+		}
+	}
+	return ""
+}
+
 type TierRank string
 
 const (
@@ -208,19 +257,14 @@ func CalculateSampleSize(games int) SampleSize {
 
 type Patch string
 
-const (
-	Patch1524 Patch = "15.24"
-	Patch151  Patch = "15.1"
-	Patch152  Patch = "15.2"
-	Patch152c Patch = "15.2c"
-)
+var patchPattern = regexp.MustCompile(`^([0-9]+)\.([0-9]+)([a-z]?)$`)
 
 func (p Patch) String() string {
 	return string(p)
 }
 
 func (p Patch) IsValid() bool {
-	return strings.Contains(string(p), "15.")
+	return patchPattern.MatchString(string(p))
 }
 
 func (p Patch) GetMajorVersion() string {
@@ -231,6 +275,79 @@ func (p Patch) GetMajorVersion() string {
 	return string(p)
 }
 
+func (p Patch) parts() (major, minor int, revision string) {
+	match := patchPattern.FindStringSubmatch(string(p))
+	if match == nil {
+		return 0, 0, ""
+	}
+	major, _ = strconv.Atoi(match[1])
+	minor, _ = strconv.Atoi(match[2])
+	return major, minor, match[3]
+}
+
+func (p Patch) Major() int {
+	major, _, _ := p.parts()
+	return major
+}
+
+func (p Patch) Minor() int {
+	_, minor, _ := p.parts()
+	return minor
+}
+
+func (p Patch) Revision() string {
+	_, _, revision := p.parts()
+	return revision
+}
+
+// Compare orders patches by version rather than lexically, so 15.10 sorts
+// after 15.9 and 15.2c after 15.2. It returns -1, 0 or 1.
+func (p Patch) Compare(other Patch) int {
+	major, minor, revision := p.parts()
+	otherMajor, otherMinor, otherRevision := other.parts()
+
+	switch {
+	case major != otherMajor:
+		return compareInts(major, otherMajor)
+	case minor != otherMinor:
+		return compareInts(minor, otherMinor)
+	default:
+		return strings.Compare(revision, otherRevision)
+	}
+}
+
+func (p Patch) Before(other Patch) bool {
+	return p.Compare(other) < 0
+}
+
+func (p Patch) After(other Patch) bool {
+	return p.Compare(other) > 0
+}
+
+func compareInts(a, b int) int {
+	if a < b {
+		return -1
+	}
+	if a > b {
+		return 1
+	}
+	return 0
+}
+
+func SortPatches(patches []Patch) {
+	sort.Slice(patches, func(i, j int) bool {
+		return patches[i].Compare(patches[j]) < 0
+	})
+}
+
+type PatchInfo struct {
+	Version     Patch     `json:"version" db:"patch_version"`
+	SetNumber   int       `json:"set_number" db:"set_number"`
+	ReleaseDate time.Time `json:"release_date" db:"release_date"`
+	FirstSeen   time.Time `json:"first_seen" db:"first_seen_at"`
+	LastSeen    time.Time `json:"last_seen" db:"last_seen_at"`
+}
+
 type SortOrder string
 
 const (
@@ -385,6 +502,7 @@ type CacheKey struct {
 	Prefix string
 	Parts  []string
 	TTL    time.Duration
+	Tags   []string
 }
 
 func NewCacheKey(prefix string, parts ...string) *CacheKey {
@@ -406,6 +524,23 @@ func (ck *CacheKey) WithTTL(ttl time.Duration) *CacheKey {
 	return ck
 }
 
+// WithTags attaches invalidation tags, built with CacheTag, so the entry
+// can be evicted by patch, region or tier without scanning keys.
+func (ck *CacheKey) WithTags(tags ...string) *CacheKey {
+	ck.Tags = append(ck.Tags, tags...)
+	return ck
+}
+
+const (
+	CacheTagPatch  = "patch"
+	CacheTagRegion = "region"
+	CacheTagTier   = "tier"
+)
+
+func CacheTag(name, value string) string {
+	return name + "=" + value
+}
+
 const (
 	CacheKeyComposition       = "comp"
 	CacheKeyLeaderboard      = "leaderboard"
@@ -413,6 +548,7 @@ const (
 	CacheKeyMeta             = "meta"
 	CacheKeyFilterOptions    = "filters"
 	CacheKeyCompositionDetail = "comp_detail"
+	CacheKeyMatch             = "match"
 )
 
 type JobType string
@@ -424,6 +560,7 @@ const (
 	JobTypeRefreshLeaderboard   JobType = "refresh_leaderboard"
 	JobTypeCleanupCache         JobType = "cleanup_cache"
 	JobTypeUpdateMetaData       JobType = "update_meta_data"
+	JobTypeWarmCache            JobType = "warm_cache"
 )
 
 func (jt JobType) String() string {
@@ -435,10 +572,20 @@ func (jt JobType) IsValid() bool {
 		JobTypeCollectChallenger: true, JobTypeCollectPlayer: true,
 		JobTypeAnalyzeCompositions: true, JobTypeRefreshLeaderboard: true,
 		JobTypeCleanupCache: true, JobTypeUpdateMetaData: true,
+		JobTypeWarmCache: true,
 	}
 	return validJobs[jt]
 }
 
+func AllJobTypes() []JobType {
+	return []JobType{
+		JobTypeCollectChallenger, JobTypeCollectPlayer,
+		JobTypeAnalyzeCompositions, JobTypeRefreshLeaderboard,
+		JobTypeCleanupCache, JobTypeUpdateMetaData,
+		JobTypeWarmCache,
+	}
+}
+
 type ValidationError struct {
 	Field   string `json:"field"`
 	Message string `json:"message"`
@@ -500,6 +647,14 @@ func ParseRegion(s string) (Region, error) {
 	return region, nil
 }
 
+func ParsePatch(s string) (Patch, error) {
+	patch := Patch(strings.TrimSpace(strings.ToLower(s)))
+	if !patch.IsValid() {
+		return "", fmt.Errorf("invalid patch: %s", s)
+	}
+	return patch, nil
+}
+
 func ParseTier(s string) (Tier, error) {
 	tier := Tier(strings.ToUpper(s))
 	if !tier.IsValid() {
@@ -4,6 +4,9 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -13,6 +16,7 @@ type TeamComposition struct {
 	Patch       string               `json:"patch" db:"patch_version"`
 	Region      string               `json:"region" db:"region"`
 	Tier        string               `json:"tier" db:"tier"`
+	QueueID     int                  `json:"queue_id" db:"queue_id"`
 	Traits      TraitDataSlice       `json:"traits" db:"traits"`
 	Units       UnitDataSlice        `json:"units" db:"units"`
 	TotalGames  int                  `json:"total_games" db:"total_games"`
@@ -22,6 +26,7 @@ type TeamComposition struct {
 	WinRate     float64              `json:"win_rate" db:"win_rate"`
 	Top4Rate    float64              `json:"top4_rate" db:"top4_rate"`
 	PickRate    float64              `json:"pick_rate" db:"pick_rate"`
+	PickSample  int                  `json:"pick_rate_sample_size" db:"pick_rate_sample_size"`
 	TierRank    string               `json:"tier_rank" db:"tier_rank"`
 	SampleSize  string               `json:"sample_size" db:"sample_size"`
 	FirstSeen   time.Time            `json:"first_seen" db:"first_seen"`
@@ -41,6 +46,8 @@ type CompositionGame struct {
 	TotalDamage   int           `json:"total_damage" db:"total_damage"`
 	FinalTraits   TraitDataSlice `json:"final_traits" db:"final_traits"`
 	FinalUnits    UnitDataSlice  `json:"final_units" db:"final_units"`
+	Tier          string        `json:"tier,omitempty" db:"tier"`
+	LobbyTier     string        `json:"lobby_tier,omitempty" db:"lobby_tier"`
 	GameDatetime  int64         `json:"game_datetime" db:"game_datetime"`
 	CreatedAt     time.Time     `json:"created_at" db:"created_at"`
 }
@@ -95,7 +102,7 @@ func (u *UnitDataSlice) Scan(value interface{}) error {
 
 	switch v := value.(type) {
 	case []byte:
-		return json.Unmarshal(v, t)
+		return json.Unmarshal(v, u)
 	case string:
 		return json.Unmarshal([]byte(v), u)
 	default:
@@ -113,13 +120,14 @@ type CompositionFilters struct {
 	Limit     int      `json:"limit" form:"limit" validate:"min=1,max=100"`
 	Offset    int      `json:"offset" form:"offset" validate:"min=0"`
 	MinGames  int      `json:"min_games" form:"min_games" validate:"min=1"`
+	QueueID   int      `json:"queue_id" form:"queue_id"`
 	Traits    []string `json:"traits,omitempty" form:"traits"`
 	Champions []string `json:"champions,omitempty" form:"champions"`
 }
 
-func (f *CompositionFilters) SetDefaults() {
+func (f *CompositionFilters) SetDefaults(currentPatch Patch) {
 	if f.Patch == "" {
-		f.Patch = "15.2c"
+		f.Patch = currentPatch.String()
 	}
 	if f.Region == "" {
 		f.Region = "kr"
@@ -139,6 +147,9 @@ func (f *CompositionFilters) SetDefaults() {
 	if f.MinGames == 0 {
 		f.MinGames = 100
 	}
+	if f.QueueID == 0 {
+		f.QueueID = QueueTFTNormal.GetQueueID()
+	}
 }
 
 type MetaSnapshot struct {
@@ -307,6 +318,9 @@ func (f *CompositionFilters) ToQueryParams() map[string]interface{} {
 	if f.MinGames > 0 {
 		params["min_games"] = f.MinGames
 	}
+	if f.QueueID != 0 {
+		params["queue_id"] = f.QueueID
+	}
 	
 	return params
 }
@@ -326,4 +340,136 @@ func (pd *PlacementDistribution) GetTop4Rate() float64 {
 		return 0
 	}
 	return float64(pd.GetTopPlacements()) / float64(total) * 100
-}
\ No newline at end of file
+}
+
+func (f *CompositionFilters) Validate() error {
+	if !Region(f.Region).IsValid() {
+		return NewValidationError("region", fmt.Sprintf("unknown region %q", f.Region), "oneof")
+	}
+	if !Tier(f.Tier).IsValid() {
+		return NewValidationError("tier", fmt.Sprintf("unknown tier %q", f.Tier), "oneof")
+	}
+	if f.Patch != "" && !Patch(f.Patch).IsValid() {
+		return NewValidationError("patch", fmt.Sprintf("invalid patch %q", f.Patch), "format")
+	}
+	if f.TierRank != "" && !TierRank(f.TierRank).IsValid() {
+		return NewValidationError("tier_rank", fmt.Sprintf("unknown tier rank %q", f.TierRank), "oneof")
+	}
+	if !SortField(f.SortBy).IsValid() {
+		return NewValidationError("sort", fmt.Sprintf("cannot sort by %q", f.SortBy), "oneof")
+	}
+	if !SortOrder(f.Order).IsValid() {
+		return NewValidationError("order", "order must be asc or desc", "oneof")
+	}
+	if f.Limit < 1 || f.Limit > 100 {
+		return NewValidationError("limit", "limit must be between 1 and 100", "max")
+	}
+	if f.Offset < 0 {
+		return NewValidationError("offset", "offset must not be negative", "min")
+	}
+	if f.MinGames < 1 {
+		return NewValidationError("min_games", "min_games must be at least 1", "min")
+	}
+	if !validQueueID(f.QueueID) {
+		return NewValidationError("queue_id", fmt.Sprintf("unknown queue %d", f.QueueID), "oneof")
+	}
+	return nil
+}
+
+// CacheKey identifies one result page. Trait and champion filters are
+// order-insensitive, so they are sorted to share entries.
+func (f *CompositionFilters) CacheKey() *CacheKey {
+	return NewCacheKey(CacheKeyLeaderboard,
+		f.Patch, f.Region, f.Tier, f.TierRank,
+		f.SortBy, f.Order,
+		strconv.Itoa(f.Limit), strconv.Itoa(f.Offset), strconv.Itoa(f.MinGames),
+		strconv.Itoa(f.QueueID), sortedList(f.Traits), sortedList(f.Champions),
+	).WithTags(
+		CacheTag(CacheTagPatch, f.Patch),
+		CacheTag(CacheTagRegion, f.Region),
+		CacheTag(CacheTagTier, f.Tier),
+	)
+}
+
+func sortedList(values []string) string {
+	sorted := append([]string(nil), values...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, ",")
+}
+
+// CompositionPage is one page of the composition leaderboard.
+type CompositionPage struct {
+	Compositions []TeamComposition `json:"compositions"`
+	Total        int               `json:"total"`
+}
+
+// MetaFilters selects one meta bucket. Tier may be a tier or one of the
+// DIAMOND_PLUS and MASTER_PLUS brackets.
+type MetaFilters struct {
+	Patch   string `json:"patch" form:"patch"`
+	Region  string `json:"region" form:"region"`
+	Tier    string `json:"tier" form:"tier"`
+	QueueID int    `json:"queue_id" form:"queue_id"`
+}
+
+func (f *MetaFilters) SetDefaults(currentPatch Patch) {
+	if f.Patch == "" {
+		f.Patch = currentPatch.String()
+	}
+	if f.Region == "" {
+		f.Region = "kr"
+	}
+	if f.Tier == "" {
+		f.Tier = TierBracketMasterPlus.String()
+	}
+	if f.QueueID == 0 {
+		f.QueueID = QueueTFTNormal.GetQueueID()
+	}
+}
+
+func (f *MetaFilters) Validate() error {
+	if !Region(f.Region).IsValid() {
+		return NewValidationError("region", fmt.Sprintf("unknown region %q", f.Region), "oneof")
+	}
+	if f.Patch != "" && !Patch(f.Patch).IsValid() {
+		return NewValidationError("patch", fmt.Sprintf("invalid patch %q", f.Patch), "format")
+	}
+
+	bucket := false
+	for _, tier := range MetaBuckets() {
+		if tier.String() == f.Tier {
+			bucket = true
+			break
+		}
+	}
+	if !bucket {
+		return NewValidationError("tier", fmt.Sprintf("unknown tier %q", f.Tier), "oneof")
+	}
+
+	if !validQueueID(f.QueueID) {
+		return NewValidationError("queue_id", fmt.Sprintf("unknown queue %d", f.QueueID), "oneof")
+	}
+	return nil
+}
+
+// validQueueID accepts the queues matches.matches allows.
+func validQueueID(queueID int) bool {
+	switch queueID {
+	case 1090, 1100, 1130, 1160:
+		return true
+	}
+	return false
+}
+
+// CacheKey tags brackets with every tier they cover, so invalidating
+// CHALLENGER also evicts DIAMOND_PLUS and MASTER_PLUS.
+func (f *MetaFilters) CacheKey() *CacheKey {
+	key := NewCacheKey(CacheKeyMeta, f.Patch, f.Region, f.Tier, strconv.Itoa(f.QueueID)).WithTags(
+		CacheTag(CacheTagPatch, f.Patch),
+		CacheTag(CacheTagRegion, f.Region),
+	)
+	for _, tier := range Tier(f.Tier).BucketTiers() {
+		key.WithTags(CacheTag(CacheTagTier, tier.String()))
+	}
+	return key
+}
@@ -0,0 +1,97 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+type JobStatus string
+
+const (
+	JobStatusQueued       JobStatus = "queued"
+	JobStatusRunning      JobStatus = "running"
+	JobStatusSucceeded    JobStatus = "succeeded"
+	JobStatusRetrying     JobStatus = "retrying"
+	JobStatusDeadLettered JobStatus = "dead_lettered"
+	JobStatusCancelled    JobStatus = "cancelled"
+)
+
+func (s JobStatus) String() string {
+	return string(s)
+}
+
+func (s JobStatus) IsValid() bool {
+	validStatuses := map[JobStatus]bool{
+		JobStatusQueued: true, JobStatusRunning: true, JobStatusSucceeded: true,
+		JobStatusRetrying: true, JobStatusDeadLettered: true, JobStatusCancelled: true,
+	}
+	return validStatuses[s]
+}
+
+// IsPending reports whether the attempt can still run or is running.
+func (s JobStatus) IsPending() bool {
+	return s == JobStatusQueued || s == JobStatusRunning
+}
+
+type JobExecution struct {
+	ID                int64           `json:"id" db:"id"`
+	JobID             string          `json:"job_id" db:"job_id"`
+	Type              JobType         `json:"type" db:"job_type"`
+	Payload           json.RawMessage `json:"payload" db:"payload"`
+	Attempt           int             `json:"attempt" db:"attempt"`
+	Status            JobStatus       `json:"status" db:"status"`
+	Error             string          `json:"error,omitempty" db:"error"`
+	Retryable         *bool           `json:"retryable,omitempty" db:"retryable"`
+	EnqueuedAt        time.Time       `json:"enqueued_at" db:"enqueued_at"`
+	NotBefore         *time.Time      `json:"not_before,omitempty" db:"not_before"`
+	StartedAt         *time.Time      `json:"started_at,omitempty" db:"started_at"`
+	FinishedAt        *time.Time      `json:"finished_at,omitempty" db:"finished_at"`
+	DurationMs        *int64          `json:"duration_ms,omitempty" db:"duration_ms"`
+	CancelRequestedAt *time.Time      `json:"cancel_requested_at,omitempty" db:"cancel_requested_at"`
+}
+
+type JobTypeSummary struct {
+	Type            JobType    `json:"type" db:"job_type"`
+	LastStatus      JobStatus  `json:"last_status,omitempty" db:"last_status"`
+	LastError       string     `json:"last_error,omitempty" db:"last_error"`
+	LastEnqueuedAt  *time.Time `json:"last_enqueued_at,omitempty" db:"last_enqueued_at"`
+	LastSucceededAt *time.Time `json:"last_succeeded_at,omitempty" db:"last_succeeded_at"`
+	LastFailedAt    *time.Time `json:"last_failed_at,omitempty" db:"last_failed_at"`
+	Queued          int        `json:"queued" db:"queued"`
+	Running         int        `json:"running" db:"running"`
+}
+
+type JobExecutionFilters struct {
+	Type   string    `json:"type" form:"type"`
+	Status string    `json:"status" form:"status"`
+	Since  time.Time `json:"since" form:"since" time_format:"2006-01-02T15:04:05Z07:00"`
+	Until  time.Time `json:"until" form:"until" time_format:"2006-01-02T15:04:05Z07:00"`
+	Limit  int       `json:"limit" form:"limit" validate:"min=1,max=200"`
+	Offset int       `json:"offset" form:"offset" validate:"min=0"`
+}
+
+func (f *JobExecutionFilters) SetDefaults() {
+	if f.Limit == 0 {
+		f.Limit = 50
+	}
+}
+
+func (f *JobExecutionFilters) Validate() error {
+	if f.Type != "" && !JobType(f.Type).IsValid() {
+		return NewValidationError("type", fmt.Sprintf("unknown job type %q", f.Type), "oneof")
+	}
+	if f.Status != "" && !JobStatus(f.Status).IsValid() {
+		return NewValidationError("status", fmt.Sprintf("unknown job status %q", f.Status), "oneof")
+	}
+	if f.Limit < 1 || f.Limit > 200 {
+		return NewValidationError("limit", "limit must be between 1 and 200", "max")
+	}
+	if f.Offset < 0 {
+		return NewValidationError("offset", "offset must not be negative", "min")
+	}
+	if !f.Since.IsZero() && !f.Until.IsZero() && f.Until.Before(f.Since) {
+		return NewValidationError("until", "until must not be before since", "gtefield")
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	gameNamePattern = regexp.MustCompile(`^[\p{L}\p{N} _.]{3,16}$`)
+	tagLinePattern  = regexp.MustCompile(`^[\p{L}\p{N}]{3,5}$`)
+)
+
+// Summoner is a row of players.summoners.
+type Summoner struct {
+	PUUID         string `json:"puuid" db:"puuid"`
+	SummonerID    string `json:"summoner_id" db:"summoner_id"`
+	AccountID     string `json:"account_id" db:"account_id"`
+	Name          string `json:"name" db:"name"`
+	GameName      string `json:"game_name" db:"game_name"`
+	TagLine       string `json:"tag_line" db:"tag_line"`
+	ProfileIconID int    `json:"profile_icon_id" db:"profile_icon_id"`
+	SummonerLevel int    `json:"summoner_level" db:"summoner_level"`
+	Region        Region `json:"region" db:"region"`
+	RevisionDate  int64  `json:"revision_date" db:"revision_date"`
+}
+
+// RiotAccountLink ties a user to a Riot account they proved to own. The
+// proof is good until ExpiresAt; after that the account has to be
+// verified again before it counts as theirs.
+type RiotAccountLink struct {
+	PUUID      string    `json:"puuid" db:"puuid"`
+	Region     Region    `json:"region" db:"region"`
+	GameName   string    `json:"game_name" db:"game_name"`
+	TagLine    string    `json:"tag_line" db:"tag_line"`
+	VerifiedAt time.Time `json:"verified_at" db:"verified_at"`
+	ExpiresAt  time.Time `json:"expires_at" db:"expires_at"`
+	Verified   bool      `json:"verified"`
+}
+
+// IsVerified reports whether the proof of ownership still holds at now.
+func (l *RiotAccountLink) IsVerified(now time.Time) bool {
+	return l.ExpiresAt.After(now)
+}
+
+type LinkRiotAccountRequest struct {
+	Region   Region `json:"region"`
+	GameName string `json:"game_name"`
+	TagLine  string `json:"tag_line"`
+}
+
+func (r *LinkRiotAccountRequest) Normalize() {
+	r.Region = Region(strings.ToLower(strings.TrimSpace(string(r.Region))))
+	r.GameName = strings.TrimSpace(r.GameName)
+	r.TagLine = strings.TrimPrefix(strings.TrimSpace(r.TagLine), "#")
+}
+
+func (r *LinkRiotAccountRequest) Validate() error {
+	if !r.Region.IsValid() {
+		return NewValidationError("region", "unknown region", "oneof")
+	}
+	if !gameNamePattern.MatchString(r.GameName) {
+		return NewValidationError("game_name", "must be 3 to 16 characters", "format")
+	}
+	if !tagLinePattern.MatchString(r.TagLine) {
+		return NewValidationError("tag_line", "must be 3 to 5 letters or digits", "format")
+	}
+	return nil
+}
+
+// RiotLinkChallenge asks the user to set ProfileIconID on the account
+// before ExpiresAt and then confirm.
+type RiotLinkChallenge struct {
+	PUUID         string    `json:"puuid"`
+	Region        Region    `json:"region"`
+	GameName      string    `json:"game_name"`
+	TagLine       string    `json:"tag_line"`
+	ProfileIconID int       `json:"profile_icon_id"`
+	ExpiresAt     time.Time `json:"expires_at"`
+}
@@ -0,0 +1,85 @@
+package models
+
+import "fmt"
+
+// Role is held by users and API keys and decides what they may do on the
+// admin routes.
+type Role string
+
+const (
+	// RoleViewer is the default and has no admin permissions.
+	RoleViewer  Role = "viewer"
+	RoleAnalyst Role = "analyst"
+	RoleAdmin   Role = "admin"
+)
+
+func (r Role) String() string {
+	return string(r)
+}
+
+func (r Role) IsValid() bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
+
+// Can reports whether the role grants permission.
+func (r Role) Can(permission Permission) bool {
+	for _, p := range rolePermissions[r] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+type Permission string
+
+const (
+	PermissionJobsRead      Permission = "jobs:read"
+	PermissionJobsManage    Permission = "jobs:manage"
+	PermissionCacheRead     Permission = "cache:read"
+	PermissionCacheManage   Permission = "cache:manage"
+	PermissionSystemRead    Permission = "system:read"
+	PermissionAPIKeysManage Permission = "api_keys:manage"
+	PermissionUsersManage   Permission = "users:manage"
+)
+
+func (p Permission) String() string {
+	return string(p)
+}
+
+// Analysts may look at jobs, the cache and the schema but change nothing.
+var rolePermissions = map[Role][]Permission{
+	RoleViewer: {},
+	RoleAnalyst: {
+		PermissionJobsRead,
+		PermissionCacheRead,
+		PermissionSystemRead,
+	},
+	RoleAdmin: {
+		PermissionJobsRead,
+		PermissionJobsManage,
+		PermissionCacheRead,
+		PermissionCacheManage,
+		PermissionSystemRead,
+		PermissionAPIKeysManage,
+		PermissionUsersManage,
+	},
+}
+
+type UpdateRoleRequest struct {
+	Role Role `json:"role"`
+}
+
+func (r *UpdateRoleRequest) Validate() error {
+	if !r.Role.IsValid() {
+		return NewValidationError("role", fmt.Sprintf("unknown role %q", r.Role), "oneof")
+	}
+	return nil
+}
+
+// MigrationStatus is the schema version recorded by cmd/migrate.
+type MigrationStatus struct {
+	Version int64 `json:"version"`
+	Dirty   bool  `json:"dirty"`
+}
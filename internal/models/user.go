@@ -0,0 +1,99 @@
+package models
+
+import (
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	PasswordMinLength = 8
+	// PasswordMaxLength is the bcrypt input limit; longer passwords would
+	// be silently truncated.
+	PasswordMaxLength = 72
+)
+
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_]{3,32}$`)
+
+type User struct {
+	ID           string     `json:"id" db:"id"`
+	Email        string     `json:"email" db:"email"`
+	Username     string     `json:"username" db:"username"`
+	Role         Role       `json:"role" db:"role"`
+	PasswordHash string     `json:"-" db:"password_hash"`
+	LockedUntil  *time.Time `json:"-" db:"locked_until"`
+	LastLoginAt  *time.Time `json:"last_login_at,omitempty" db:"last_login_at"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+}
+
+// IsLocked reports whether logins are refused at now.
+func (u *User) IsLocked(now time.Time) bool {
+	return u.LockedUntil != nil && u.LockedUntil.After(now)
+}
+
+type RegisterRequest struct {
+	Email    string `json:"email"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// Normalize lowercases and trims the email so it matches the stored form.
+func (r *RegisterRequest) Normalize() {
+	r.Email = strings.ToLower(strings.TrimSpace(r.Email))
+	r.Username = strings.TrimSpace(r.Username)
+}
+
+func (r *RegisterRequest) Validate() error {
+	if addr, err := mail.ParseAddress(r.Email); err != nil || addr.Address != r.Email || len(r.Email) > 255 {
+		return NewValidationError("email", "invalid email address", "email")
+	}
+	if !usernamePattern.MatchString(r.Username) {
+		return NewValidationError("username", "must be 3 to 32 letters, digits or underscores", "format")
+	}
+	if len(r.Password) < PasswordMinLength || len(r.Password) > PasswordMaxLength {
+		return NewValidationError("password",
+			fmt.Sprintf("must be %d to %d bytes long", PasswordMinLength, PasswordMaxLength), "len")
+	}
+	return nil
+}
+
+type LoginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+
+	// UserAgent and IP are taken from the request and shown in the
+	// session list.
+	UserAgent string `json:"-"`
+	IP        string `json:"-"`
+}
+
+func (r *LoginRequest) Normalize() {
+	r.Email = strings.ToLower(strings.TrimSpace(r.Email))
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// TokenPair is returned on login and refresh. ExpiresIn is the access
+// token lifetime in seconds.
+type TokenPair struct {
+	AccessToken      string    `json:"access_token"`
+	RefreshToken     string    `json:"refresh_token"`
+	TokenType        string    `json:"token_type"`
+	ExpiresIn        int64     `json:"expires_in"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+}
+
+// Session is a login session as listed to its user. Current marks the one
+// the listing request was made with.
+type Session struct {
+	ID         string    `json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+	IP         string    `json:"ip,omitempty"`
+	Current    bool      `json:"current"`
+}
@@ -0,0 +1,110 @@
+package patches
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rsdlab-dk/tft-api/internal/models"
+	"github.com/rsdlab-dk/tft-api/internal/repository"
+	"go.uber.org/zap"
+)
+
+var ErrNoPatches = errors.New("no patches registered")
+
+// Registry caches the patches table so request paths can resolve the
+// current patch without a database round trip. Until the table has been
+// loaded, the configured fallback is the current patch.
+type Registry struct {
+	repo     *repository.PatchRepository
+	fallback models.Patch
+	logger   *zap.Logger
+
+	mu      sync.RWMutex
+	patches []models.PatchInfo
+}
+
+func NewRegistry(repo *repository.PatchRepository, fallback models.Patch, logger *zap.Logger) *Registry {
+	return &Registry{repo: repo, fallback: fallback, logger: logger}
+}
+
+func (r *Registry) Refresh(ctx context.Context) error {
+	patches, err := r.repo.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(patches, func(i, j int) bool {
+		return patches[i].Version.After(patches[j].Version)
+	})
+
+	r.mu.Lock()
+	r.patches = patches
+	r.mu.Unlock()
+
+	return nil
+}
+
+func (r *Registry) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Refresh(ctx); err != nil {
+				r.logger.Warn("failed to refresh patches", zap.Error(err))
+			}
+		}
+	}
+}
+
+// CurrentPatch returns the newest registered patch. Before the registry
+// has loaded it returns the fallback, which is empty unless configured.
+func (r *Registry) CurrentPatch() models.Patch {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if len(r.patches) == 0 {
+		return r.fallback
+	}
+	return r.patches[0].Version
+}
+
+// All returns registered patches, newest first.
+func (r *Registry) All() []models.PatchInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	patches := make([]models.PatchInfo, len(r.patches))
+	copy(patches, r.patches)
+	return patches
+}
+
+func (r *Registry) Get(patch models.Patch) (models.PatchInfo, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, info := range r.patches {
+		if info.Version == patch {
+			return info, true
+		}
+	}
+	return models.PatchInfo{}, false
+}
+
+func (r *Registry) Previous(patch models.Patch) (models.Patch, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, info := range r.patches {
+		if info.Version.Before(patch) {
+			return info.Version, nil
+		}
+	}
+	return "", ErrNoPatches
+}
@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/rsdlab-dk/tft-api/internal/models"
+)
+
+var ErrMetaNotFound = errors.New("meta not found")
+
+// metaCompsPerRank caps the compositions listed per tier rank in a meta
+// snapshot.
+const metaCompsPerRank = 10
+
+type AnalyticsRepository struct {
+	db *sql.DB
+}
+
+func NewAnalyticsRepository(db *sql.DB) *AnalyticsRepository {
+	return &AnalyticsRepository{db: db}
+}
+
+// UpdateMetaFromMatches recomputes trait and champion meta for every tier
+// bucket whose participants changed, limited to patch and region when they
+// are set. With full set, every bucket of patch (or of all active patches
+// when patch is empty) is recomputed. It returns the number of buckets
+// processed.
+func (r *AnalyticsRepository) UpdateMetaFromMatches(ctx context.Context, patch, region string, full bool) (int, error) {
+	var processed int
+	err := r.db.QueryRowContext(ctx,
+		`SELECT analytics.update_meta_from_matches($1, $2, $3)`, nullString(patch), nullString(region), full,
+	).Scan(&processed)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update meta: %w", err)
+	}
+	return processed, nil
+}
+
+// CurrentMeta assembles the meta snapshot of one bucket from current_meta
+// and the composition leaderboard. Bracket tiers such as MASTER_PLUS list
+// compositions of every tier they cover.
+func (r *AnalyticsRepository) CurrentMeta(ctx context.Context, filters models.MetaFilters) (*models.MetaSnapshot, error) {
+	snapshot := &models.MetaSnapshot{Patch: filters.Patch, Region: filters.Region}
+
+	var traits, champions []byte
+	var lastUpdated sql.NullTime
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COALESCE(top_traits, '[]'::json), COALESCE(top_champions, '[]'::json), last_updated
+		FROM analytics.current_meta
+		WHERE patch_version = $1 AND region = $2 AND tier = $3 AND queue_id = $4`,
+		filters.Patch, filters.Region, filters.Tier, filters.QueueID,
+	).Scan(&traits, &champions, &lastUpdated)
+	if err == sql.ErrNoRows {
+		return nil, ErrMetaNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query current meta: %w", err)
+	}
+	if err := json.Unmarshal(traits, &snapshot.TopTraits); err != nil {
+		return nil, fmt.Errorf("failed to decode top traits: %w", err)
+	}
+	if err := json.Unmarshal(champions, &snapshot.TopChamps); err != nil {
+		return nil, fmt.Errorf("failed to decode top champions: %w", err)
+	}
+	snapshot.LastUpdated = lastUpdated.Time
+
+	// Boards per tier are the pick rate denominators; a bracket sums them.
+	err = r.db.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(boards), 0) FROM (
+			SELECT MAX(pick_rate_sample_size) AS boards
+			FROM compositions.comp_leaderboard
+			WHERE patch_version = $1 AND region = $2
+			  AND tier = ANY(analytics.bucket_tiers($3)) AND queue_id = $4
+			GROUP BY tier
+		) per_tier`,
+		filters.Patch, filters.Region, filters.Tier, filters.QueueID,
+	).Scan(&snapshot.TotalGames)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count meta games: %w", err)
+	}
+
+	// A bracket spans several leaderboard rows per comp; each comp is
+	// ranked by the tier most of its games were played at.
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+leaderboardColumns+` FROM (
+			SELECT *, ROW_NUMBER() OVER (PARTITION BY tier_rank ORDER BY win_rate DESC, pick_rate DESC) AS rank_position
+			FROM (
+				SELECT DISTINCT ON (comp_hash) *
+				FROM compositions.comp_leaderboard
+				WHERE patch_version = $1 AND region = $2
+				  AND tier = ANY(analytics.bucket_tiers($3)) AND queue_id = $4
+				ORDER BY comp_hash, total_games DESC
+			) per_comp
+			WHERE tier_rank IN ('S', 'A', 'B')
+		) ranked
+		WHERE rank_position <= $5
+		ORDER BY tier_rank, rank_position`,
+		filters.Patch, filters.Region, filters.Tier, filters.QueueID, metaCompsPerRank,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query meta compositions: %w", err)
+	}
+	defer rows.Close()
+
+	comps, err := scanCompositions(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot.STierComps = make([]models.TeamComposition, 0)
+	snapshot.ATierComps = make([]models.TeamComposition, 0)
+	snapshot.BTierComps = make([]models.TeamComposition, 0)
+	for _, comp := range comps {
+		switch models.TierRank(comp.TierRank) {
+		case models.TierRankS:
+			snapshot.STierComps = append(snapshot.STierComps, comp)
+		case models.TierRankA:
+			snapshot.ATierComps = append(snapshot.ATierComps, comp)
+		case models.TierRankB:
+			snapshot.BTierComps = append(snapshot.BTierComps, comp)
+		}
+	}
+	return snapshot, nil
+}
@@ -0,0 +1,191 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/rsdlab-dk/tft-api/internal/models"
+)
+
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+const apiKeyColumns = `
+	id, user_id, name, key_prefix, scopes, role, requests_per_minute,
+	expires_at, last_used_at, revoked_at, created_at`
+
+// APIKeyUsageCount is what one replica counted for a key on a day since
+// its last flush.
+type APIKeyUsageCount struct {
+	KeyID    string
+	Day      time.Time
+	Requests int64
+	Rejected int64
+	LastUsed time.Time
+}
+
+type APIKeyRepository struct {
+	db *sql.DB
+}
+
+func NewAPIKeyRepository(db *sql.DB) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+func (r *APIKeyRepository) Create(ctx context.Context, key *models.APIKey, keyHash string) error {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO auth.api_keys (id, user_id, name, key_prefix, key_hash, scopes, role, requests_per_minute, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING created_at`,
+		key.ID, key.UserID, key.Name, key.Prefix, keyHash, pq.Array(scopeStrings(key.Scopes)),
+		key.Role, key.RequestsPerMinute, key.ExpiresAt,
+	).Scan(&key.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create api key: %w", err)
+	}
+	return nil
+}
+
+func (r *APIKeyRepository) GetByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+apiKeyColumns+` FROM auth.api_keys WHERE key_hash = $1`, keyHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query api key: %w", err)
+	}
+	defer rows.Close()
+
+	keys, err := scanAPIKeys(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, ErrAPIKeyNotFound
+	}
+	return &keys[0], nil
+}
+
+// List returns all keys, newest first, revoked ones included.
+func (r *APIKeyRepository) List(ctx context.Context) ([]models.APIKey, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+apiKeyColumns+` FROM auth.api_keys ORDER BY created_at DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query api keys: %w", err)
+	}
+	defer rows.Close()
+
+	return scanAPIKeys(rows)
+}
+
+func (r *APIKeyRepository) Revoke(ctx context.Context, id string) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE auth.api_keys SET revoked_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND revoked_at IS NULL`, id)
+	if err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+	if affected == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// RecordUsage adds the counts to the daily totals and moves last_used_at
+// forward, in one transaction.
+func (r *APIKeyRepository) RecordUsage(ctx context.Context, counts []APIKeyUsageCount) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin usage transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, c := range counts {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO auth.api_key_usage (key_id, day, requests, rejected)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (key_id, day) DO UPDATE SET
+				requests = auth.api_key_usage.requests + EXCLUDED.requests,
+				rejected = auth.api_key_usage.rejected + EXCLUDED.rejected`,
+			c.KeyID, c.Day, c.Requests, c.Rejected,
+		); err != nil {
+			return fmt.Errorf("failed to record api key usage: %w", err)
+		}
+
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE auth.api_keys SET last_used_at = $2
+			WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < $2)`,
+			c.KeyID, c.LastUsed,
+		); err != nil {
+			return fmt.Errorf("failed to record api key usage: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit api key usage: %w", err)
+	}
+	return nil
+}
+
+// Usage returns the daily counts of the last days days, newest first.
+func (r *APIKeyRepository) Usage(ctx context.Context, id string, days int) ([]models.APIKeyUsage, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT to_char(day, 'YYYY-MM-DD'), requests, rejected
+		FROM auth.api_key_usage
+		WHERE key_id = $1 AND day > CURRENT_DATE - $2::INTEGER
+		ORDER BY day DESC`, id, days)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query api key usage: %w", err)
+	}
+	defer rows.Close()
+
+	usage := make([]models.APIKeyUsage, 0)
+	for rows.Next() {
+		var u models.APIKeyUsage
+		if err := rows.Scan(&u.Day, &u.Requests, &u.Rejected); err != nil {
+			return nil, fmt.Errorf("failed to scan api key usage: %w", err)
+		}
+		usage = append(usage, u)
+	}
+	return usage, rows.Err()
+}
+
+func scanAPIKeys(rows *sql.Rows) ([]models.APIKey, error) {
+	keys := make([]models.APIKey, 0)
+	for rows.Next() {
+		var key models.APIKey
+		var scopes []string
+		if err := rows.Scan(
+			&key.ID,
+			&key.UserID,
+			&key.Name,
+			&key.Prefix,
+			pq.Array(&scopes),
+			&key.Role,
+			&key.RequestsPerMinute,
+			&key.ExpiresAt,
+			&key.LastUsedAt,
+			&key.RevokedAt,
+			&key.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan api key: %w", err)
+		}
+		for _, s := range scopes {
+			key.Scopes = append(key.Scopes, models.Scope(s))
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+func scopeStrings(scopes []models.Scope) []string {
+	out := make([]string, len(scopes))
+	for i, s := range scopes {
+		out[i] = s.String()
+	}
+	return out
+}
@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/rsdlab-dk/tft-api/internal/models"
+)
+
+var ErrCompositionNotFound = errors.New("composition not found")
+
+const leaderboardColumns = `
+	id, comp_hash, patch_version, region, tier, queue_id, traits, units,
+	total_games, total_wins, total_top4, avg_placement, win_rate, top4_rate,
+	pick_rate, pick_rate_sample_size, tier_rank, sample_size,
+	first_seen, last_seen, last_updated`
+
+type CompositionRepository struct {
+	db *sql.DB
+}
+
+func NewCompositionRepository(db *sql.DB) *CompositionRepository {
+	return &CompositionRepository{db: db}
+}
+
+func (r *CompositionRepository) RebuildStats(ctx context.Context) (int, error) {
+	var corrected int
+	if err := r.db.QueryRowContext(ctx, `SELECT compositions.rebuild_comp_stats()`).Scan(&corrected); err != nil {
+		return 0, fmt.Errorf("failed to rebuild composition stats: %w", err)
+	}
+	return corrected, nil
+}
+
+func (r *CompositionRepository) RecalculatePickRates(ctx context.Context, patch, region string) (int, error) {
+	var updated int
+	err := r.db.QueryRowContext(ctx,
+		`SELECT compositions.recalculate_pick_rates($1, $2)`,
+		nullString(patch), nullString(region),
+	).Scan(&updated)
+	if err != nil {
+		return 0, fmt.Errorf("failed to recalculate pick rates: %w", err)
+	}
+	return updated, nil
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func (r *CompositionRepository) RefreshLeaderboard(ctx context.Context) error {
+	if _, err := r.db.ExecContext(ctx, `SELECT compositions.refresh_leaderboard()`); err != nil {
+		return fmt.Errorf("failed to refresh composition leaderboard: %w", err)
+	}
+	return nil
+}
+
+// List returns one page of the composition leaderboard and the number of
+// compositions matching filters. Tier is the rank of the players who
+// played the boards, not the tier they were collected from; queues are
+// never mixed. Call filters.SetDefaults first.
+func (r *CompositionRepository) List(ctx context.Context, filters models.CompositionFilters) ([]models.TeamComposition, int, error) {
+	args := []interface{}{filters.Patch, filters.Region, filters.Tier, filters.QueueID, filters.MinGames}
+	conditions := []string{"patch_version = $1", "region = $2", "tier = $3", "queue_id = $4", "total_games >= $5"}
+
+	if filters.TierRank != "" {
+		args = append(args, filters.TierRank)
+		conditions = append(conditions, fmt.Sprintf("tier_rank = $%d", len(args)))
+	}
+	if len(filters.Traits) > 0 {
+		args = append(args, containsAll("name", filters.Traits))
+		conditions = append(conditions, fmt.Sprintf("traits @> $%d::jsonb", len(args)))
+	}
+	if len(filters.Champions) > 0 {
+		args = append(args, containsAll("champion", filters.Champions))
+		conditions = append(conditions, fmt.Sprintf("units @> $%d::jsonb", len(args)))
+	}
+
+	where := "WHERE " + strings.Join(conditions, " AND ")
+
+	var total int
+	if err := r.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM compositions.comp_leaderboard `+where, args...,
+	).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count compositions: %w", err)
+	}
+
+	column := models.SortField(filters.SortBy).GetSQLColumn()
+	direction := "DESC"
+	if models.SortOrder(filters.Order) == models.SortOrderASC {
+		direction = "ASC"
+	}
+
+	args = append(args, filters.Limit, filters.Offset)
+	rows, err := r.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT %s FROM compositions.comp_leaderboard
+		%s
+		ORDER BY %s %s, id
+		LIMIT $%d OFFSET $%d`,
+		leaderboardColumns, where, column, direction, len(args)-1, len(args),
+	), args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query compositions: %w", err)
+	}
+	defer rows.Close()
+
+	comps, err := scanCompositions(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+	return comps, total, nil
+}
+
+// Page is List as served and cached by the API.
+func (r *CompositionRepository) Page(ctx context.Context, filters models.CompositionFilters) (*models.CompositionPage, error) {
+	comps, total, err := r.List(ctx, filters)
+	if err != nil {
+		return nil, err
+	}
+	return &models.CompositionPage{Compositions: comps, Total: total}, nil
+}
+
+// GetByHash returns the composition's leaderboard row for the tier most
+// of its games were played at.
+func (r *CompositionRepository) GetByHash(ctx context.Context, compHash string) (*models.TeamComposition, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+leaderboardColumns+` FROM compositions.comp_leaderboard
+		WHERE comp_hash = $1
+		ORDER BY total_games DESC, tier
+		LIMIT 1`, compHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query composition: %w", err)
+	}
+	defer rows.Close()
+
+	comps, err := scanCompositions(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(comps) == 0 {
+		return nil, ErrCompositionNotFound
+	}
+	return &comps[0], nil
+}
+
+// containsAll builds a JSONB array that @> matches when every value is
+// present under field.
+func containsAll(field string, values []string) string {
+	elements := make([]map[string]string, len(values))
+	for i, v := range values {
+		elements[i] = map[string]string{field: v}
+	}
+	data, _ := json.Marshal(elements)
+	return string(data)
+}
+
+func scanCompositions(rows *sql.Rows) ([]models.TeamComposition, error) {
+	comps := make([]models.TeamComposition, 0)
+	for rows.Next() {
+		var c models.TeamComposition
+		if err := rows.Scan(
+			&c.ID, &c.CompHash, &c.Patch, &c.Region, &c.Tier, &c.QueueID, &c.Traits, &c.Units,
+			&c.TotalGames, &c.TotalWins, &c.TotalTop4, &c.AvgPlace, &c.WinRate, &c.Top4Rate,
+			&c.PickRate, &c.PickSample, &c.TierRank, &c.SampleSize,
+			&c.FirstSeen, &c.LastSeen, &c.LastUpdated,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan composition: %w", err)
+		}
+		comps = append(comps, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate compositions: %w", err)
+	}
+	return comps, nil
+}
@@ -0,0 +1,292 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rsdlab-dk/tft-api/internal/models"
+)
+
+var (
+	ErrJobNotFound   = errors.New("job not found")
+	ErrJobNotPending = errors.New("job is not queued or running")
+)
+
+const jobExecutionColumns = `
+	id, job_id, job_type, payload, attempt, status, error, retryable,
+	enqueued_at, not_before, started_at, finished_at, duration_ms, cancel_requested_at`
+
+type JobRepository struct {
+	db *sql.DB
+}
+
+func NewJobRepository(db *sql.DB) *JobRepository {
+	return &JobRepository{db: db}
+}
+
+func (r *JobRepository) RecordQueued(ctx context.Context, exec *models.JobExecution) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO jobs.executions (job_id, job_type, payload, attempt, status, enqueued_at, not_before)
+		VALUES ($1, $2, $3, $4, 'queued', $5, $6)
+		ON CONFLICT (job_id, attempt) DO NOTHING`,
+		exec.JobID, exec.Type, []byte(exec.Payload), exec.Attempt, exec.EnqueuedAt, exec.NotBefore,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record queued job %s: %w", exec.JobID, err)
+	}
+	return nil
+}
+
+// RecordStarted marks an attempt as running. It returns false when the
+// attempt must not run: it was cancelled while queued or already finished
+// and is only being redelivered. Attempts that were never recorded as
+// queued are inserted.
+func (r *JobRepository) RecordStarted(ctx context.Context, exec *models.JobExecution) (bool, error) {
+	var id int64
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO jobs.executions (job_id, job_type, payload, attempt, status, enqueued_at, not_before, started_at)
+		VALUES ($1, $2, $3, $4, 'running', $5, $6, NOW())
+		ON CONFLICT (job_id, attempt) DO UPDATE SET
+			status = 'running',
+			started_at = NOW(),
+			updated_at = NOW()
+		WHERE jobs.executions.status IN ('queued', 'running')
+		  AND jobs.executions.cancel_requested_at IS NULL
+		RETURNING id`,
+		exec.JobID, exec.Type, []byte(exec.Payload), exec.Attempt, exec.EnqueuedAt, exec.NotBefore,
+	).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to record started job %s: %w", exec.JobID, err)
+	}
+	return true, nil
+}
+
+func (r *JobRepository) RecordFinished(ctx context.Context, jobID string, attempt int, status models.JobStatus, jobErr error, retryable *bool, duration time.Duration) error {
+	var message sql.NullString
+	if jobErr != nil {
+		message = nullString(jobErr.Error())
+	}
+
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE jobs.executions SET
+			status = $3,
+			error = $4,
+			retryable = $5,
+			finished_at = NOW(),
+			duration_ms = $6,
+			updated_at = NOW()
+		WHERE job_id = $1 AND attempt = $2`,
+		jobID, attempt, status, message, retryable, duration.Milliseconds(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record finished job %s: %w", jobID, err)
+	}
+	return nil
+}
+
+// RequestCancel flags the latest attempt of a job for cancellation. A
+// queued attempt is cancelled immediately; a running one keeps its status
+// until the worker observes the request. The returned status is the one
+// the attempt had when the request was made.
+func (r *JobRepository) RequestCancel(ctx context.Context, jobID string) (models.JobStatus, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var (
+		id     int64
+		status models.JobStatus
+	)
+	err = tx.QueryRowContext(ctx, `
+		SELECT id, status FROM jobs.executions
+		WHERE job_id = $1
+		ORDER BY attempt DESC
+		LIMIT 1
+		FOR UPDATE`, jobID,
+	).Scan(&id, &status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrJobNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to load job %s: %w", jobID, err)
+	}
+	if !status.IsPending() {
+		return status, ErrJobNotPending
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE jobs.executions SET
+			cancel_requested_at = NOW(),
+			status = CASE WHEN status = 'queued' THEN 'cancelled' ELSE status END,
+			finished_at = CASE WHEN status = 'queued' THEN NOW() ELSE finished_at END,
+			updated_at = NOW()
+		WHERE id = $1`, id)
+	if err != nil {
+		return "", fmt.Errorf("failed to cancel job %s: %w", jobID, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return status, nil
+}
+
+func (r *JobRepository) List(ctx context.Context, filters models.JobExecutionFilters) ([]models.JobExecution, int, error) {
+	conditions := make([]string, 0, 4)
+	args := make([]interface{}, 0, 6)
+
+	if filters.Type != "" {
+		args = append(args, filters.Type)
+		conditions = append(conditions, fmt.Sprintf("job_type = $%d", len(args)))
+	}
+	if filters.Status != "" {
+		args = append(args, filters.Status)
+		conditions = append(conditions, fmt.Sprintf("status = $%d", len(args)))
+	}
+	if !filters.Since.IsZero() {
+		args = append(args, filters.Since)
+		conditions = append(conditions, fmt.Sprintf("enqueued_at >= $%d", len(args)))
+	}
+	if !filters.Until.IsZero() {
+		args = append(args, filters.Until)
+		conditions = append(conditions, fmt.Sprintf("enqueued_at < $%d", len(args)))
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	if err := r.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM jobs.executions `+where, args...,
+	).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count job executions: %w", err)
+	}
+
+	args = append(args, filters.Limit, filters.Offset)
+	rows, err := r.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT %s FROM jobs.executions
+		%s
+		ORDER BY enqueued_at DESC, id DESC
+		LIMIT $%d OFFSET $%d`,
+		jobExecutionColumns, where, len(args)-1, len(args),
+	), args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query job executions: %w", err)
+	}
+	defer rows.Close()
+
+	executions, err := scanJobExecutions(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+	return executions, total, nil
+}
+
+// History returns every attempt of a job, first attempt first.
+func (r *JobRepository) History(ctx context.Context, jobID string) ([]models.JobExecution, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+jobExecutionColumns+` FROM jobs.executions
+		WHERE job_id = $1
+		ORDER BY attempt`, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query job %s: %w", jobID, err)
+	}
+	defer rows.Close()
+
+	executions, err := scanJobExecutions(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(executions) == 0 {
+		return nil, ErrJobNotFound
+	}
+	return executions, nil
+}
+
+// Summaries returns the latest outcome for every job type, including types
+// that have never been enqueued.
+func (r *JobRepository) Summaries(ctx context.Context) ([]models.JobTypeSummary, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT job_type, last_status, COALESCE(last_error, ''), last_enqueued_at,
+		       last_succeeded_at, last_failed_at, queued, running
+		FROM jobs.type_summary`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query job summaries: %w", err)
+	}
+	defer rows.Close()
+
+	byType := make(map[models.JobType]models.JobTypeSummary)
+	for rows.Next() {
+		var summary models.JobTypeSummary
+		if err := rows.Scan(
+			&summary.Type,
+			&summary.LastStatus,
+			&summary.LastError,
+			&summary.LastEnqueuedAt,
+			&summary.LastSucceededAt,
+			&summary.LastFailedAt,
+			&summary.Queued,
+			&summary.Running,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan job summary: %w", err)
+		}
+		byType[summary.Type] = summary
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	summaries := make([]models.JobTypeSummary, 0, len(models.AllJobTypes()))
+	for _, jobType := range models.AllJobTypes() {
+		summary, ok := byType[jobType]
+		if !ok {
+			summary.Type = jobType
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries, nil
+}
+
+func scanJobExecutions(rows *sql.Rows) ([]models.JobExecution, error) {
+	executions := make([]models.JobExecution, 0)
+	for rows.Next() {
+		var (
+			exec    models.JobExecution
+			payload []byte
+			message sql.NullString
+		)
+		if err := rows.Scan(
+			&exec.ID,
+			&exec.JobID,
+			&exec.Type,
+			&payload,
+			&exec.Attempt,
+			&exec.Status,
+			&message,
+			&exec.Retryable,
+			&exec.EnqueuedAt,
+			&exec.NotBefore,
+			&exec.StartedAt,
+			&exec.FinishedAt,
+			&exec.DurationMs,
+			&exec.CancelRequestedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan job execution: %w", err)
+		}
+		exec.Payload = payload
+		exec.Error = message.String
+		executions = append(executions, exec)
+	}
+	return executions, rows.Err()
+}
@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+type MatchRepository struct {
+	db *sql.DB
+}
+
+func NewMatchRepository(db *sql.DB) *MatchRepository {
+	return &MatchRepository{db: db}
+}
+
+// ReattributeUnranked retries rank attribution for up to limit matches
+// with unranked participants, for players whose league entries arrived
+// after the match was ingested. Each match is retried at most once a day.
+func (r *MatchRepository) ReattributeUnranked(ctx context.Context, limit int) (int, error) {
+	var processed int
+	err := r.db.QueryRowContext(ctx,
+		`SELECT matches.reattribute_unranked_matches($1)`, limit,
+	).Scan(&processed)
+	if err != nil {
+		return 0, fmt.Errorf("failed to reattribute unranked matches: %w", err)
+	}
+	return processed, nil
+}
@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/rsdlab-dk/tft-api/internal/models"
+)
+
+// MigrationRepository reads the table cmd/migrate keeps its version in.
+type MigrationRepository struct {
+	db *sql.DB
+}
+
+func NewMigrationRepository(db *sql.DB) *MigrationRepository {
+	return &MigrationRepository{db: db}
+}
+
+// Status returns the applied version, or version 0 if no migration has
+// run yet.
+func (r *MigrationRepository) Status(ctx context.Context) (*models.MigrationStatus, error) {
+	var status models.MigrationStatus
+	err := r.db.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).
+		Scan(&status.Version, &status.Dirty)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to get migration status: %w", err)
+	}
+	return &status, nil
+}
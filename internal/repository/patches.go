@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/rsdlab-dk/tft-api/internal/models"
+)
+
+type PatchRepository struct {
+	db *sql.DB
+}
+
+func NewPatchRepository(db *sql.DB) *PatchRepository {
+	return &PatchRepository{db: db}
+}
+
+func (r *PatchRepository) List(ctx context.Context) ([]models.PatchInfo, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT patch_version, COALESCE(set_number, 0), release_date, first_seen_at, last_seen_at
+		FROM matches.patches
+		ORDER BY major_version DESC, minor_version DESC, revision DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query patches: %w", err)
+	}
+	defer rows.Close()
+
+	patches := make([]models.PatchInfo, 0)
+	for rows.Next() {
+		var patch models.PatchInfo
+		if err := rows.Scan(
+			&patch.Version,
+			&patch.SetNumber,
+			&patch.ReleaseDate,
+			&patch.FirstSeen,
+			&patch.LastSeen,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan patch: %w", err)
+		}
+		patches = append(patches, patch)
+	}
+
+	return patches, rows.Err()
+}
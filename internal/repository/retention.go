@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// RetentionRepository wraps the batched cleanup functions. Each call
+// deletes at most batchSize rows in its own statement and returns how many
+// were deleted; with dryRun it deletes nothing and returns the total number
+// of candidates instead.
+type RetentionRepository struct {
+	db *sql.DB
+}
+
+func NewRetentionRepository(db *sql.DB) *RetentionRepository {
+	return &RetentionRepository{db: db}
+}
+
+func (r *RetentionRepository) CleanupMatches(ctx context.Context, days, batchSize int, dryRun bool) (int, error) {
+	return r.cleanup(ctx, "matches",
+		`SELECT matches.cleanup_old_matches($1, $2, $3)`, days, batchSize, dryRun)
+}
+
+func (r *RetentionRepository) CleanupTimelineEvents(ctx context.Context, days, batchSize int, dryRun bool) (int, error) {
+	return r.cleanup(ctx, "timeline events",
+		`SELECT matches.cleanup_old_timeline_events($1, $2, $3)`, days, batchSize, dryRun)
+}
+
+func (r *RetentionRepository) CleanupCompGames(ctx context.Context, days, batchSize int, dryRun bool) (int, error) {
+	return r.cleanup(ctx, "composition games",
+		`SELECT compositions.cleanup_old_comp_games($1, $2, $3)`, days, batchSize, dryRun)
+}
+
+func (r *RetentionRepository) CleanupCompositions(ctx context.Context, minGames, days, batchSize int, dryRun bool) (int, error) {
+	var affected int
+	err := r.db.QueryRowContext(ctx,
+		`SELECT compositions.cleanup_old_compositions($1, $2, $3, $4)`, minGames, days, batchSize, dryRun,
+	).Scan(&affected)
+	if err != nil {
+		return 0, fmt.Errorf("failed to clean up compositions: %w", err)
+	}
+	return affected, nil
+}
+
+func (r *RetentionRepository) RefreshMatchStats(ctx context.Context) error {
+	if _, err := r.db.ExecContext(ctx, `SELECT matches.refresh_match_stats()`); err != nil {
+		return fmt.Errorf("failed to refresh match stats: %w", err)
+	}
+	return nil
+}
+
+func (r *RetentionRepository) cleanup(ctx context.Context, what, query string, days, batchSize int, dryRun bool) (int, error) {
+	var affected int
+	if err := r.db.QueryRowContext(ctx, query, days, batchSize, dryRun).Scan(&affected); err != nil {
+		return 0, fmt.Errorf("failed to clean up %s: %w", what, err)
+	}
+	return affected, nil
+}
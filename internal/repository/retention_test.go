@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// openTestDB connects to TEST_DATABASE_URL, a database with every
+// migration applied, or skips the test.
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+
+	db, err := sql.Open("postgres", url)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	require.NoError(t, db.PingContext(context.Background()))
+	return db
+}
+
+// insertTestComp adds a one-game composition with the given ages in days
+// and returns its hash.
+func insertTestComp(t *testing.T, db *sql.DB, lastSeenDays, lastUpdatedDays int) string {
+	t.Helper()
+
+	hash := "test-" + uuid.NewString()
+	_, err := db.Exec(`
+		INSERT INTO compositions.team_comps (
+			comp_hash, patch_version, region, tier, traits, units, total_games,
+			last_seen, last_updated
+		) VALUES (
+			$1, '15.1', 'kr', 'CHALLENGER', '[{"name": "TFT_Test"}]', '[{"character_id": "TFT_Test"}]', 1,
+			NOW() - INTERVAL '1 day' * $2, NOW() - INTERVAL '1 day' * $3
+		)`, hash, lastSeenDays, lastUpdatedDays)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Exec(`DELETE FROM compositions.team_comps WHERE comp_hash = $1`, hash) })
+	return hash
+}
+
+func compExists(t *testing.T, db *sql.DB, hash string) bool {
+	t.Helper()
+
+	var exists bool
+	err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM compositions.team_comps WHERE comp_hash = $1)`, hash).Scan(&exists)
+	require.NoError(t, err)
+	return exists
+}
+
+func TestCleanupCompositionsUsesLastSeen(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewRetentionRepository(db)
+	ctx := context.Background()
+
+	// Recalculated today but without games for 60 days.
+	stale := insertTestComp(t, db, 60, 0)
+	// Played yesterday, stats last recalculated long ago.
+	active := insertTestComp(t, db, 1, 60)
+
+	for {
+		deleted, err := repo.CleanupCompositions(ctx, 10, 30, 1000, false)
+		require.NoError(t, err)
+		if deleted == 0 {
+			break
+		}
+	}
+
+	assert.False(t, compExists(t, db, stale))
+	assert.True(t, compExists(t, db, active))
+}
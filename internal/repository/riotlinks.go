@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/rsdlab-dk/tft-api/internal/models"
+)
+
+var (
+	ErrRiotLinkNotFound = errors.New("riot account link not found")
+
+	// ErrRiotAccountClaimed is returned when another user linked the
+	// account between our release and insert.
+	ErrRiotAccountClaimed = errors.New("riot account is being linked by another user")
+)
+
+type RiotLinkRepository struct {
+	db *sql.DB
+}
+
+func NewRiotLinkRepository(db *sql.DB) *RiotLinkRepository {
+	return &RiotLinkRepository{db: db}
+}
+
+// Link records that userID proved to own puuid at verifiedAt. A fresh
+// proof wins over any earlier one, so the account moves over if it was
+// linked to someone else. Two users linking the same account at once
+// cannot both win; the one that loses gets ErrRiotAccountClaimed.
+func (r *RiotLinkRepository) Link(ctx context.Context, userID, puuid string, verifiedAt, expiresAt time.Time) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin link transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM auth.riot_accounts WHERE puuid = $1 AND user_id <> $2`,
+		puuid, userID,
+	); err != nil {
+		return fmt.Errorf("failed to release riot account: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO auth.riot_accounts (user_id, puuid, verified_at, expires_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, puuid) DO UPDATE SET
+			verified_at = EXCLUDED.verified_at,
+			expires_at = EXCLUDED.expires_at,
+			updated_at = NOW()`,
+		userID, puuid, verifiedAt, expiresAt,
+	)
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == "idx_riot_accounts_puuid" {
+		return ErrRiotAccountClaimed
+	}
+	if err != nil {
+		return fmt.Errorf("failed to link riot account: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit riot account link: %w", err)
+	}
+	return nil
+}
+
+// List returns the accounts linked to userID, expired ones included.
+func (r *RiotLinkRepository) List(ctx context.Context, userID string) ([]models.RiotAccountLink, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT l.puuid, s.region, COALESCE(s.game_name, s.name), COALESCE(s.tag_line, ''),
+			l.verified_at, l.expires_at
+		FROM auth.riot_accounts l
+		JOIN players.summoners s ON s.puuid = l.puuid
+		WHERE l.user_id = $1
+		ORDER BY l.verified_at DESC`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query riot account links: %w", err)
+	}
+	defer rows.Close()
+
+	links := make([]models.RiotAccountLink, 0)
+	for rows.Next() {
+		var link models.RiotAccountLink
+		if err := rows.Scan(
+			&link.PUUID,
+			&link.Region,
+			&link.GameName,
+			&link.TagLine,
+			&link.VerifiedAt,
+			&link.ExpiresAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan riot account link: %w", err)
+		}
+		links = append(links, link)
+	}
+	return links, rows.Err()
+}
+
+func (r *RiotLinkRepository) Unlink(ctx context.Context, userID, puuid string) error {
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM auth.riot_accounts WHERE user_id = $1 AND puuid = $2`, userID, puuid)
+	if err != nil {
+		return fmt.Errorf("failed to unlink riot account: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to unlink riot account: %w", err)
+	}
+	if affected == 0 {
+		return ErrRiotLinkNotFound
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/rsdlab-dk/tft-api/internal/models"
+)
+
+type SummonerRepository struct {
+	db *sql.DB
+}
+
+func NewSummonerRepository(db *sql.DB) *SummonerRepository {
+	return &SummonerRepository{db: db}
+}
+
+// Upsert stores summoner as last seen on the Riot API and returns the
+// profile icon now on record. Empty summoner and account IDs, which the
+// Riot API leaves out for some accounts, are stored as NULL.
+func (r *SummonerRepository) Upsert(ctx context.Context, summoner *models.Summoner) (int, error) {
+	var profileIconID int
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO players.summoners (
+			puuid, summoner_id, account_id, name, game_name, tag_line,
+			profile_icon_id, summoner_level, region, revision_date
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (puuid) DO UPDATE SET
+			summoner_id = COALESCE(EXCLUDED.summoner_id, players.summoners.summoner_id),
+			account_id = COALESCE(EXCLUDED.account_id, players.summoners.account_id),
+			game_name = EXCLUDED.game_name,
+			tag_line = EXCLUDED.tag_line,
+			profile_icon_id = EXCLUDED.profile_icon_id,
+			summoner_level = EXCLUDED.summoner_level,
+			region = EXCLUDED.region,
+			revision_date = EXCLUDED.revision_date,
+			updated_at = NOW()
+		RETURNING profile_icon_id`,
+		summoner.PUUID, nullString(summoner.SummonerID), nullString(summoner.AccountID), summoner.Name,
+		summoner.GameName, summoner.TagLine, summoner.ProfileIconID, summoner.SummonerLevel,
+		summoner.Region, summoner.RevisionDate,
+	).Scan(&profileIconID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to upsert summoner: %w", err)
+	}
+	return profileIconID, nil
+}
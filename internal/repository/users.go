@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/rsdlab-dk/tft-api/internal/models"
+)
+
+var (
+	ErrUserNotFound = errors.New("user not found")
+	ErrUserExists   = errors.New("email or username already registered")
+)
+
+const userColumns = `id, email, username, role, password_hash, locked_until, last_login_at, created_at`
+
+type UserRepository struct {
+	db *sql.DB
+}
+
+func NewUserRepository(db *sql.DB) *UserRepository {
+	return &UserRepository{db: db}
+}
+
+// Create inserts user. The email must already be lowercased.
+func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO auth.users (id, email, username, password_hash)
+		VALUES ($1, $2, $3, $4)
+		RETURNING role, created_at`,
+		user.ID, user.Email, user.Username, user.PasswordHash,
+	).Scan(&user.Role, &user.CreatedAt)
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return ErrUserExists
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+	return nil
+}
+
+func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	return r.get(ctx, `SELECT `+userColumns+` FROM auth.users WHERE email = $1`, email)
+}
+
+func (r *UserRepository) GetByID(ctx context.Context, id string) (*models.User, error) {
+	return r.get(ctx, `SELECT `+userColumns+` FROM auth.users WHERE id = $1`, id)
+}
+
+func (r *UserRepository) get(ctx context.Context, query string, arg interface{}) (*models.User, error) {
+	var user models.User
+	err := r.db.QueryRowContext(ctx, query, arg).Scan(
+		&user.ID,
+		&user.Email,
+		&user.Username,
+		&user.Role,
+		&user.PasswordHash,
+		&user.LockedUntil,
+		&user.LastLoginAt,
+		&user.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return &user, nil
+}
+
+// RecordFailedLogin counts a failed login and returns when the account is
+// locked until, or nil if this attempt did not lock it.
+func (r *UserRepository) RecordFailedLogin(ctx context.Context, id string, maxAttempts int, lockout time.Duration) (*time.Time, error) {
+	var lockedUntil *time.Time
+	err := r.db.QueryRowContext(ctx,
+		`SELECT auth.record_failed_login($1, $2, $3 * INTERVAL '1 millisecond')`,
+		id, maxAttempts, lockout.Milliseconds(),
+	).Scan(&lockedUntil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record failed login: %w", err)
+	}
+	return lockedUntil, nil
+}
+
+// RecordLogin clears failed attempts after a successful login.
+func (r *UserRepository) RecordLogin(ctx context.Context, id string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE auth.users SET
+			failed_login_attempts = 0,
+			locked_until = NULL,
+			last_login_at = NOW(),
+			updated_at = NOW()
+		WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to record login: %w", err)
+	}
+	return nil
+}
+
+func (r *UserRepository) SetRole(ctx context.Context, id string, role models.Role) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE auth.users SET role = $2, updated_at = NOW()
+		WHERE id = $1`, id, role)
+	if err != nil {
+		return fmt.Errorf("failed to set user role: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to set user role: %w", err)
+	}
+	if affected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
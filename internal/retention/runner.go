@@ -0,0 +1,188 @@
+package retention
+
+import (
+	"context"
+	"time"
+
+	"github.com/rsdlab-dk/tft-api/internal/config"
+	"github.com/rsdlab-dk/tft-api/internal/repository"
+	"go.uber.org/zap"
+)
+
+const (
+	TargetTimelineEvents = "timeline_events"
+	TargetCompGames      = "comp_games"
+	TargetMatches        = "matches"
+	TargetCompositions   = "team_comps"
+)
+
+// TargetReport describes one retention target. On a dry run Deleted is the
+// number of rows that would have been deleted.
+type TargetReport struct {
+	Name     string        `json:"name"`
+	Window   time.Duration `json:"window"`
+	Deleted  int           `json:"deleted"`
+	Batches  int           `json:"batches"`
+	Duration time.Duration `json:"duration"`
+}
+
+type Report struct {
+	DryRun     bool           `json:"dry_run"`
+	Targets    []TargetReport `json:"targets"`
+	StartedAt  time.Time      `json:"started_at"`
+	FinishedAt time.Time      `json:"finished_at"`
+}
+
+func (r *Report) Deleted() int {
+	total := 0
+	for _, t := range r.Targets {
+		total += t.Deleted
+	}
+	return total
+}
+
+func (r *Report) deleted(names ...string) bool {
+	for _, t := range r.Targets {
+		for _, name := range names {
+			if t.Name == name && t.Deleted > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ProgressFunc is called after each target with the number of targets
+// done so far.
+type ProgressFunc func(done, total int, target TargetReport)
+
+type cleanupFunc func(ctx context.Context, days, batchSize int, dryRun bool) (int, error)
+
+type target struct {
+	name    string
+	window  time.Duration
+	cleanup cleanupFunc
+}
+
+// Runner applies the retention windows. Rows are deleted in batches of
+// BatchSize, each in its own short transaction with a pause in between, so
+// ingestion and readers are never blocked for long.
+type Runner struct {
+	repo   *repository.RetentionRepository
+	comps  *repository.CompositionRepository
+	cfg    config.RetentionConfig
+	logger *zap.Logger
+}
+
+func NewRunner(repo *repository.RetentionRepository, comps *repository.CompositionRepository, cfg config.RetentionConfig, logger *zap.Logger) *Runner {
+	return &Runner{repo: repo, comps: comps, cfg: cfg, logger: logger}
+}
+
+// Run deletes everything outside the configured windows. Timeline events
+// and comp_games go before matches so the cascade from matches stays
+// small; low-sample compositions go last, once their old games are gone.
+// The materialized views are refreshed only if something was deleted.
+// progress may be nil.
+func (r *Runner) Run(ctx context.Context, dryRun bool, progress ProgressFunc) (*Report, error) {
+	dryRun = dryRun || r.cfg.DryRun
+
+	report := &Report{DryRun: dryRun, StartedAt: time.Now()}
+
+	targets := r.targets()
+	for i, t := range targets {
+		tr, err := r.runTarget(ctx, t, dryRun)
+		report.Targets = append(report.Targets, tr)
+		if err != nil {
+			report.FinishedAt = time.Now()
+			return report, err
+		}
+		if progress != nil {
+			progress(i+1, len(targets), tr)
+		}
+	}
+
+	if !dryRun {
+		if report.deleted(TargetMatches, TargetTimelineEvents) {
+			if err := r.repo.RefreshMatchStats(ctx); err != nil {
+				return report, err
+			}
+		}
+		if report.deleted(TargetCompGames, TargetCompositions) {
+			if err := r.comps.RefreshLeaderboard(ctx); err != nil {
+				return report, err
+			}
+		}
+	}
+
+	report.FinishedAt = time.Now()
+	return report, nil
+}
+
+func (r *Runner) targets() []target {
+	return []target{
+		{name: TargetTimelineEvents, window: r.cfg.TimelineEventsWindow, cleanup: r.repo.CleanupTimelineEvents},
+		{name: TargetCompGames, window: r.cfg.CompGamesWindow, cleanup: r.repo.CleanupCompGames},
+		{name: TargetMatches, window: r.cfg.MatchesWindow, cleanup: r.repo.CleanupMatches},
+		{
+			name:   TargetCompositions,
+			window: r.cfg.CompositionsWindow,
+			cleanup: func(ctx context.Context, days, batchSize int, dryRun bool) (int, error) {
+				return r.repo.CleanupCompositions(ctx, r.cfg.CompositionsMinGames, days, batchSize, dryRun)
+			},
+		},
+	}
+}
+
+func (r *Runner) runTarget(ctx context.Context, t target, dryRun bool) (TargetReport, error) {
+	report := TargetReport{Name: t.name, Window: t.window}
+	started := time.Now()
+
+	days := windowDays(t.window)
+
+	if dryRun {
+		n, err := t.cleanup(ctx, days, r.cfg.BatchSize, true)
+		report.Deleted = n
+		report.Duration = time.Since(started)
+		return report, err
+	}
+
+	for {
+		n, err := t.cleanup(ctx, days, r.cfg.BatchSize, false)
+		if err != nil {
+			report.Duration = time.Since(started)
+			return report, err
+		}
+		report.Deleted += n
+		report.Batches++
+
+		if n < r.cfg.BatchSize {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			report.Duration = time.Since(started)
+			return report, ctx.Err()
+		case <-time.After(r.cfg.BatchPause):
+		}
+	}
+
+	r.logger.Debug("retention target cleaned",
+		zap.String("target", t.name),
+		zap.Int("deleted", report.Deleted),
+		zap.Int("batches", report.Batches),
+	)
+
+	report.Duration = time.Since(started)
+	return report, nil
+}
+
+// windowDays rounds a retention window down to whole days, the granularity
+// of the cleanup functions, keeping at least one day.
+func windowDays(window time.Duration) int {
+	days := int(window / (24 * time.Hour))
+	if days < 1 {
+		return 1
+	}
+	return days
+}
@@ -0,0 +1,196 @@
+package riot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/rsdlab-dk/tft-api/internal/config"
+	"github.com/rsdlab-dk/tft-api/internal/models"
+	"golang.org/x/time/rate"
+)
+
+const tokenHeader = "X-Riot-Token"
+
+var (
+	ErrNotFound = errors.New("not found on the riot api")
+	// ErrUnavailable matches every failed call other than ErrNotFound:
+	// transport errors and statuses that survived the retries.
+	ErrUnavailable = errors.New("riot api unavailable")
+)
+
+// StatusError carries the HTTP status of a failed call. Its StatusCode
+// lets jobs.IsRetryable classify it.
+type StatusError struct {
+	Code int
+	Path string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("riot api returned %d for %s", e.Code, e.Path)
+}
+
+func (e *StatusError) StatusCode() int {
+	return e.Code
+}
+
+func (e *StatusError) Unwrap() error {
+	return ErrUnavailable
+}
+
+type Account struct {
+	PUUID    string `json:"puuid"`
+	GameName string `json:"gameName"`
+	TagLine  string `json:"tagLine"`
+}
+
+type Summoner struct {
+	ID            string `json:"id"`
+	AccountID     string `json:"accountId"`
+	PUUID         string `json:"puuid"`
+	ProfileIconID int    `json:"profileIconId"`
+	RevisionDate  int64  `json:"revisionDate"`
+	SummonerLevel int    `json:"summonerLevel"`
+}
+
+// League is a ranked ladder such as the challenger league of a region.
+type League struct {
+	Tier    string        `json:"tier"`
+	Queue   string        `json:"queue"`
+	Entries []LeagueEntry `json:"entries"`
+}
+
+type LeagueEntry struct {
+	PUUID        string `json:"puuid"`
+	Rank         string `json:"rank"`
+	LeaguePoints int    `json:"leaguePoints"`
+	Wins         int    `json:"wins"`
+	Losses       int    `json:"losses"`
+}
+
+// Client calls the Riot API within the configured rate limit, retrying
+// rate-limited and failed calls with exponential backoff.
+type Client struct {
+	http    *http.Client
+	limiter *rate.Limiter
+	cfg     config.RiotConfig
+}
+
+func NewClient(cfg config.RiotConfig) *Client {
+	return &Client{
+		http:    &http.Client{Timeout: cfg.RequestTimeout},
+		limiter: rate.NewLimiter(rate.Every(cfg.RateLimitWindow/time.Duration(cfg.RateLimit)), cfg.RateLimit),
+		cfg:     cfg,
+	}
+}
+
+// AccountByRiotID resolves gameName#tagLine on the regional cluster of
+// region.
+func (c *Client) AccountByRiotID(ctx context.Context, region models.Region, gameName, tagLine string) (*Account, error) {
+	var account Account
+	path := fmt.Sprintf("/riot/account/v1/accounts/by-riot-id/%s/%s", url.PathEscape(gameName), url.PathEscape(tagLine))
+	if err := c.get(ctx, region.ToCluster(), path, &account); err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// AccountByPUUID returns the Riot ID of puuid from the regional cluster of
+// region.
+func (c *Client) AccountByPUUID(ctx context.Context, region models.Region, puuid string) (*Account, error) {
+	var account Account
+	path := "/riot/account/v1/accounts/by-puuid/" + url.PathEscape(puuid)
+	if err := c.get(ctx, region.ToCluster(), path, &account); err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// SummonerByPUUID returns the TFT summoner of puuid on region.
+func (c *Client) SummonerByPUUID(ctx context.Context, region models.Region, puuid string) (*Summoner, error) {
+	var summoner Summoner
+	path := "/tft/summoner/v1/summoners/by-puuid/" + url.PathEscape(puuid)
+	if err := c.get(ctx, region.String(), path, &summoner); err != nil {
+		return nil, err
+	}
+	return &summoner, nil
+}
+
+// ChallengerLeague returns the ranked TFT challenger ladder of region.
+func (c *Client) ChallengerLeague(ctx context.Context, region models.Region) (*League, error) {
+	var league League
+	if err := c.get(ctx, region.String(), "/tft/league/v1/challenger", &league); err != nil {
+		return nil, err
+	}
+	return &league, nil
+}
+
+func (c *Client) get(ctx context.Context, host, path string, out interface{}) error {
+	endpoint := "https://" + host + ".api.riotgames.com" + path
+
+	for attempt := 0; ; attempt++ {
+		if err := c.limiter.Wait(ctx); err != nil {
+			return err
+		}
+
+		retryAfter, err := c.do(ctx, endpoint, path, out)
+		if err == nil || errors.Is(err, ErrNotFound) || attempt >= c.cfg.MaxRetries {
+			return err
+		}
+
+		var status *StatusError
+		if errors.As(err, &status) && status.Code != http.StatusTooManyRequests && status.Code < 500 {
+			return err
+		}
+
+		wait := c.cfg.RetryBackoff << attempt
+		if retryAfter > wait {
+			wait = retryAfter
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// do makes one call and returns the Retry-After of a rate-limited one.
+func (c *Client) do(ctx context.Context, endpoint, path string, out interface{}) (time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to build riot api request: %w", err)
+	}
+	req.Header.Set(tokenHeader, c.cfg.APIKey)
+	req.Header.Set("User-Agent", c.cfg.UserAgent)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return 0, fmt.Errorf("failed to decode riot api response for %s: %w", path, err)
+		}
+		return 0, nil
+	case resp.StatusCode == http.StatusNotFound:
+		return 0, ErrNotFound
+	}
+
+	io.Copy(io.Discard, resp.Body)
+	var retryAfter time.Duration
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		retryAfter = time.Duration(seconds) * time.Second
+	}
+	return retryAfter, &StatusError{Code: resp.StatusCode, Path: path}
+}
@@ -0,0 +1,205 @@
+package warmup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/rsdlab-dk/tft-api/internal/cache"
+	"github.com/rsdlab-dk/tft-api/internal/models"
+	"github.com/rsdlab-dk/tft-api/internal/patches"
+	"github.com/rsdlab-dk/tft-api/internal/repository"
+	"go.uber.org/zap"
+)
+
+// Prefixes are the cached reads the API records popularity for, in the
+// order they are warmed.
+var Prefixes = []string{
+	models.CacheKeyLeaderboard,
+	models.CacheKeyMeta,
+	models.CacheKeyCompositionDetail,
+}
+
+// TargetReport describes one warmed prefix. Fresh reads were already
+// cached; Failed reads could not be decoded or loaded.
+type TargetReport struct {
+	Prefix     string        `json:"prefix"`
+	Candidates int           `json:"candidates"`
+	Warmed     int           `json:"warmed"`
+	Fresh      int           `json:"fresh"`
+	Failed     int           `json:"failed"`
+	Duration   time.Duration `json:"duration"`
+}
+
+type Report struct {
+	Targets    []TargetReport `json:"targets"`
+	StartedAt  time.Time      `json:"started_at"`
+	FinishedAt time.Time      `json:"finished_at"`
+}
+
+func (r *Report) Warmed() int {
+	total := 0
+	for _, t := range r.Targets {
+		total += t.Warmed
+	}
+	return total
+}
+
+// ProgressFunc is called after each read with the number of reads done so
+// far.
+type ProgressFunc func(done, total int)
+
+// Runner pre-computes the most requested reads so the first users after a
+// deploy or a Redis flush do not pay for cold queries. Reads go through
+// the same cache keys and loads as the API handlers.
+type Runner struct {
+	cache      *cache.Cache
+	popularity *cache.Popularity
+	comps      *repository.CompositionRepository
+	analytics  *repository.AnalyticsRepository
+	patches    *patches.Registry
+	keys       int
+	logger     *zap.Logger
+}
+
+// NewRunner warms up to keys reads per prefix unless Run is given a limit.
+func NewRunner(c *cache.Cache, popularity *cache.Popularity, comps *repository.CompositionRepository, analytics *repository.AnalyticsRepository, registry *patches.Registry, keys int, logger *zap.Logger) *Runner {
+	return &Runner{
+		cache:      c,
+		popularity: popularity,
+		comps:      comps,
+		analytics:  analytics,
+		patches:    registry,
+		keys:       keys,
+		logger:     logger,
+	}
+}
+
+// Run loads the top reads of every prefix that are not cached and fresh,
+// then decays the recorded counts. limit overrides the configured number
+// of reads per prefix when positive. progress may be nil.
+func (r *Runner) Run(ctx context.Context, limit int, progress ProgressFunc) (*Report, error) {
+	if limit <= 0 {
+		limit = r.keys
+	}
+
+	report := &Report{StartedAt: time.Now()}
+
+	candidates := make(map[string][]json.RawMessage, len(Prefixes))
+	total := 0
+	for _, prefix := range Prefixes {
+		reads, err := r.popularity.Top(ctx, prefix, limit)
+		if err != nil {
+			return report, err
+		}
+		candidates[prefix] = reads
+		total += len(reads)
+	}
+
+	done := 0
+	for _, prefix := range Prefixes {
+		tr := TargetReport{Prefix: prefix, Candidates: len(candidates[prefix])}
+		started := time.Now()
+		seen := make(map[string]bool)
+
+		for _, raw := range candidates[prefix] {
+			if err := ctx.Err(); err != nil {
+				report.Targets = append(report.Targets, tr)
+				report.FinishedAt = time.Now()
+				return report, err
+			}
+
+			done++
+			if progress != nil {
+				progress(done, total)
+			}
+
+			key, load, err := r.read(prefix, raw)
+			if err != nil {
+				tr.Failed++
+				r.logger.Debug("skipping unusable popular read", zap.String("prefix", prefix), zap.Error(err))
+				continue
+			}
+			// Reads recorded before and after defaults may share a key.
+			if seen[key.String()] {
+				continue
+			}
+			seen[key.String()] = true
+
+			warmed, err := r.cache.Warm(ctx, key, load)
+			switch {
+			case err != nil:
+				tr.Failed++
+				r.logger.Debug("failed to warm cache key", zap.String("key", key.String()), zap.Error(err))
+			case warmed:
+				tr.Warmed++
+			default:
+				tr.Fresh++
+			}
+		}
+
+		tr.Duration = time.Since(started)
+		report.Targets = append(report.Targets, tr)
+
+		if err := r.popularity.Decay(ctx, prefix); err != nil {
+			r.logger.Warn("failed to decay cache popularity", zap.String("prefix", prefix), zap.Error(err))
+		}
+	}
+
+	report.FinishedAt = time.Now()
+	return report, nil
+}
+
+// read rebuilds the cache key and load of a recorded read the way the API
+// handlers do.
+func (r *Runner) read(prefix string, raw json.RawMessage) (*models.CacheKey, cache.LoadFunc, error) {
+	switch prefix {
+	case models.CacheKeyLeaderboard:
+		var filters models.CompositionFilters
+		if err := json.Unmarshal(raw, &filters); err != nil {
+			return nil, nil, err
+		}
+		filters.SetDefaults(r.patches.CurrentPatch())
+		if filters.Patch == "" {
+			return nil, nil, patches.ErrNoPatches
+		}
+		if err := filters.Validate(); err != nil {
+			return nil, nil, err
+		}
+		return filters.CacheKey(), func(ctx context.Context) (interface{}, error) {
+			return r.comps.Page(ctx, filters)
+		}, nil
+
+	case models.CacheKeyMeta:
+		var filters models.MetaFilters
+		if err := json.Unmarshal(raw, &filters); err != nil {
+			return nil, nil, err
+		}
+		filters.SetDefaults(r.patches.CurrentPatch())
+		if filters.Patch == "" {
+			return nil, nil, patches.ErrNoPatches
+		}
+		if err := filters.Validate(); err != nil {
+			return nil, nil, err
+		}
+		return filters.CacheKey(), func(ctx context.Context) (interface{}, error) {
+			return r.analytics.CurrentMeta(ctx, filters)
+		}, nil
+
+	case models.CacheKeyCompositionDetail:
+		var hash string
+		if err := json.Unmarshal(raw, &hash); err != nil {
+			return nil, nil, err
+		}
+		if len(hash) != 64 {
+			return nil, nil, fmt.Errorf("invalid composition hash %q", hash)
+		}
+		return models.NewCacheKey(models.CacheKeyCompositionDetail, hash), func(ctx context.Context) (interface{}, error) {
+			return r.comps.GetByHash(ctx, hash)
+		}, nil
+
+	default:
+		return nil, nil, fmt.Errorf("no warm-up for %s reads", prefix)
+	}
+}
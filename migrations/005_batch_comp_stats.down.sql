@@ -0,0 +1,67 @@
+-- =====================================================
+-- File: migrations/005_batch_comp_stats.down.sql
+-- =====================================================
+DROP TRIGGER IF EXISTS trigger_apply_inserted_comp_games ON compositions.comp_games;
+DROP TRIGGER IF EXISTS trigger_apply_deleted_comp_games ON compositions.comp_games;
+DROP FUNCTION IF EXISTS compositions.apply_inserted_comp_games();
+DROP FUNCTION IF EXISTS compositions.apply_deleted_comp_games();
+DROP FUNCTION IF EXISTS compositions.apply_comp_game_deltas(JSONB);
+DROP FUNCTION IF EXISTS compositions.rebuild_comp_stats();
+
+ALTER TABLE compositions.team_comps DROP COLUMN IF EXISTS placement_sum;
+
+CREATE OR REPLACE FUNCTION compositions.update_comp_stats()
+RETURNS TRIGGER AS $$
+DECLARE
+    total_games_in_region INTEGER;
+BEGIN
+    UPDATE compositions.team_comps
+    SET
+        total_games = (
+            SELECT COUNT(*)
+            FROM compositions.comp_games
+            WHERE comp_hash = NEW.comp_hash
+        ),
+        total_wins = (
+            SELECT COUNT(*)
+            FROM compositions.comp_games
+            WHERE comp_hash = NEW.comp_hash AND placement = 1
+        ),
+        total_top4 = (
+            SELECT COUNT(*)
+            FROM compositions.comp_games
+            WHERE comp_hash = NEW.comp_hash AND placement <= 4
+        ),
+        avg_placement = (
+            SELECT AVG(placement)
+            FROM compositions.comp_games
+            WHERE comp_hash = NEW.comp_hash
+        ),
+        last_seen = NOW(),
+        last_updated = NOW()
+    WHERE comp_hash = NEW.comp_hash;
+
+    UPDATE compositions.team_comps
+    SET
+        win_rate = ROUND((total_wins::DECIMAL / NULLIF(total_games, 0)) * 100, 2),
+        top4_rate = ROUND((total_top4::DECIMAL / NULLIF(total_games, 0)) * 100, 2)
+    WHERE comp_hash = NEW.comp_hash;
+
+    SELECT COUNT(DISTINCT comp_hash) INTO total_games_in_region
+    FROM compositions.team_comps tc
+    WHERE tc.patch_version = (SELECT patch_version FROM compositions.team_comps WHERE comp_hash = NEW.comp_hash LIMIT 1)
+      AND tc.region = (SELECT region FROM compositions.team_comps WHERE comp_hash = NEW.comp_hash LIMIT 1)
+      AND tc.tier = (SELECT tier FROM compositions.team_comps WHERE comp_hash = NEW.comp_hash LIMIT 1);
+
+    UPDATE compositions.team_comps
+    SET pick_rate = ROUND((total_games::DECIMAL / NULLIF(total_games_in_region, 0)) * 100, 2)
+    WHERE comp_hash = NEW.comp_hash;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trigger_update_comp_stats
+    AFTER INSERT ON compositions.comp_games
+    FOR EACH ROW
+    EXECUTE FUNCTION compositions.update_comp_stats();
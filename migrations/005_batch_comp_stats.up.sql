@@ -0,0 +1,214 @@
+-- =====================================================
+-- TFT Arena - Batched Composition Statistics
+-- File: migrations/005_batch_comp_stats.up.sql
+-- =====================================================
+
+-- =====================================================
+-- Running Placement Sum for Incremental Averages
+-- =====================================================
+ALTER TABLE compositions.team_comps
+    ADD COLUMN placement_sum BIGINT NOT NULL DEFAULT 0;
+
+UPDATE compositions.team_comps tc
+SET placement_sum = g.placement_sum
+FROM (
+    SELECT comp_hash, SUM(placement) as placement_sum
+    FROM compositions.comp_games
+    GROUP BY comp_hash
+) g
+WHERE tc.comp_hash = g.comp_hash;
+
+-- =====================================================
+-- Remove Per-Row Statistics Trigger
+-- =====================================================
+DROP TRIGGER IF EXISTS trigger_update_comp_stats ON compositions.comp_games;
+DROP FUNCTION IF EXISTS compositions.update_comp_stats();
+
+-- =====================================================
+-- Delta Application for a Batch of Games
+-- =====================================================
+CREATE OR REPLACE FUNCTION compositions.apply_comp_game_deltas(
+    p_deltas JSONB
+)
+RETURNS VOID AS $$
+BEGIN
+    UPDATE compositions.team_comps tc
+    SET
+        total_games = tc.total_games + d.games,
+        total_wins = tc.total_wins + d.wins,
+        total_top4 = tc.total_top4 + d.top4,
+        placement_sum = tc.placement_sum + d.placement_sum,
+        avg_placement = ROUND((tc.placement_sum + d.placement_sum)::DECIMAL / NULLIF(tc.total_games + d.games, 0), 2),
+        win_rate = ROUND(((tc.total_wins + d.wins)::DECIMAL / NULLIF(tc.total_games + d.games, 0)) * 100, 2),
+        top4_rate = ROUND(((tc.total_top4 + d.top4)::DECIMAL / NULLIF(tc.total_games + d.games, 0)) * 100, 2),
+        last_seen = CASE WHEN d.games > 0 THEN NOW() ELSE tc.last_seen END,
+        last_updated = NOW()
+    FROM jsonb_to_recordset(p_deltas) as d(
+        comp_hash VARCHAR(64),
+        games INTEGER,
+        wins INTEGER,
+        top4 INTEGER,
+        placement_sum BIGINT
+    )
+    WHERE tc.comp_hash = d.comp_hash;
+
+    -- Pick rate uses one bucket count per batch instead of one per row
+    UPDATE compositions.team_comps tc
+    SET pick_rate = ROUND((tc.total_games::DECIMAL / NULLIF(b.comp_count, 0)) * 100, 2)
+    FROM (
+        SELECT tc2.patch_version, tc2.region, tc2.tier, COUNT(DISTINCT tc2.comp_hash) as comp_count
+        FROM compositions.team_comps tc2
+        WHERE (tc2.patch_version, tc2.region, tc2.tier) IN (
+            SELECT t.patch_version, t.region, t.tier
+            FROM compositions.team_comps t
+            WHERE t.comp_hash IN (SELECT d->>'comp_hash' FROM jsonb_array_elements(p_deltas) as d)
+        )
+        GROUP BY tc2.patch_version, tc2.region, tc2.tier
+    ) b
+    WHERE tc.comp_hash IN (SELECT d->>'comp_hash' FROM jsonb_array_elements(p_deltas) as d)
+      AND tc.patch_version = b.patch_version
+      AND tc.region = b.region
+      AND tc.tier = b.tier;
+END;
+$$ LANGUAGE plpgsql;
+
+-- =====================================================
+-- Statement-Level Triggers (one delta pass per batch)
+-- =====================================================
+CREATE OR REPLACE FUNCTION compositions.apply_inserted_comp_games()
+RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM compositions.apply_comp_game_deltas(
+        COALESCE((
+            SELECT jsonb_agg(jsonb_build_object(
+                'comp_hash', comp_hash,
+                'games', games,
+                'wins', wins,
+                'top4', top4,
+                'placement_sum', placement_sum
+            ))
+            FROM (
+                SELECT
+                    comp_hash,
+                    COUNT(*) as games,
+                    COUNT(*) FILTER (WHERE placement = 1) as wins,
+                    COUNT(*) FILTER (WHERE placement <= 4) as top4,
+                    SUM(placement) as placement_sum
+                FROM new_games
+                GROUP BY comp_hash
+            ) g
+        ), '[]'::jsonb)
+    );
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION compositions.apply_deleted_comp_games()
+RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM compositions.apply_comp_game_deltas(
+        COALESCE((
+            SELECT jsonb_agg(jsonb_build_object(
+                'comp_hash', comp_hash,
+                'games', -games,
+                'wins', -wins,
+                'top4', -top4,
+                'placement_sum', -placement_sum
+            ))
+            FROM (
+                SELECT
+                    comp_hash,
+                    COUNT(*) as games,
+                    COUNT(*) FILTER (WHERE placement = 1) as wins,
+                    COUNT(*) FILTER (WHERE placement <= 4) as top4,
+                    SUM(placement) as placement_sum
+                FROM old_games
+                GROUP BY comp_hash
+            ) g
+        ), '[]'::jsonb)
+    );
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trigger_apply_inserted_comp_games
+    AFTER INSERT ON compositions.comp_games
+    REFERENCING NEW TABLE AS new_games
+    FOR EACH STATEMENT
+    EXECUTE FUNCTION compositions.apply_inserted_comp_games();
+
+CREATE TRIGGER trigger_apply_deleted_comp_games
+    AFTER DELETE ON compositions.comp_games
+    REFERENCING OLD TABLE AS old_games
+    FOR EACH STATEMENT
+    EXECUTE FUNCTION compositions.apply_deleted_comp_games();
+
+-- =====================================================
+-- Full Rollup (repair / verification of counters)
+-- =====================================================
+CREATE OR REPLACE FUNCTION compositions.rebuild_comp_stats()
+RETURNS INTEGER AS $$
+DECLARE
+    updated_count INTEGER;
+BEGIN
+    UPDATE compositions.team_comps tc
+    SET
+        total_games = COALESCE(g.games, 0),
+        total_wins = COALESCE(g.wins, 0),
+        total_top4 = COALESCE(g.top4, 0),
+        placement_sum = COALESCE(g.placement_sum, 0),
+        avg_placement = ROUND(g.placement_sum::DECIMAL / NULLIF(g.games, 0), 2),
+        win_rate = ROUND((COALESCE(g.wins, 0)::DECIMAL / NULLIF(g.games, 0)) * 100, 2),
+        top4_rate = ROUND((COALESCE(g.top4, 0)::DECIMAL / NULLIF(g.games, 0)) * 100, 2),
+        last_updated = NOW()
+    FROM compositions.team_comps base
+    LEFT JOIN (
+        SELECT
+            comp_hash,
+            COUNT(*) as games,
+            COUNT(*) FILTER (WHERE placement = 1) as wins,
+            COUNT(*) FILTER (WHERE placement <= 4) as top4,
+            SUM(placement) as placement_sum
+        FROM compositions.comp_games
+        GROUP BY comp_hash
+    ) g ON g.comp_hash = base.comp_hash
+    WHERE tc.id = base.id
+      AND (
+        tc.total_games IS DISTINCT FROM COALESCE(g.games, 0)
+        OR tc.total_wins IS DISTINCT FROM COALESCE(g.wins, 0)
+        OR tc.total_top4 IS DISTINCT FROM COALESCE(g.top4, 0)
+        OR tc.placement_sum IS DISTINCT FROM COALESCE(g.placement_sum, 0)
+      );
+
+    GET DIAGNOSTICS updated_count = ROW_COUNT;
+
+    UPDATE compositions.team_comps tc
+    SET pick_rate = ROUND((tc.total_games::DECIMAL / NULLIF(b.comp_count, 0)) * 100, 2)
+    FROM (
+        SELECT patch_version, region, tier, COUNT(DISTINCT comp_hash) as comp_count
+        FROM compositions.team_comps
+        GROUP BY patch_version, region, tier
+    ) b
+    WHERE tc.patch_version = b.patch_version
+      AND tc.region = b.region
+      AND tc.tier = b.tier;
+
+    RETURN updated_count;
+END;
+$$ LANGUAGE plpgsql;
+
+-- =====================================================
+-- Grant Permissions
+-- =====================================================
+GRANT ALL PRIVILEGES ON ALL FUNCTIONS IN SCHEMA compositions TO tft_user;
+
+-- =====================================================
+-- Comments
+-- =====================================================
+COMMENT ON COLUMN compositions.team_comps.placement_sum IS 'Running sum of placements, used to maintain avg_placement incrementally';
+COMMENT ON FUNCTION compositions.apply_comp_game_deltas(JSONB) IS 'Applies per-composition counter deltas for a batch of inserted or deleted games';
+COMMENT ON FUNCTION compositions.apply_inserted_comp_games() IS 'Statement-level trigger applying counter deltas for all games inserted by one statement';
+COMMENT ON FUNCTION compositions.apply_deleted_comp_games() IS 'Statement-level trigger removing counter contributions of deleted games';
+COMMENT ON FUNCTION compositions.rebuild_comp_stats() IS 'Periodic rollup recomputing all composition counters from comp_games; returns rows corrected';
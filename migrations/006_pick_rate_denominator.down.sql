@@ -0,0 +1,64 @@
+-- =====================================================
+-- File: migrations/006_pick_rate_denominator.down.sql
+-- =====================================================
+DROP FUNCTION IF EXISTS compositions.recalculate_pick_rates(VARCHAR, VARCHAR);
+
+DROP MATERIALIZED VIEW IF EXISTS compositions.comp_leaderboard;
+
+CREATE MATERIALIZED VIEW compositions.comp_leaderboard AS
+SELECT
+    tc.id,
+    tc.comp_hash,
+    tc.patch_version,
+    tc.region,
+    tc.tier,
+    tc.traits,
+    tc.units,
+    tc.total_games,
+    tc.total_wins,
+    tc.total_top4,
+    tc.avg_placement,
+    tc.win_rate,
+    tc.top4_rate,
+    tc.pick_rate,
+    CASE
+        WHEN tc.win_rate >= 25 AND tc.pick_rate >= 5 AND tc.total_games >= 500 THEN 'S'
+        WHEN tc.win_rate >= 20 AND tc.pick_rate >= 3 AND tc.total_games >= 300 THEN 'A'
+        WHEN tc.win_rate >= 15 AND tc.pick_rate >= 1 AND tc.total_games >= 100 THEN 'B'
+        WHEN tc.total_games >= 50 THEN 'C'
+        ELSE 'D'
+    END as tier_rank,
+    CASE
+        WHEN tc.total_games >= 1000 THEN 'high'
+        WHEN tc.total_games >= 100 THEN 'medium'
+        WHEN tc.total_games >= 50 THEN 'low'
+        ELSE 'insufficient'
+    END as sample_size,
+    tc.first_seen,
+    tc.last_seen,
+    tc.last_updated
+FROM compositions.team_comps tc
+WHERE tc.total_games >= 50
+ORDER BY
+    tc.patch_version DESC,
+    tc.region,
+    tc.tier,
+    tc.win_rate DESC,
+    tc.pick_rate DESC;
+
+CREATE UNIQUE INDEX idx_comp_leaderboard_id ON compositions.comp_leaderboard (id);
+CREATE INDEX idx_comp_leaderboard_tier_rank ON compositions.comp_leaderboard (tier_rank);
+CREATE INDEX idx_comp_leaderboard_patch_region ON compositions.comp_leaderboard (patch_version, region);
+CREATE INDEX idx_comp_leaderboard_performance ON compositions.comp_leaderboard (
+    tier_rank, win_rate DESC, pick_rate DESC
+);
+
+DROP INDEX IF EXISTS compositions.idx_team_comps_bucket;
+CREATE INDEX idx_team_comps_patch_region_tier ON compositions.team_comps (patch_version, region, tier);
+
+ALTER TABLE compositions.team_comps
+    DROP CONSTRAINT IF EXISTS check_queue_id_valid,
+    DROP CONSTRAINT IF EXISTS check_pick_rate_sample_size_positive,
+    DROP COLUMN IF EXISTS queue_id,
+    DROP COLUMN IF EXISTS pick_rate_sample_size,
+    DROP COLUMN IF EXISTS pick_rate_updated_at;
@@ -0,0 +1,215 @@
+-- =====================================================
+-- TFT Arena - Pick Rate Denominator
+-- File: migrations/006_pick_rate_denominator.up.sql
+-- =====================================================
+
+-- =====================================================
+-- Queue and Sample Size Columns
+-- =====================================================
+ALTER TABLE compositions.team_comps
+    ADD COLUMN queue_id INTEGER NOT NULL DEFAULT 1100,
+    ADD COLUMN pick_rate_sample_size INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN pick_rate_updated_at TIMESTAMP WITH TIME ZONE;
+
+ALTER TABLE compositions.team_comps
+    ADD CONSTRAINT check_queue_id_valid CHECK (queue_id IN (1090, 1100, 1130, 1160)),
+    ADD CONSTRAINT check_pick_rate_sample_size_positive CHECK (pick_rate_sample_size >= 0);
+
+-- Attribute each existing comp to the queue most of its games came from
+UPDATE compositions.team_comps tc
+SET queue_id = q.queue_id
+FROM (
+    SELECT DISTINCT ON (cg.comp_hash) cg.comp_hash, m.queue_id
+    FROM compositions.comp_games cg
+    JOIN matches.matches m ON m.match_id = cg.match_id
+    GROUP BY cg.comp_hash, m.queue_id
+    ORDER BY cg.comp_hash, COUNT(*) DESC
+) q
+WHERE tc.comp_hash = q.comp_hash;
+
+DROP INDEX IF EXISTS compositions.idx_team_comps_patch_region_tier;
+CREATE INDEX idx_team_comps_bucket ON compositions.team_comps (patch_version, region, tier, queue_id);
+
+-- =====================================================
+-- Counter Deltas no longer touch pick_rate
+-- =====================================================
+CREATE OR REPLACE FUNCTION compositions.apply_comp_game_deltas(
+    p_deltas JSONB
+)
+RETURNS VOID AS $$
+BEGIN
+    UPDATE compositions.team_comps tc
+    SET
+        total_games = tc.total_games + d.games,
+        total_wins = tc.total_wins + d.wins,
+        total_top4 = tc.total_top4 + d.top4,
+        placement_sum = tc.placement_sum + d.placement_sum,
+        avg_placement = ROUND((tc.placement_sum + d.placement_sum)::DECIMAL / NULLIF(tc.total_games + d.games, 0), 2),
+        win_rate = ROUND(((tc.total_wins + d.wins)::DECIMAL / NULLIF(tc.total_games + d.games, 0)) * 100, 2),
+        top4_rate = ROUND(((tc.total_top4 + d.top4)::DECIMAL / NULLIF(tc.total_games + d.games, 0)) * 100, 2),
+        last_seen = CASE WHEN d.games > 0 THEN NOW() ELSE tc.last_seen END,
+        last_updated = NOW()
+    FROM jsonb_to_recordset(p_deltas) as d(
+        comp_hash VARCHAR(64),
+        games INTEGER,
+        wins INTEGER,
+        top4 INTEGER,
+        placement_sum BIGINT
+    )
+    WHERE tc.comp_hash = d.comp_hash;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION compositions.rebuild_comp_stats()
+RETURNS INTEGER AS $$
+DECLARE
+    updated_count INTEGER;
+BEGIN
+    UPDATE compositions.team_comps tc
+    SET
+        total_games = COALESCE(g.games, 0),
+        total_wins = COALESCE(g.wins, 0),
+        total_top4 = COALESCE(g.top4, 0),
+        placement_sum = COALESCE(g.placement_sum, 0),
+        avg_placement = ROUND(g.placement_sum::DECIMAL / NULLIF(g.games, 0), 2),
+        win_rate = ROUND((COALESCE(g.wins, 0)::DECIMAL / NULLIF(g.games, 0)) * 100, 2),
+        top4_rate = ROUND((COALESCE(g.top4, 0)::DECIMAL / NULLIF(g.games, 0)) * 100, 2),
+        last_updated = NOW()
+    FROM compositions.team_comps base
+    LEFT JOIN (
+        SELECT
+            comp_hash,
+            COUNT(*) as games,
+            COUNT(*) FILTER (WHERE placement = 1) as wins,
+            COUNT(*) FILTER (WHERE placement <= 4) as top4,
+            SUM(placement) as placement_sum
+        FROM compositions.comp_games
+        GROUP BY comp_hash
+    ) g ON g.comp_hash = base.comp_hash
+    WHERE tc.id = base.id
+      AND (
+        tc.total_games IS DISTINCT FROM COALESCE(g.games, 0)
+        OR tc.total_wins IS DISTINCT FROM COALESCE(g.wins, 0)
+        OR tc.total_top4 IS DISTINCT FROM COALESCE(g.top4, 0)
+        OR tc.placement_sum IS DISTINCT FROM COALESCE(g.placement_sum, 0)
+      );
+
+    GET DIAGNOSTICS updated_count = ROW_COUNT;
+
+    RETURN updated_count;
+END;
+$$ LANGUAGE plpgsql;
+
+-- =====================================================
+-- Batch Pick Rate Recalculation
+-- =====================================================
+-- pick_rate = comp games / participant boards in the same
+-- patch/region/tier/queue bucket. Every comp game is one board,
+-- so the bucket's boards are the sum of its comps' games.
+CREATE OR REPLACE FUNCTION compositions.recalculate_pick_rates(
+    p_patch VARCHAR(10) DEFAULT NULL,
+    p_region VARCHAR(10) DEFAULT NULL
+)
+RETURNS INTEGER AS $$
+DECLARE
+    updated_count INTEGER;
+BEGIN
+    UPDATE compositions.team_comps tc
+    SET
+        pick_rate = ROUND((tc.total_games::DECIMAL / NULLIF(b.boards, 0)) * 100, 2),
+        pick_rate_sample_size = b.boards,
+        pick_rate_updated_at = NOW()
+    FROM (
+        SELECT patch_version, region, tier, queue_id, SUM(total_games)::int as boards
+        FROM compositions.team_comps
+        WHERE (p_patch IS NULL OR patch_version = p_patch)
+          AND (p_region IS NULL OR region = p_region)
+        GROUP BY patch_version, region, tier, queue_id
+    ) b
+    WHERE tc.patch_version = b.patch_version
+      AND tc.region = b.region
+      AND tc.tier = b.tier
+      AND tc.queue_id = b.queue_id;
+
+    GET DIAGNOSTICS updated_count = ROW_COUNT;
+
+    RETURN updated_count;
+END;
+$$ LANGUAGE plpgsql;
+
+SELECT compositions.recalculate_pick_rates();
+
+-- =====================================================
+-- Leaderboard View with Queue and Sample Size
+-- =====================================================
+DROP MATERIALIZED VIEW IF EXISTS compositions.comp_leaderboard;
+
+CREATE MATERIALIZED VIEW compositions.comp_leaderboard AS
+SELECT
+    tc.id,
+    tc.comp_hash,
+    tc.patch_version,
+    tc.region,
+    tc.tier,
+    tc.queue_id,
+    tc.traits,
+    tc.units,
+    tc.total_games,
+    tc.total_wins,
+    tc.total_top4,
+    tc.avg_placement,
+    tc.win_rate,
+    tc.top4_rate,
+    tc.pick_rate,
+    tc.pick_rate_sample_size,
+
+    -- Calculate tier rank based on performance
+    CASE
+        WHEN tc.win_rate >= 25 AND tc.pick_rate >= 5 AND tc.total_games >= 500 THEN 'S'
+        WHEN tc.win_rate >= 20 AND tc.pick_rate >= 3 AND tc.total_games >= 300 THEN 'A'
+        WHEN tc.win_rate >= 15 AND tc.pick_rate >= 1 AND tc.total_games >= 100 THEN 'B'
+        WHEN tc.total_games >= 50 THEN 'C'
+        ELSE 'D'
+    END as tier_rank,
+
+    -- Sample size categorization
+    CASE
+        WHEN tc.total_games >= 1000 THEN 'high'
+        WHEN tc.total_games >= 100 THEN 'medium'
+        WHEN tc.total_games >= 50 THEN 'low'
+        ELSE 'insufficient'
+    END as sample_size,
+
+    tc.first_seen,
+    tc.last_seen,
+    tc.last_updated
+FROM compositions.team_comps tc
+WHERE tc.total_games >= 50  -- Minimum threshold for leaderboard
+ORDER BY
+    tc.patch_version DESC,
+    tc.region,
+    tc.tier,
+    tc.queue_id,
+    tc.win_rate DESC,
+    tc.pick_rate DESC;
+
+CREATE UNIQUE INDEX idx_comp_leaderboard_id ON compositions.comp_leaderboard (id);
+CREATE INDEX idx_comp_leaderboard_tier_rank ON compositions.comp_leaderboard (tier_rank);
+CREATE INDEX idx_comp_leaderboard_patch_region ON compositions.comp_leaderboard (patch_version, region, queue_id);
+CREATE INDEX idx_comp_leaderboard_performance ON compositions.comp_leaderboard (
+    tier_rank, win_rate DESC, pick_rate DESC
+);
+
+-- =====================================================
+-- Grant Permissions
+-- =====================================================
+GRANT ALL PRIVILEGES ON ALL TABLES IN SCHEMA compositions TO tft_user;
+GRANT ALL PRIVILEGES ON ALL FUNCTIONS IN SCHEMA compositions TO tft_user;
+
+-- =====================================================
+-- Comments
+-- =====================================================
+COMMENT ON COLUMN compositions.team_comps.queue_id IS 'Queue the composition games were played in';
+COMMENT ON COLUMN compositions.team_comps.pick_rate_sample_size IS 'Participant boards in the patch/region/tier/queue bucket used as pick rate denominator';
+COMMENT ON FUNCTION compositions.recalculate_pick_rates(VARCHAR, VARCHAR) IS 'Batch recomputation of pick rates against participant boards per bucket';
+COMMENT ON MATERIALIZED VIEW compositions.comp_leaderboard IS 'Optimized view for leaderboard queries with pre-calculated tier rankings';
@@ -0,0 +1,18 @@
+-- =====================================================
+-- File: migrations/007_create_patch_registry.down.sql
+-- =====================================================
+ALTER TABLE compositions.team_comps DROP CONSTRAINT IF EXISTS check_patch_format;
+ALTER TABLE compositions.team_comps
+    ADD CONSTRAINT check_patch_format CHECK (patch_version ~ '^15\.[0-9]+[a-z]?$');
+
+CREATE OR REPLACE FUNCTION matches.extract_patch_trigger()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.patch_version = matches.extract_patch_from_version(NEW.game_version);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP FUNCTION IF EXISTS matches.current_patch();
+DROP FUNCTION IF EXISTS matches.register_patch(VARCHAR, VARCHAR, INTEGER, BIGINT);
+DROP TABLE IF EXISTS matches.patches;
@@ -0,0 +1,125 @@
+-- =====================================================
+-- TFT Arena - Patch Registry
+-- File: migrations/007_create_patch_registry.up.sql
+-- =====================================================
+
+-- =====================================================
+-- Patches Table
+-- =====================================================
+CREATE TABLE matches.patches (
+    patch_version VARCHAR(10) PRIMARY KEY,
+    major_version INTEGER NOT NULL,
+    minor_version INTEGER NOT NULL,
+    revision VARCHAR(1) NOT NULL DEFAULT '',
+    set_number INTEGER,
+    release_date DATE NOT NULL,
+    sample_game_version VARCHAR(20),
+
+    first_seen_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    last_seen_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    CONSTRAINT check_patch_format CHECK (patch_version ~ '^[0-9]+\.[0-9]+[a-z]?$'),
+    CONSTRAINT check_major_positive CHECK (major_version > 0),
+    CONSTRAINT check_minor_positive CHECK (minor_version >= 0),
+    CONSTRAINT check_set_number_valid CHECK (set_number IS NULL OR set_number >= 1)
+);
+
+CREATE INDEX idx_patches_order ON matches.patches (major_version DESC, minor_version DESC, revision DESC);
+CREATE INDEX idx_patches_set_number ON matches.patches (set_number);
+CREATE INDEX idx_patches_release_date ON matches.patches (release_date DESC);
+
+-- =====================================================
+-- Registration from Game Versions
+-- =====================================================
+CREATE OR REPLACE FUNCTION matches.register_patch(
+    p_patch VARCHAR(10),
+    p_game_version VARCHAR(20),
+    p_set_number INTEGER,
+    p_game_datetime BIGINT
+)
+RETURNS VOID AS $$
+DECLARE
+    parts TEXT[];
+BEGIN
+    IF p_patch IS NULL THEN
+        RETURN;
+    END IF;
+
+    parts := regexp_match(p_patch, '^([0-9]+)\.([0-9]+)([a-z]?)$');
+    IF parts IS NULL THEN
+        RETURN;
+    END IF;
+
+    INSERT INTO matches.patches (
+        patch_version, major_version, minor_version, revision,
+        set_number, release_date, sample_game_version
+    )
+    VALUES (
+        p_patch, parts[1]::int, parts[2]::int, parts[3],
+        p_set_number, DATE(to_timestamp(p_game_datetime)), p_game_version
+    )
+    ON CONFLICT (patch_version) DO UPDATE SET
+        set_number = COALESCE(matches.patches.set_number, EXCLUDED.set_number),
+        release_date = LEAST(matches.patches.release_date, EXCLUDED.release_date),
+        last_seen_at = NOW();
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION matches.extract_patch_trigger()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.patch_version = matches.extract_patch_from_version(NEW.game_version);
+    PERFORM matches.register_patch(
+        NEW.patch_version, NEW.game_version, NEW.tft_set_number, NEW.game_datetime
+    );
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION matches.current_patch()
+RETURNS VARCHAR(10) AS $$
+    SELECT patch_version
+    FROM matches.patches
+    ORDER BY major_version DESC, minor_version DESC, revision DESC
+    LIMIT 1;
+$$ LANGUAGE sql STABLE;
+
+-- =====================================================
+-- Backfill from Existing Matches
+-- =====================================================
+INSERT INTO matches.patches (
+    patch_version, major_version, minor_version, revision,
+    set_number, release_date, sample_game_version
+)
+SELECT
+    m.patch_version,
+    (regexp_match(m.patch_version, '^([0-9]+)\.([0-9]+)([a-z]?)$'))[1]::int,
+    (regexp_match(m.patch_version, '^([0-9]+)\.([0-9]+)([a-z]?)$'))[2]::int,
+    (regexp_match(m.patch_version, '^([0-9]+)\.([0-9]+)([a-z]?)$'))[3],
+    MAX(m.tft_set_number),
+    DATE(to_timestamp(MIN(m.game_datetime))),
+    MIN(m.game_version)
+FROM matches.matches m
+WHERE m.patch_version ~ '^[0-9]+\.[0-9]+[a-z]?$'
+GROUP BY m.patch_version
+ON CONFLICT (patch_version) DO NOTHING;
+
+-- =====================================================
+-- Set-Agnostic Patch Format on Compositions
+-- =====================================================
+ALTER TABLE compositions.team_comps DROP CONSTRAINT IF EXISTS check_patch_format;
+ALTER TABLE compositions.team_comps
+    ADD CONSTRAINT check_patch_format CHECK (patch_version ~ '^[0-9]+\.[0-9]+[a-z]?$');
+
+-- =====================================================
+-- Grant Permissions
+-- =====================================================
+GRANT ALL PRIVILEGES ON ALL TABLES IN SCHEMA matches TO tft_user;
+GRANT ALL PRIVILEGES ON ALL FUNCTIONS IN SCHEMA matches TO tft_user;
+
+-- =====================================================
+-- Comments
+-- =====================================================
+COMMENT ON TABLE matches.patches IS 'Registry of patches seen in ingested matches, populated by the patch extraction trigger';
+COMMENT ON FUNCTION matches.register_patch(VARCHAR, VARCHAR, INTEGER, BIGINT) IS 'Records a patch and its earliest game date as release date';
+COMMENT ON FUNCTION matches.current_patch() IS 'Returns the highest known patch version';
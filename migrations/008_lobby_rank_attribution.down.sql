@@ -0,0 +1,41 @@
+-- =====================================================
+-- File: migrations/008_lobby_rank_attribution.down.sql
+-- =====================================================
+DROP FUNCTION IF EXISTS compositions.comp_stats_for_tier(VARCHAR, VARCHAR, VARCHAR);
+
+DROP TRIGGER IF EXISTS trigger_copy_participant_rank ON compositions.comp_games;
+DROP FUNCTION IF EXISTS compositions.copy_participant_rank();
+
+DROP TRIGGER IF EXISTS trigger_attribute_inserted_participants ON matches.participants;
+DROP FUNCTION IF EXISTS matches.attribute_inserted_participants();
+DROP FUNCTION IF EXISTS matches.reattribute_unranked_matches(INTEGER);
+DROP FUNCTION IF EXISTS matches.attribute_lobby_ranks(VARCHAR);
+
+DROP INDEX IF EXISTS compositions.idx_comp_games_hash_tier;
+DROP INDEX IF EXISTS matches.idx_matches_lobby_tier;
+DROP INDEX IF EXISTS matches.idx_participants_unranked;
+DROP INDEX IF EXISTS matches.idx_participants_tier;
+
+ALTER TABLE compositions.comp_games
+    DROP COLUMN IF EXISTS tier,
+    DROP COLUMN IF EXISTS lobby_tier;
+
+ALTER TABLE matches.matches
+    DROP CONSTRAINT IF EXISTS check_lobby_tier_valid,
+    DROP COLUMN IF EXISTS lobby_tier,
+    DROP COLUMN IF EXISTS lobby_tier_weight,
+    DROP COLUMN IF EXISTS ranked_participants;
+
+ALTER TABLE matches.participants
+    DROP CONSTRAINT IF EXISTS check_participant_tier_valid,
+    DROP COLUMN IF EXISTS tier,
+    DROP COLUMN IF EXISTS rank_division,
+    DROP COLUMN IF EXISTS league_points,
+    DROP COLUMN IF EXISTS rank_recorded_at;
+
+DROP TRIGGER IF EXISTS trigger_record_league_entry_history ON players.league_entries;
+DROP FUNCTION IF EXISTS players.record_league_entry_history();
+DROP TABLE IF EXISTS players.league_entry_history;
+
+DROP FUNCTION IF EXISTS players.tier_from_weight(INTEGER);
+DROP FUNCTION IF EXISTS players.tier_weight(VARCHAR);
@@ -0,0 +1,309 @@
+-- =====================================================
+-- TFT Arena - Lobby Rank Attribution
+-- File: migrations/008_lobby_rank_attribution.up.sql
+-- =====================================================
+
+-- =====================================================
+-- Tier Weight Helpers
+-- =====================================================
+CREATE OR REPLACE FUNCTION players.tier_weight(p_tier VARCHAR(20))
+RETURNS INTEGER AS $$
+    SELECT CASE p_tier
+        WHEN 'IRON' THEN 1
+        WHEN 'BRONZE' THEN 2
+        WHEN 'SILVER' THEN 3
+        WHEN 'GOLD' THEN 4
+        WHEN 'PLATINUM' THEN 5
+        WHEN 'EMERALD' THEN 6
+        WHEN 'DIAMOND' THEN 7
+        WHEN 'MASTER' THEN 8
+        WHEN 'GRANDMASTER' THEN 9
+        WHEN 'CHALLENGER' THEN 10
+    END;
+$$ LANGUAGE sql IMMUTABLE;
+
+CREATE OR REPLACE FUNCTION players.tier_from_weight(p_weight INTEGER)
+RETURNS VARCHAR(20) AS $$
+    SELECT CASE p_weight
+        WHEN 1 THEN 'IRON'
+        WHEN 2 THEN 'BRONZE'
+        WHEN 3 THEN 'SILVER'
+        WHEN 4 THEN 'GOLD'
+        WHEN 5 THEN 'PLATINUM'
+        WHEN 6 THEN 'EMERALD'
+        WHEN 7 THEN 'DIAMOND'
+        WHEN 8 THEN 'MASTER'
+        WHEN 9 THEN 'GRANDMASTER'
+        WHEN 10 THEN 'CHALLENGER'
+    END;
+$$ LANGUAGE sql IMMUTABLE;
+
+-- =====================================================
+-- League Entry History (rank at a point in time)
+-- =====================================================
+CREATE TABLE players.league_entry_history (
+    id BIGSERIAL PRIMARY KEY,
+    puuid VARCHAR(78) NOT NULL REFERENCES players.summoners(puuid) ON DELETE CASCADE,
+    queue_type VARCHAR(20) NOT NULL,
+    region VARCHAR(10) NOT NULL,
+    tier VARCHAR(20) NOT NULL,
+    rank_division VARCHAR(5),
+    league_points INTEGER NOT NULL,
+    recorded_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_league_entry_history_lookup ON players.league_entry_history (
+    puuid, queue_type, region, recorded_at DESC
+);
+
+CREATE OR REPLACE FUNCTION players.record_league_entry_history()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'UPDATE'
+       AND NEW.tier = OLD.tier
+       AND NEW.rank_division IS NOT DISTINCT FROM OLD.rank_division
+       AND NEW.league_points = OLD.league_points THEN
+        RETURN NEW;
+    END IF;
+
+    INSERT INTO players.league_entry_history (
+        puuid, queue_type, region, tier, rank_division, league_points
+    )
+    VALUES (
+        NEW.puuid, NEW.queue_type, NEW.region, NEW.tier, NEW.rank_division, NEW.league_points
+    );
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trigger_record_league_entry_history
+    AFTER INSERT OR UPDATE ON players.league_entries
+    FOR EACH ROW
+    EXECUTE FUNCTION players.record_league_entry_history();
+
+INSERT INTO players.league_entry_history (
+    puuid, queue_type, region, tier, rank_division, league_points, recorded_at
+)
+SELECT puuid, queue_type, region, tier, rank_division, league_points, COALESCE(updated_at, created_at, NOW())
+FROM players.league_entries;
+
+-- =====================================================
+-- Rank Columns on Participants, Matches and Comp Games
+-- =====================================================
+ALTER TABLE matches.participants
+    ADD COLUMN tier VARCHAR(20),
+    ADD COLUMN rank_division VARCHAR(5),
+    ADD COLUMN league_points INTEGER,
+    ADD COLUMN rank_recorded_at TIMESTAMP WITH TIME ZONE,
+    ADD CONSTRAINT check_participant_tier_valid CHECK (tier IS NULL OR tier IN (
+        'IRON', 'BRONZE', 'SILVER', 'GOLD', 'PLATINUM', 'EMERALD',
+        'DIAMOND', 'MASTER', 'GRANDMASTER', 'CHALLENGER'
+    ));
+
+ALTER TABLE matches.matches
+    ADD COLUMN lobby_tier VARCHAR(20),
+    ADD COLUMN lobby_tier_weight DECIMAL(4,2),
+    ADD COLUMN ranked_participants INTEGER NOT NULL DEFAULT 0,
+    ADD CONSTRAINT check_lobby_tier_valid CHECK (lobby_tier IS NULL OR lobby_tier IN (
+        'IRON', 'BRONZE', 'SILVER', 'GOLD', 'PLATINUM', 'EMERALD',
+        'DIAMOND', 'MASTER', 'GRANDMASTER', 'CHALLENGER'
+    ));
+
+ALTER TABLE compositions.comp_games
+    ADD COLUMN tier VARCHAR(20),
+    ADD COLUMN lobby_tier VARCHAR(20);
+
+CREATE INDEX idx_participants_tier ON matches.participants (tier);
+CREATE INDEX idx_participants_unranked ON matches.participants (match_id) WHERE tier IS NULL;
+CREATE INDEX idx_matches_lobby_tier ON matches.matches (region, patch_version, lobby_tier);
+CREATE INDEX idx_comp_games_hash_tier ON compositions.comp_games (comp_hash, tier);
+
+-- =====================================================
+-- Attribution
+-- =====================================================
+-- Each participant gets the latest rank recorded at or before game time.
+-- When the player was first seen after the game, the earliest later
+-- snapshot is used instead, as the closest known rank.
+CREATE OR REPLACE FUNCTION matches.attribute_lobby_ranks(p_match_id VARCHAR(50))
+RETURNS INTEGER AS $$
+DECLARE
+    ranked_count INTEGER;
+BEGIN
+    UPDATE matches.participants p
+    SET
+        tier = r.tier,
+        rank_division = r.rank_division,
+        league_points = r.league_points,
+        rank_recorded_at = r.recorded_at
+    FROM (
+        SELECT target.id, h.tier, h.rank_division, h.league_points, h.recorded_at
+        FROM matches.participants target
+        JOIN matches.matches m ON m.match_id = target.match_id
+        CROSS JOIN LATERAL (
+            SELECT snap.tier, snap.rank_division, snap.league_points, snap.recorded_at
+            FROM players.league_entry_history snap
+            WHERE snap.puuid = target.puuid
+              AND snap.region = m.region
+              AND snap.queue_type = CASE matches.get_queue_type_from_id(m.queue_id)
+                    WHEN 'RANKED_TFT_DOUBLE_UP' THEN 'RANKED_TFT_DOUBLE_UP'
+                    WHEN 'RANKED_TFT_TURBO' THEN 'RANKED_TFT_TURBO'
+                    ELSE 'RANKED_TFT'
+                  END
+            ORDER BY
+                snap.recorded_at <= to_timestamp(m.game_datetime) DESC,
+                CASE WHEN snap.recorded_at <= to_timestamp(m.game_datetime) THEN snap.recorded_at END DESC,
+                snap.recorded_at ASC
+            LIMIT 1
+        ) h
+        WHERE target.match_id = p_match_id
+    ) r
+    WHERE p.id = r.id;
+
+    SELECT COUNT(*) INTO ranked_count
+    FROM matches.participants
+    WHERE match_id = p_match_id AND tier IS NOT NULL;
+
+    UPDATE matches.matches m
+    SET
+        lobby_tier_weight = l.avg_weight,
+        lobby_tier = players.tier_from_weight(ROUND(l.avg_weight)::int),
+        ranked_participants = ranked_count
+    FROM (
+        SELECT ROUND(AVG(players.tier_weight(tier)), 2) as avg_weight
+        FROM matches.participants
+        WHERE match_id = p_match_id AND tier IS NOT NULL
+    ) l
+    WHERE m.match_id = p_match_id;
+
+    UPDATE compositions.comp_games cg
+    SET
+        tier = p.tier,
+        lobby_tier = m.lobby_tier
+    FROM matches.participants p
+    JOIN matches.matches m ON m.match_id = p.match_id
+    WHERE cg.match_id = p_match_id
+      AND p.match_id = cg.match_id
+      AND p.participant_id = cg.participant_id
+      AND (cg.tier IS DISTINCT FROM p.tier OR cg.lobby_tier IS DISTINCT FROM m.lobby_tier);
+
+    RETURN ranked_count;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Retries matches whose participants had no known rank at ingestion
+CREATE OR REPLACE FUNCTION matches.reattribute_unranked_matches(p_limit INTEGER DEFAULT 1000)
+RETURNS INTEGER AS $$
+DECLARE
+    target_match VARCHAR(50);
+    processed INTEGER := 0;
+BEGIN
+    FOR target_match IN
+        SELECT DISTINCT p.match_id
+        FROM matches.participants p
+        WHERE p.tier IS NULL
+        LIMIT p_limit
+    LOOP
+        PERFORM matches.attribute_lobby_ranks(target_match);
+        processed := processed + 1;
+    END LOOP;
+
+    RETURN processed;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Attribute ranks once per ingested batch of participants
+CREATE OR REPLACE FUNCTION matches.attribute_inserted_participants()
+RETURNS TRIGGER AS $$
+DECLARE
+    target_match VARCHAR(50);
+BEGIN
+    FOR target_match IN SELECT DISTINCT match_id FROM new_participants
+    LOOP
+        PERFORM matches.attribute_lobby_ranks(target_match);
+    END LOOP;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trigger_attribute_inserted_participants
+    AFTER INSERT ON matches.participants
+    REFERENCING NEW TABLE AS new_participants
+    FOR EACH STATEMENT
+    EXECUTE FUNCTION matches.attribute_inserted_participants();
+
+-- Comp games ingested after their match inherit the participant's rank
+CREATE OR REPLACE FUNCTION compositions.copy_participant_rank()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.tier IS NULL THEN
+        SELECT p.tier, m.lobby_tier
+        INTO NEW.tier, NEW.lobby_tier
+        FROM matches.participants p
+        JOIN matches.matches m ON m.match_id = p.match_id
+        WHERE p.match_id = NEW.match_id
+          AND p.participant_id = NEW.participant_id;
+    END IF;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trigger_copy_participant_rank
+    BEFORE INSERT ON compositions.comp_games
+    FOR EACH ROW
+    EXECUTE FUNCTION compositions.copy_participant_rank();
+
+-- =====================================================
+-- Tier-Filtered Composition Statistics
+-- =====================================================
+CREATE OR REPLACE FUNCTION compositions.comp_stats_for_tier(
+    p_patch VARCHAR(10),
+    p_region VARCHAR(10),
+    p_min_tier VARCHAR(20)
+)
+RETURNS TABLE (
+    comp_hash VARCHAR(64),
+    total_games BIGINT,
+    total_wins BIGINT,
+    total_top4 BIGINT,
+    avg_placement DECIMAL(4,2),
+    win_rate DECIMAL(5,2),
+    top4_rate DECIMAL(5,2)
+) AS $$
+    SELECT
+        cg.comp_hash,
+        COUNT(*),
+        COUNT(*) FILTER (WHERE cg.placement = 1),
+        COUNT(*) FILTER (WHERE cg.placement <= 4),
+        ROUND(AVG(cg.placement), 2),
+        ROUND((COUNT(*) FILTER (WHERE cg.placement = 1)::DECIMAL / COUNT(*)) * 100, 2),
+        ROUND((COUNT(*) FILTER (WHERE cg.placement <= 4)::DECIMAL / COUNT(*)) * 100, 2)
+    FROM compositions.comp_games cg
+    JOIN compositions.team_comps tc ON tc.comp_hash = cg.comp_hash
+    WHERE tc.patch_version = p_patch
+      AND tc.region = p_region
+      AND players.tier_weight(cg.tier) >= players.tier_weight(p_min_tier)
+    GROUP BY cg.comp_hash;
+$$ LANGUAGE sql STABLE;
+
+-- =====================================================
+-- Grant Permissions
+-- =====================================================
+GRANT ALL PRIVILEGES ON ALL TABLES IN SCHEMA players TO tft_user;
+GRANT ALL PRIVILEGES ON ALL SEQUENCES IN SCHEMA players TO tft_user;
+GRANT ALL PRIVILEGES ON ALL FUNCTIONS IN SCHEMA players TO tft_user;
+GRANT ALL PRIVILEGES ON ALL FUNCTIONS IN SCHEMA matches TO tft_user;
+GRANT ALL PRIVILEGES ON ALL FUNCTIONS IN SCHEMA compositions TO tft_user;
+
+-- =====================================================
+-- Comments
+-- =====================================================
+COMMENT ON TABLE players.league_entry_history IS 'Snapshots of league entries used to resolve a player''s rank at game time';
+COMMENT ON COLUMN matches.participants.tier IS 'Participant rank at game time, attributed from league entry history';
+COMMENT ON COLUMN matches.matches.lobby_tier IS 'Lobby average tier across ranked participants';
+COMMENT ON COLUMN compositions.comp_games.tier IS 'Rank of the player who played this board';
+COMMENT ON FUNCTION matches.attribute_lobby_ranks(VARCHAR) IS 'Attributes participant ranks and lobby average tier for one match';
+COMMENT ON FUNCTION matches.reattribute_unranked_matches(INTEGER) IS 'Retries rank attribution for matches with unranked participants';
+COMMENT ON FUNCTION compositions.comp_stats_for_tier(VARCHAR, VARCHAR, VARCHAR) IS 'Composition statistics restricted to boards played at or above a tier';
@@ -0,0 +1,4 @@
+-- =====================================================
+-- File: migrations/009_create_job_executions.down.sql
+-- =====================================================
+DROP SCHEMA IF EXISTS jobs CASCADE;
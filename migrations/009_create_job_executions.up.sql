@@ -0,0 +1,80 @@
+-- =====================================================
+-- TFT Arena - Job Execution History
+-- File: migrations/009_create_job_executions.up.sql
+-- =====================================================
+
+CREATE SCHEMA IF NOT EXISTS jobs;
+
+-- =====================================================
+-- Executions Table
+-- =====================================================
+-- One row per attempt. A job that is retried gets a new row for every
+-- attempt; the failed attempt is left in the 'retrying' state.
+CREATE TABLE jobs.executions (
+    id BIGSERIAL PRIMARY KEY,
+    job_id UUID NOT NULL,
+    job_type VARCHAR(50) NOT NULL,
+    payload JSONB NOT NULL DEFAULT '{}',
+    attempt INTEGER NOT NULL DEFAULT 0,
+    status VARCHAR(20) NOT NULL DEFAULT 'queued',
+
+    error TEXT,
+    retryable BOOLEAN,
+
+    enqueued_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    not_before TIMESTAMP WITH TIME ZONE,
+    started_at TIMESTAMP WITH TIME ZONE,
+    finished_at TIMESTAMP WITH TIME ZONE,
+    duration_ms BIGINT,
+    cancel_requested_at TIMESTAMP WITH TIME ZONE,
+
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    CONSTRAINT check_job_type_valid CHECK (job_type IN (
+        'collect_challenger', 'collect_player', 'analyze_compositions',
+        'refresh_leaderboard', 'cleanup_cache', 'update_meta_data'
+    )),
+    CONSTRAINT check_status_valid CHECK (status IN (
+        'queued', 'running', 'succeeded', 'retrying', 'dead_lettered', 'cancelled'
+    )),
+    CONSTRAINT check_attempt_positive CHECK (attempt >= 0),
+    CONSTRAINT check_duration_positive CHECK (duration_ms IS NULL OR duration_ms >= 0),
+
+    UNIQUE(job_id, attempt)
+);
+
+CREATE INDEX idx_executions_type_enqueued ON jobs.executions (job_type, enqueued_at DESC);
+CREATE INDEX idx_executions_status ON jobs.executions (status, enqueued_at DESC);
+CREATE INDEX idx_executions_pending ON jobs.executions (job_type)
+    WHERE status IN ('queued', 'running');
+
+-- =====================================================
+-- Per-Type Summary
+-- =====================================================
+CREATE VIEW jobs.type_summary AS
+SELECT
+    e.job_type,
+    (array_agg(e.status ORDER BY e.id DESC))[1] as last_status,
+    (array_agg(e.error ORDER BY e.id DESC) FILTER (WHERE e.error IS NOT NULL))[1] as last_error,
+    MAX(e.enqueued_at) as last_enqueued_at,
+    MAX(e.finished_at) FILTER (WHERE e.status = 'succeeded') as last_succeeded_at,
+    MAX(e.finished_at) FILTER (WHERE e.status IN ('retrying', 'dead_lettered')) as last_failed_at,
+    COUNT(*) FILTER (WHERE e.status = 'queued') as queued,
+    COUNT(*) FILTER (WHERE e.status = 'running') as running
+FROM jobs.executions e
+GROUP BY e.job_type;
+
+-- =====================================================
+-- Grant Permissions
+-- =====================================================
+GRANT USAGE ON SCHEMA jobs TO tft_user;
+GRANT ALL PRIVILEGES ON ALL TABLES IN SCHEMA jobs TO tft_user;
+GRANT ALL PRIVILEGES ON ALL SEQUENCES IN SCHEMA jobs TO tft_user;
+
+-- =====================================================
+-- Comments
+-- =====================================================
+COMMENT ON SCHEMA jobs IS 'Schema for background job execution history';
+COMMENT ON TABLE jobs.executions IS 'Every attempt of every background job, recorded by the worker pool';
+COMMENT ON VIEW jobs.type_summary IS 'Latest outcome and pending counts per job type';
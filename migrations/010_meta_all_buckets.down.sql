@@ -0,0 +1,238 @@
+-- =====================================================
+-- File: migrations/010_meta_all_buckets.down.sql
+-- =====================================================
+DROP FUNCTION IF EXISTS analytics.update_meta_from_matches(VARCHAR, BOOLEAN);
+DROP FUNCTION IF EXISTS analytics.recalculate_trait_meta(VARCHAR, VARCHAR, VARCHAR, INTEGER);
+DROP FUNCTION IF EXISTS analytics.recalculate_champion_meta(VARCHAR, VARCHAR, VARCHAR, INTEGER);
+DROP FUNCTION IF EXISTS analytics.mark_all_buckets_dirty(VARCHAR);
+DROP FUNCTION IF EXISTS analytics.active_patches(INTERVAL);
+
+DROP TRIGGER IF EXISTS trigger_mark_inserted_participants_dirty ON matches.participants;
+DROP TRIGGER IF EXISTS trigger_mark_updated_participants_dirty ON matches.participants;
+DROP FUNCTION IF EXISTS analytics.mark_participant_buckets_dirty();
+DROP TABLE IF EXISTS analytics.meta_dirty_buckets;
+
+DROP INDEX IF EXISTS matches.idx_matches_patch_region_queue;
+
+DROP MATERIALIZED VIEW IF EXISTS analytics.current_meta;
+
+TRUNCATE analytics.trait_meta, analytics.champion_meta;
+
+DROP INDEX IF EXISTS analytics.idx_trait_meta_bucket;
+DROP INDEX IF EXISTS analytics.idx_champion_meta_bucket;
+CREATE INDEX idx_trait_meta_patch_region_tier ON analytics.trait_meta (patch_version, region, tier);
+CREATE INDEX idx_champion_meta_patch_region_tier ON analytics.champion_meta (patch_version, region, tier);
+
+ALTER TABLE analytics.trait_meta
+    DROP CONSTRAINT IF EXISTS trait_meta_bucket_key,
+    DROP CONSTRAINT IF EXISTS check_tier_bucket_valid,
+    DROP CONSTRAINT IF EXISTS check_queue_id_valid,
+    DROP COLUMN IF EXISTS queue_id,
+    ADD CONSTRAINT trait_meta_trait_name_patch_version_region_tier_key UNIQUE (trait_name, patch_version, region, tier);
+
+ALTER TABLE analytics.champion_meta
+    DROP CONSTRAINT IF EXISTS champion_meta_bucket_key,
+    DROP CONSTRAINT IF EXISTS check_tier_bucket_valid,
+    DROP CONSTRAINT IF EXISTS check_queue_id_valid,
+    DROP COLUMN IF EXISTS queue_id,
+    ADD CONSTRAINT champion_meta_champion_name_patch_version_region_tier_key UNIQUE (champion_name, patch_version, region, tier);
+
+DROP FUNCTION IF EXISTS analytics.unit_cost(INTEGER);
+DROP FUNCTION IF EXISTS analytics.buckets_for_tier(VARCHAR);
+DROP FUNCTION IF EXISTS analytics.bucket_tiers(VARCHAR);
+
+CREATE MATERIALIZED VIEW analytics.current_meta AS
+SELECT 
+    tm.patch_version,
+    tm.region,
+    tm.tier,
+    
+    json_agg(
+        json_build_object(
+            'name', tm.trait_name,
+            'play_rate', tm.play_rate,
+            'win_rate', tm.win_rate,
+            'avg_placement', tm.avg_placement
+        ) ORDER BY tm.play_rate DESC
+    ) FILTER (WHERE tm.play_rate >= 10) as top_traits,
+    
+    json_agg(
+        json_build_object(
+            'name', cm.champion_name,
+            'cost', cm.cost,
+            'play_rate', cm.play_rate,
+            'win_rate', cm.win_rate,
+            'avg_tier', cm.avg_tier
+        ) ORDER BY cm.play_rate DESC
+    ) FILTER (WHERE cm.play_rate >= 5) as top_champions,
+    
+    AVG(tm.play_rate) as trait_diversity,
+    AVG(cm.play_rate) as champion_diversity,
+    
+    MAX(tm.updated_at) as last_updated
+FROM analytics.trait_meta tm
+FULL OUTER JOIN analytics.champion_meta cm 
+    ON tm.patch_version = cm.patch_version 
+    AND tm.region = cm.region 
+    AND tm.tier = cm.tier
+WHERE tm.total_games >= 100 OR cm.total_games >= 100
+GROUP BY tm.patch_version, tm.region, tm.tier
+ORDER BY tm.patch_version DESC, tm.region, tm.tier;
+
+-- Index on materialized view
+CREATE UNIQUE INDEX idx_current_meta_patch_region_tier ON analytics.current_meta (
+    patch_version, region, tier
+);
+
+CREATE OR REPLACE FUNCTION analytics.update_meta_from_matches()
+RETURNS VOID AS $$
+DECLARE
+    current_patch VARCHAR(10);
+    target_region VARCHAR(10);
+BEGIN
+    SELECT DISTINCT patch_version INTO current_patch
+    FROM matches.matches
+    ORDER BY created_at DESC
+    LIMIT 1;
+    
+    FOR target_region IN 
+        SELECT DISTINCT region FROM matches.matches 
+        WHERE patch_version = current_patch
+    LOOP
+        PERFORM analytics.recalculate_trait_meta(current_patch, target_region, 'CHALLENGER');
+        PERFORM analytics.recalculate_champion_meta(current_patch, target_region, 'CHALLENGER');
+        PERFORM analytics.recalculate_item_meta(current_patch, target_region, 'CHALLENGER');
+    END LOOP;
+    
+    REFRESH MATERIALIZED VIEW CONCURRENTLY analytics.current_meta;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION analytics.recalculate_trait_meta(
+    p_patch VARCHAR(10),
+    p_region VARCHAR(10), 
+    p_tier VARCHAR(20)
+)
+RETURNS VOID AS $$
+BEGIN
+    INSERT INTO analytics.trait_meta (
+        trait_name, patch_version, region, tier,
+        play_rate, win_rate, avg_placement, avg_count,
+        total_games, total_wins, total_top4,
+        style_distribution
+    )
+    SELECT 
+        trait_data->>'name' as trait_name,
+        p_patch,
+        p_region,
+        p_tier,
+        
+        ROUND((COUNT(*)::DECIMAL / (
+            SELECT COUNT(DISTINCT p2.match_id) 
+            FROM matches.participants p2 
+            JOIN matches.matches m2 ON p2.match_id = m2.match_id
+            WHERE m2.patch_version = p_patch AND m2.region = p_region
+        )) * 100, 2) as play_rate,
+        
+        ROUND((COUNT(*) FILTER (WHERE p.placement = 1)::DECIMAL / COUNT(*)) * 100, 2) as win_rate,
+        ROUND(AVG(p.placement), 2) as avg_placement,
+        ROUND(AVG((trait_data->>'num_units')::int), 2) as avg_count,
+        
+        COUNT(*)::int as total_games,
+        COUNT(*) FILTER (WHERE p.placement = 1) as total_wins,
+        COUNT(*) FILTER (WHERE p.placement <= 4) as total_top4,
+        
+        json_agg(
+            json_build_object(
+                'style', (trait_data->>'style')::int,
+                'count', COUNT(*)
+            )
+        ) as style_distribution
+        
+    FROM matches.participants p
+    JOIN matches.matches m ON p.match_id = m.match_id
+    CROSS JOIN LATERAL jsonb_array_elements(p.traits) as trait_data
+    WHERE m.patch_version = p_patch 
+      AND m.region = p_region
+      AND (trait_data->>'num_units')::int >= 2
+      AND (trait_data->>'style')::int > 0
+    GROUP BY trait_data->>'name'
+    HAVING COUNT(*) >= 50
+    
+    ON CONFLICT (trait_name, patch_version, region, tier)
+    DO UPDATE SET
+        play_rate = EXCLUDED.play_rate,
+        win_rate = EXCLUDED.win_rate,
+        avg_placement = EXCLUDED.avg_placement,
+        avg_count = EXCLUDED.avg_count,
+        total_games = EXCLUDED.total_games,
+        total_wins = EXCLUDED.total_wins,
+        total_top4 = EXCLUDED.total_top4,
+        style_distribution = EXCLUDED.style_distribution,
+        updated_at = NOW();
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION analytics.recalculate_champion_meta(
+    p_patch VARCHAR(10),
+    p_region VARCHAR(10), 
+    p_tier VARCHAR(20)
+)
+RETURNS VOID AS $$
+BEGIN
+    INSERT INTO analytics.champion_meta (
+        champion_name, patch_version, region, tier,
+        play_rate, win_rate, avg_placement, avg_tier, cost,
+        total_games, total_wins, total_top4,
+        item_usage, position_stats
+    )
+    SELECT 
+        unit_data->>'character_id' as champion_name,
+        p_patch,
+        p_region,
+        p_tier,
+        
+        ROUND((COUNT(*)::DECIMAL / (
+            SELECT COUNT(DISTINCT p2.match_id) 
+            FROM matches.participants p2 
+            JOIN matches.matches m2 ON p2.match_id = m2.match_id
+            WHERE m2.patch_version = p_patch AND m2.region = p_region
+        )) * 100, 2) as play_rate,
+        
+        ROUND((COUNT(*) FILTER (WHERE p.placement = 1)::DECIMAL / COUNT(*)) * 100, 2) as win_rate,
+        ROUND(AVG(p.placement), 2) as avg_placement,
+        ROUND(AVG((unit_data->>'tier')::int), 1) as avg_tier,
+        (unit_data->>'rarity')::int as cost,
+        
+        COUNT(*)::int as total_games,
+        COUNT(*) FILTER (WHERE p.placement = 1) as total_wins,
+        COUNT(*) FILTER (WHERE p.placement <= 4) as total_top4,
+        
+        json_agg(unit_data->'items') as item_usage,
+        json_build_object(
+            'avg_items', AVG(jsonb_array_length(unit_data->'items')),
+            'carry_rate', (COUNT(*) FILTER (WHERE jsonb_array_length(unit_data->'items') >= 2)::DECIMAL / COUNT(*)) * 100
+        ) as position_stats
+        
+    FROM matches.participants p
+    JOIN matches.matches m ON p.match_id = m.match_id
+    CROSS JOIN LATERAL jsonb_array_elements(p.units) as unit_data
+    WHERE m.patch_version = p_patch 
+      AND m.region = p_region
+    GROUP BY unit_data->>'character_id', (unit_data->>'rarity')::int
+    HAVING COUNT(*) >= 100
+    
+    ON CONFLICT (champion_name, patch_version, region, tier)
+    DO UPDATE SET
+        play_rate = EXCLUDED.play_rate,
+        win_rate = EXCLUDED.win_rate,
+        avg_placement = EXCLUDED.avg_placement,
+        avg_tier = EXCLUDED.avg_tier,
+        total_games = EXCLUDED.total_games,
+        total_wins = EXCLUDED.total_wins,
+        total_top4 = EXCLUDED.total_top4,
+        item_usage = EXCLUDED.item_usage,
+        position_stats = EXCLUDED.position_stats,
+        updated_at = NOW();
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,505 @@
+-- =====================================================
+-- TFT Arena - Meta Across Tiers, Patches and Queues
+-- File: migrations/010_meta_all_buckets.up.sql
+-- =====================================================
+
+-- =====================================================
+-- Tier Buckets
+-- =====================================================
+-- A meta bucket is a single tier or one of the aggregate brackets.
+CREATE OR REPLACE FUNCTION analytics.bucket_tiers(p_bucket VARCHAR(20))
+RETURNS TEXT[] AS $$
+    SELECT CASE p_bucket
+        WHEN 'MASTER_PLUS' THEN ARRAY['MASTER', 'GRANDMASTER', 'CHALLENGER']
+        WHEN 'DIAMOND_PLUS' THEN ARRAY['DIAMOND', 'MASTER', 'GRANDMASTER', 'CHALLENGER']
+        ELSE ARRAY[p_bucket::TEXT]
+    END;
+$$ LANGUAGE sql IMMUTABLE;
+
+CREATE OR REPLACE FUNCTION analytics.buckets_for_tier(p_tier VARCHAR(20))
+RETURNS TEXT[] AS $$
+    SELECT ARRAY[p_tier::TEXT] || CASE
+        WHEN players.tier_weight(p_tier) >= players.tier_weight('MASTER') THEN ARRAY['DIAMOND_PLUS', 'MASTER_PLUS']
+        WHEN players.tier_weight(p_tier) >= players.tier_weight('DIAMOND') THEN ARRAY['DIAMOND_PLUS']
+        ELSE ARRAY[]::TEXT[]
+    END;
+$$ LANGUAGE sql IMMUTABLE;
+
+-- Riot reports unit rarity with gaps: 0, 1, 2, 4 and 6 are the one to five
+-- cost shop tiers.
+CREATE OR REPLACE FUNCTION analytics.unit_cost(p_rarity INTEGER)
+RETURNS INTEGER AS $$
+    SELECT CASE p_rarity
+        WHEN 0 THEN 1
+        WHEN 1 THEN 2
+        WHEN 2 THEN 3
+        WHEN 4 THEN 4
+        WHEN 6 THEN 5
+    END;
+$$ LANGUAGE sql IMMUTABLE;
+
+-- =====================================================
+-- Queue-Aware Meta Tables
+-- =====================================================
+-- Existing rows were computed over every player and labelled CHALLENGER;
+-- they are rebuilt from the dirty buckets seeded below.
+TRUNCATE analytics.trait_meta, analytics.champion_meta;
+
+ALTER TABLE analytics.trait_meta
+    ADD COLUMN queue_id INTEGER NOT NULL DEFAULT 1100,
+    ADD CONSTRAINT check_queue_id_valid CHECK (queue_id IN (1090, 1100, 1130, 1160)),
+    ADD CONSTRAINT check_tier_bucket_valid CHECK (tier IN (
+        'IRON', 'BRONZE', 'SILVER', 'GOLD', 'PLATINUM', 'EMERALD',
+        'DIAMOND', 'MASTER', 'GRANDMASTER', 'CHALLENGER',
+        'DIAMOND_PLUS', 'MASTER_PLUS'
+    )),
+    DROP CONSTRAINT trait_meta_trait_name_patch_version_region_tier_key,
+    ADD CONSTRAINT trait_meta_bucket_key UNIQUE (trait_name, patch_version, region, tier, queue_id);
+
+ALTER TABLE analytics.champion_meta
+    ADD COLUMN queue_id INTEGER NOT NULL DEFAULT 1100,
+    ADD CONSTRAINT check_queue_id_valid CHECK (queue_id IN (1090, 1100, 1130, 1160)),
+    ADD CONSTRAINT check_tier_bucket_valid CHECK (tier IN (
+        'IRON', 'BRONZE', 'SILVER', 'GOLD', 'PLATINUM', 'EMERALD',
+        'DIAMOND', 'MASTER', 'GRANDMASTER', 'CHALLENGER',
+        'DIAMOND_PLUS', 'MASTER_PLUS'
+    )),
+    DROP CONSTRAINT champion_meta_champion_name_patch_version_region_tier_key,
+    ADD CONSTRAINT champion_meta_bucket_key UNIQUE (champion_name, patch_version, region, tier, queue_id);
+
+DROP INDEX IF EXISTS analytics.idx_trait_meta_patch_region_tier;
+DROP INDEX IF EXISTS analytics.idx_champion_meta_patch_region_tier;
+CREATE INDEX idx_trait_meta_bucket ON analytics.trait_meta (patch_version, region, tier, queue_id);
+CREATE INDEX idx_champion_meta_bucket ON analytics.champion_meta (patch_version, region, tier, queue_id);
+
+CREATE INDEX idx_matches_patch_region_queue ON matches.matches (patch_version, region, queue_id);
+
+-- =====================================================
+-- Dirty Buckets
+-- =====================================================
+CREATE TABLE analytics.meta_dirty_buckets (
+    patch_version VARCHAR(10) NOT NULL,
+    region VARCHAR(10) NOT NULL,
+    queue_id INTEGER NOT NULL,
+    tier VARCHAR(20) NOT NULL,
+    marked_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    PRIMARY KEY (patch_version, region, queue_id, tier)
+);
+
+-- Participants are inserted unranked and updated once their rank is
+-- attributed, so both inserts and updates mark their buckets.
+CREATE OR REPLACE FUNCTION analytics.mark_participant_buckets_dirty()
+RETURNS TRIGGER AS $$
+BEGIN
+    INSERT INTO analytics.meta_dirty_buckets (patch_version, region, queue_id, tier)
+    SELECT DISTINCT m.patch_version, m.region, m.queue_id, b.bucket
+    FROM changed_participants p
+    JOIN matches.matches m ON m.match_id = p.match_id
+    CROSS JOIN LATERAL unnest(analytics.buckets_for_tier(p.tier)) AS b(bucket)
+    WHERE p.tier IS NOT NULL
+    ON CONFLICT (patch_version, region, queue_id, tier)
+    DO UPDATE SET marked_at = NOW();
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trigger_mark_inserted_participants_dirty
+    AFTER INSERT ON matches.participants
+    REFERENCING NEW TABLE AS changed_participants
+    FOR EACH STATEMENT
+    EXECUTE FUNCTION analytics.mark_participant_buckets_dirty();
+
+CREATE TRIGGER trigger_mark_updated_participants_dirty
+    AFTER UPDATE ON matches.participants
+    REFERENCING NEW TABLE AS changed_participants
+    FOR EACH STATEMENT
+    EXECUTE FUNCTION analytics.mark_participant_buckets_dirty();
+
+-- Patches with matches seen within the window.
+CREATE OR REPLACE FUNCTION analytics.active_patches(p_window INTERVAL DEFAULT '30 days')
+RETURNS SETOF VARCHAR(10) AS $$
+    SELECT patch_version
+    FROM matches.patches
+    WHERE last_seen_at >= NOW() - p_window;
+$$ LANGUAGE sql STABLE;
+
+CREATE OR REPLACE FUNCTION analytics.mark_all_buckets_dirty(p_patch VARCHAR(10) DEFAULT NULL)
+RETURNS INTEGER AS $$
+DECLARE
+    marked INTEGER;
+BEGIN
+    INSERT INTO analytics.meta_dirty_buckets (patch_version, region, queue_id, tier)
+    SELECT DISTINCT m.patch_version, m.region, m.queue_id, b.bucket
+    FROM matches.participants p
+    JOIN matches.matches m ON m.match_id = p.match_id
+    CROSS JOIN LATERAL unnest(analytics.buckets_for_tier(p.tier)) AS b(bucket)
+    WHERE p.tier IS NOT NULL
+      AND (
+          (p_patch IS NULL AND m.patch_version IN (SELECT analytics.active_patches()))
+          OR m.patch_version = p_patch
+      )
+    ON CONFLICT (patch_version, region, queue_id, tier)
+    DO UPDATE SET marked_at = NOW();
+
+    GET DIAGNOSTICS marked = ROW_COUNT;
+    RETURN marked;
+END;
+$$ LANGUAGE plpgsql;
+
+-- =====================================================
+-- Trait Meta
+-- =====================================================
+DROP FUNCTION IF EXISTS analytics.recalculate_trait_meta(VARCHAR, VARCHAR, VARCHAR);
+
+CREATE OR REPLACE FUNCTION analytics.recalculate_trait_meta(
+    p_patch VARCHAR(10),
+    p_region VARCHAR(10),
+    p_tier VARCHAR(20),
+    p_queue_id INTEGER DEFAULT 1100
+)
+RETURNS INTEGER AS $$
+DECLARE
+    member_tiers TEXT[] := analytics.bucket_tiers(p_tier);
+    total_boards INTEGER;
+    upserted INTEGER;
+BEGIN
+    SELECT COUNT(*) INTO total_boards
+    FROM matches.participants p
+    JOIN matches.matches m ON m.match_id = p.match_id
+    WHERE m.patch_version = p_patch
+      AND m.region = p_region
+      AND m.queue_id = p_queue_id
+      AND p.tier = ANY(member_tiers);
+
+    WITH active_traits AS (
+        SELECT
+            trait_data->>'name' as trait_name,
+            (trait_data->>'style')::int as style,
+            (trait_data->>'num_units')::int as num_units,
+            p.placement
+        FROM matches.participants p
+        JOIN matches.matches m ON m.match_id = p.match_id
+        CROSS JOIN LATERAL jsonb_array_elements(p.traits) as trait_data
+        WHERE m.patch_version = p_patch
+          AND m.region = p_region
+          AND m.queue_id = p_queue_id
+          AND p.tier = ANY(member_tiers)
+          AND (trait_data->>'num_units')::int >= 2
+          AND (trait_data->>'style')::int > 0
+    ),
+    styles AS (
+        SELECT trait_name, jsonb_object_agg(style, games) as style_distribution
+        FROM (
+            SELECT trait_name, style, COUNT(*) as games
+            FROM active_traits
+            GROUP BY trait_name, style
+        ) s
+        GROUP BY trait_name
+    ),
+    stats AS (
+        SELECT
+            trait_name,
+            COUNT(*) as total_games,
+            COUNT(*) FILTER (WHERE placement = 1) as total_wins,
+            COUNT(*) FILTER (WHERE placement <= 4) as total_top4,
+            ROUND(AVG(placement), 2) as avg_placement,
+            ROUND(AVG(num_units), 2) as avg_count
+        FROM active_traits
+        GROUP BY trait_name
+        HAVING COUNT(*) >= 50
+    ),
+    saved AS (
+        INSERT INTO analytics.trait_meta (
+            trait_name, patch_version, region, tier, queue_id,
+            play_rate, win_rate, avg_placement, avg_count,
+            total_games, total_wins, total_top4,
+            style_distribution
+        )
+        SELECT
+            s.trait_name, p_patch, p_region, p_tier, p_queue_id,
+            ROUND((s.total_games::DECIMAL / total_boards) * 100, 2),
+            ROUND((s.total_wins::DECIMAL / s.total_games) * 100, 2),
+            s.avg_placement,
+            s.avg_count,
+            s.total_games, s.total_wins, s.total_top4,
+            st.style_distribution
+        FROM stats s
+        JOIN styles st ON st.trait_name = s.trait_name
+        ON CONFLICT (trait_name, patch_version, region, tier, queue_id)
+        DO UPDATE SET
+            play_rate = EXCLUDED.play_rate,
+            win_rate = EXCLUDED.win_rate,
+            avg_placement = EXCLUDED.avg_placement,
+            avg_count = EXCLUDED.avg_count,
+            total_games = EXCLUDED.total_games,
+            total_wins = EXCLUDED.total_wins,
+            total_top4 = EXCLUDED.total_top4,
+            style_distribution = EXCLUDED.style_distribution,
+            updated_at = NOW()
+        RETURNING trait_name
+    ),
+    -- Traits that dropped under the sample threshold leave the bucket.
+    stale AS (
+        DELETE FROM analytics.trait_meta tm
+        WHERE tm.patch_version = p_patch
+          AND tm.region = p_region
+          AND tm.tier = p_tier
+          AND tm.queue_id = p_queue_id
+          AND tm.trait_name NOT IN (SELECT trait_name FROM saved)
+    )
+    SELECT COUNT(*) INTO upserted FROM saved;
+
+    RETURN upserted;
+END;
+$$ LANGUAGE plpgsql;
+
+-- =====================================================
+-- Champion Meta
+-- =====================================================
+DROP FUNCTION IF EXISTS analytics.recalculate_champion_meta(VARCHAR, VARCHAR, VARCHAR);
+
+CREATE OR REPLACE FUNCTION analytics.recalculate_champion_meta(
+    p_patch VARCHAR(10),
+    p_region VARCHAR(10),
+    p_tier VARCHAR(20),
+    p_queue_id INTEGER DEFAULT 1100
+)
+RETURNS INTEGER AS $$
+DECLARE
+    member_tiers TEXT[] := analytics.bucket_tiers(p_tier);
+    total_boards INTEGER;
+    upserted INTEGER;
+BEGIN
+    SELECT COUNT(*) INTO total_boards
+    FROM matches.participants p
+    JOIN matches.matches m ON m.match_id = p.match_id
+    WHERE m.patch_version = p_patch
+      AND m.region = p_region
+      AND m.queue_id = p_queue_id
+      AND p.tier = ANY(member_tiers);
+
+    -- One row per board and champion, so duplicate copies of a unit on the
+    -- same board count once.
+    WITH board_units AS (
+        SELECT
+            p.id as participant_id,
+            unit_data->>'character_id' as champion_name,
+            MAX(analytics.unit_cost((unit_data->>'rarity')::int)) as cost,
+            MAX((unit_data->>'tier')::int) as star_level,
+            MAX(jsonb_array_length(COALESCE(unit_data->'itemNames', unit_data->'items', '[]'::jsonb))) as item_count,
+            MIN(p.placement) as placement
+        FROM matches.participants p
+        JOIN matches.matches m ON m.match_id = p.match_id
+        CROSS JOIN LATERAL jsonb_array_elements(p.units) as unit_data
+        WHERE m.patch_version = p_patch
+          AND m.region = p_region
+          AND m.queue_id = p_queue_id
+          AND p.tier = ANY(member_tiers)
+        GROUP BY p.id, unit_data->>'character_id'
+    ),
+    items AS (
+        SELECT champion_name, jsonb_object_agg(item, uses) as item_usage
+        FROM (
+            SELECT
+                unit_data->>'character_id' as champion_name,
+                item,
+                COUNT(*) as uses
+            FROM matches.participants p
+            JOIN matches.matches m ON m.match_id = p.match_id
+            CROSS JOIN LATERAL jsonb_array_elements(p.units) as unit_data
+            CROSS JOIN LATERAL jsonb_array_elements_text(
+                COALESCE(unit_data->'itemNames', unit_data->'items', '[]'::jsonb)
+            ) as item
+            WHERE m.patch_version = p_patch
+              AND m.region = p_region
+              AND m.queue_id = p_queue_id
+              AND p.tier = ANY(member_tiers)
+            GROUP BY unit_data->>'character_id', item
+        ) i
+        GROUP BY champion_name
+    ),
+    stats AS (
+        SELECT
+            champion_name,
+            MAX(cost) as cost,
+            COUNT(*) as total_games,
+            COUNT(*) FILTER (WHERE placement = 1) as total_wins,
+            COUNT(*) FILTER (WHERE placement <= 4) as total_top4,
+            ROUND(AVG(placement), 2) as avg_placement,
+            LEAST(ROUND(AVG(star_level), 1), 3) as avg_tier,
+            ROUND(AVG(item_count), 2) as avg_items,
+            ROUND((COUNT(*) FILTER (WHERE item_count >= 2)::DECIMAL / COUNT(*)) * 100, 2) as carry_rate
+        FROM board_units
+        WHERE cost IS NOT NULL
+        GROUP BY champion_name
+        HAVING COUNT(*) >= 100
+    ),
+    saved AS (
+        INSERT INTO analytics.champion_meta (
+            champion_name, patch_version, region, tier, queue_id,
+            play_rate, win_rate, avg_placement, avg_tier, cost,
+            total_games, total_wins, total_top4,
+            item_usage, position_stats
+        )
+        SELECT
+            s.champion_name, p_patch, p_region, p_tier, p_queue_id,
+            ROUND((s.total_games::DECIMAL / total_boards) * 100, 2),
+            ROUND((s.total_wins::DECIMAL / s.total_games) * 100, 2),
+            s.avg_placement,
+            s.avg_tier,
+            s.cost,
+            s.total_games, s.total_wins, s.total_top4,
+            COALESCE(i.item_usage, '{}'::jsonb),
+            jsonb_build_object('avg_items', s.avg_items, 'carry_rate', s.carry_rate)
+        FROM stats s
+        LEFT JOIN items i ON i.champion_name = s.champion_name
+        ON CONFLICT (champion_name, patch_version, region, tier, queue_id)
+        DO UPDATE SET
+            play_rate = EXCLUDED.play_rate,
+            win_rate = EXCLUDED.win_rate,
+            avg_placement = EXCLUDED.avg_placement,
+            avg_tier = EXCLUDED.avg_tier,
+            cost = EXCLUDED.cost,
+            total_games = EXCLUDED.total_games,
+            total_wins = EXCLUDED.total_wins,
+            total_top4 = EXCLUDED.total_top4,
+            item_usage = EXCLUDED.item_usage,
+            position_stats = EXCLUDED.position_stats,
+            updated_at = NOW()
+        RETURNING champion_name
+    ),
+    stale AS (
+        DELETE FROM analytics.champion_meta cm
+        WHERE cm.patch_version = p_patch
+          AND cm.region = p_region
+          AND cm.tier = p_tier
+          AND cm.queue_id = p_queue_id
+          AND cm.champion_name NOT IN (SELECT champion_name FROM saved)
+    )
+    SELECT COUNT(*) INTO upserted FROM saved;
+
+    RETURN upserted;
+END;
+$$ LANGUAGE plpgsql;
+
+-- =====================================================
+-- Current Meta Overview
+-- =====================================================
+-- Traits and champions are aggregated separately; joining the two tables
+-- row by row multiplied every entry.
+DROP MATERIALIZED VIEW IF EXISTS analytics.current_meta;
+
+CREATE MATERIALIZED VIEW analytics.current_meta AS
+WITH traits AS (
+    SELECT
+        patch_version, region, tier, queue_id,
+        json_agg(
+            json_build_object(
+                'name', trait_name,
+                'play_rate', play_rate,
+                'win_rate', win_rate,
+                'avg_placement', avg_placement
+            ) ORDER BY play_rate DESC
+        ) FILTER (WHERE play_rate >= 10) as top_traits,
+        AVG(play_rate) as trait_diversity,
+        MAX(updated_at) as last_updated
+    FROM analytics.trait_meta
+    WHERE total_games >= 100
+    GROUP BY patch_version, region, tier, queue_id
+),
+champions AS (
+    SELECT
+        patch_version, region, tier, queue_id,
+        json_agg(
+            json_build_object(
+                'name', champion_name,
+                'cost', cost,
+                'play_rate', play_rate,
+                'win_rate', win_rate,
+                'avg_tier', avg_tier
+            ) ORDER BY play_rate DESC
+        ) FILTER (WHERE play_rate >= 5) as top_champions,
+        AVG(play_rate) as champion_diversity,
+        MAX(updated_at) as last_updated
+    FROM analytics.champion_meta
+    WHERE total_games >= 100
+    GROUP BY patch_version, region, tier, queue_id
+)
+SELECT
+    COALESCE(t.patch_version, c.patch_version) as patch_version,
+    COALESCE(t.region, c.region) as region,
+    COALESCE(t.tier, c.tier) as tier,
+    COALESCE(t.queue_id, c.queue_id) as queue_id,
+    t.top_traits,
+    c.top_champions,
+    t.trait_diversity,
+    c.champion_diversity,
+    GREATEST(t.last_updated, c.last_updated) as last_updated
+FROM traits t
+FULL OUTER JOIN champions c
+    ON t.patch_version = c.patch_version
+    AND t.region = c.region
+    AND t.tier = c.tier
+    AND t.queue_id = c.queue_id;
+
+CREATE UNIQUE INDEX idx_current_meta_bucket ON analytics.current_meta (
+    patch_version, region, tier, queue_id
+);
+
+-- =====================================================
+-- Incremental Update Entry Point
+-- =====================================================
+DROP FUNCTION IF EXISTS analytics.update_meta_from_matches();
+
+-- Recomputes every dirty bucket. With p_full, all buckets of p_patch (or of
+-- every active patch) are marked dirty first.
+CREATE OR REPLACE FUNCTION analytics.update_meta_from_matches(
+    p_patch VARCHAR(10) DEFAULT NULL,
+    p_full BOOLEAN DEFAULT FALSE
+)
+RETURNS INTEGER AS $$
+DECLARE
+    bucket RECORD;
+    processed INTEGER := 0;
+BEGIN
+    IF p_full THEN
+        PERFORM analytics.mark_all_buckets_dirty(p_patch);
+    END IF;
+
+    FOR bucket IN
+        DELETE FROM analytics.meta_dirty_buckets
+        WHERE p_patch IS NULL OR patch_version = p_patch
+        RETURNING patch_version, region, queue_id, tier
+    LOOP
+        PERFORM analytics.recalculate_trait_meta(bucket.patch_version, bucket.region, bucket.tier, bucket.queue_id);
+        PERFORM analytics.recalculate_champion_meta(bucket.patch_version, bucket.region, bucket.tier, bucket.queue_id);
+        processed := processed + 1;
+    END LOOP;
+
+    IF processed > 0 THEN
+        REFRESH MATERIALIZED VIEW CONCURRENTLY analytics.current_meta;
+    END IF;
+
+    RETURN processed;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Rebuild everything on the next run.
+SELECT analytics.mark_all_buckets_dirty();
+
+-- =====================================================
+-- Grant Permissions
+-- =====================================================
+GRANT ALL PRIVILEGES ON ALL TABLES IN SCHEMA analytics TO tft_user;
+GRANT ALL PRIVILEGES ON ALL FUNCTIONS IN SCHEMA analytics TO tft_user;
+
+-- =====================================================
+-- Comments
+-- =====================================================
+COMMENT ON TABLE analytics.meta_dirty_buckets IS 'Meta buckets whose source participants changed since the last recomputation';
+COMMENT ON FUNCTION analytics.bucket_tiers(VARCHAR) IS 'Participant tiers that belong to a meta bucket';
+COMMENT ON FUNCTION analytics.buckets_for_tier(VARCHAR) IS 'Meta buckets a participant of the given tier counts towards';
+COMMENT ON FUNCTION analytics.update_meta_from_matches(VARCHAR, BOOLEAN) IS 'Recomputes trait and champion meta for dirty buckets and returns how many were processed';
+COMMENT ON MATERIALIZED VIEW analytics.current_meta IS 'Current meta overview with top performing traits and champions per bucket';
@@ -0,0 +1,51 @@
+-- =====================================================
+-- File: migrations/011_batched_retention.down.sql
+-- =====================================================
+DROP FUNCTION IF EXISTS compositions.cleanup_old_compositions(INTEGER, INTEGER, INTEGER, BOOLEAN);
+DROP FUNCTION IF EXISTS compositions.cleanup_old_comp_games(INTEGER, INTEGER, BOOLEAN);
+DROP FUNCTION IF EXISTS matches.cleanup_old_timeline_events(INTEGER, INTEGER, BOOLEAN);
+DROP FUNCTION IF EXISTS matches.cleanup_old_matches(INTEGER, INTEGER, BOOLEAN);
+
+CREATE OR REPLACE FUNCTION matches.cleanup_old_matches(
+    days_old INTEGER DEFAULT 180
+)
+RETURNS INTEGER AS $$
+DECLARE
+    deleted_count INTEGER;
+BEGIN
+    DELETE FROM matches.matches 
+    WHERE created_at < NOW() - INTERVAL '1 day' * days_old;
+    
+    GET DIAGNOSTICS deleted_count = ROW_COUNT;
+    
+    PERFORM matches.refresh_match_stats();
+    
+    RETURN deleted_count;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION compositions.cleanup_old_compositions(
+    min_games INTEGER DEFAULT 10,
+    days_old INTEGER DEFAULT 30
+)
+RETURNS INTEGER AS $$
+DECLARE
+    deleted_count INTEGER;
+BEGIN
+    -- Delete compositions with insufficient games and old data
+    DELETE FROM compositions.team_comps 
+    WHERE total_games < min_games 
+      AND last_updated < NOW() - INTERVAL '1 day' * days_old;
+    
+    GET DIAGNOSTICS deleted_count = ROW_COUNT;
+    
+    -- Clean up orphaned games (shouldn't happen due to FK, but just in case)
+    DELETE FROM compositions.comp_games 
+    WHERE comp_hash NOT IN (SELECT comp_hash FROM compositions.team_comps);
+    
+    -- Refresh materialized view after cleanup
+    PERFORM compositions.refresh_leaderboard();
+    
+    RETURN deleted_count;
+END;
+$$ LANGUAGE plpgsql;
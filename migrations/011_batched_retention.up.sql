@@ -0,0 +1,165 @@
+-- =====================================================
+-- TFT Arena - Batched Data Retention
+-- File: migrations/011_batched_retention.up.sql
+-- =====================================================
+
+-- Each cleanup function deletes at most batch_size rows per call so the
+-- caller can commit between batches and never hold long locks. With
+-- dry_run the candidates are only counted. Materialized views are
+-- refreshed by the caller once all batches are done.
+
+-- =====================================================
+-- Matches
+-- =====================================================
+DROP FUNCTION IF EXISTS matches.cleanup_old_matches(INTEGER);
+
+-- Deleting a match cascades to its participants and timeline events.
+CREATE OR REPLACE FUNCTION matches.cleanup_old_matches(
+    days_old INTEGER DEFAULT 180,
+    batch_size INTEGER DEFAULT 500,
+    dry_run BOOLEAN DEFAULT FALSE
+)
+RETURNS INTEGER AS $$
+DECLARE
+    cutoff BIGINT := EXTRACT(EPOCH FROM NOW() - INTERVAL '1 day' * days_old)::BIGINT;
+    affected INTEGER;
+BEGIN
+    IF dry_run THEN
+        SELECT COUNT(*) INTO affected
+        FROM matches.matches
+        WHERE game_datetime < cutoff;
+        RETURN affected;
+    END IF;
+
+    DELETE FROM matches.matches
+    WHERE id IN (
+        SELECT id FROM matches.matches
+        WHERE game_datetime < cutoff
+        ORDER BY game_datetime
+        LIMIT batch_size
+        FOR UPDATE SKIP LOCKED
+    );
+
+    GET DIAGNOSTICS affected = ROW_COUNT;
+    RETURN affected;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION matches.cleanup_old_timeline_events(
+    days_old INTEGER DEFAULT 30,
+    batch_size INTEGER DEFAULT 5000,
+    dry_run BOOLEAN DEFAULT FALSE
+)
+RETURNS INTEGER AS $$
+DECLARE
+    cutoff BIGINT := EXTRACT(EPOCH FROM NOW() - INTERVAL '1 day' * days_old)::BIGINT;
+    affected INTEGER;
+BEGIN
+    IF dry_run THEN
+        SELECT COUNT(*) INTO affected
+        FROM matches.timeline_events te
+        JOIN matches.matches m ON m.match_id = te.match_id
+        WHERE m.game_datetime < cutoff;
+        RETURN affected;
+    END IF;
+
+    DELETE FROM matches.timeline_events
+    WHERE id IN (
+        SELECT te.id
+        FROM matches.timeline_events te
+        JOIN matches.matches m ON m.match_id = te.match_id
+        WHERE m.game_datetime < cutoff
+        LIMIT batch_size
+        FOR UPDATE OF te SKIP LOCKED
+    );
+
+    GET DIAGNOSTICS affected = ROW_COUNT;
+    RETURN affected;
+END;
+$$ LANGUAGE plpgsql;
+
+-- =====================================================
+-- Compositions
+-- =====================================================
+-- Removing comp_games fires the statement-level delta trigger, so the
+-- counters of the affected team_comps shrink to the retained games.
+CREATE OR REPLACE FUNCTION compositions.cleanup_old_comp_games(
+    days_old INTEGER DEFAULT 90,
+    batch_size INTEGER DEFAULT 2000,
+    dry_run BOOLEAN DEFAULT FALSE
+)
+RETURNS INTEGER AS $$
+DECLARE
+    cutoff BIGINT := EXTRACT(EPOCH FROM NOW() - INTERVAL '1 day' * days_old)::BIGINT;
+    affected INTEGER;
+BEGIN
+    IF dry_run THEN
+        SELECT COUNT(*) INTO affected
+        FROM compositions.comp_games
+        WHERE game_datetime < cutoff;
+        RETURN affected;
+    END IF;
+
+    DELETE FROM compositions.comp_games
+    WHERE id IN (
+        SELECT id FROM compositions.comp_games
+        WHERE game_datetime < cutoff
+        ORDER BY game_datetime
+        LIMIT batch_size
+        FOR UPDATE SKIP LOCKED
+    );
+
+    GET DIAGNOSTICS affected = ROW_COUNT;
+    RETURN affected;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP FUNCTION IF EXISTS compositions.cleanup_old_compositions(INTEGER, INTEGER);
+
+-- Low-sample compositions that stopped receiving games. Their remaining
+-- comp_games go with them through the foreign key cascade.
+CREATE OR REPLACE FUNCTION compositions.cleanup_old_compositions(
+    min_games INTEGER DEFAULT 10,
+    days_old INTEGER DEFAULT 30,
+    batch_size INTEGER DEFAULT 500,
+    dry_run BOOLEAN DEFAULT FALSE
+)
+RETURNS INTEGER AS $$
+DECLARE
+    affected INTEGER;
+BEGIN
+    IF dry_run THEN
+        SELECT COUNT(*) INTO affected
+        FROM compositions.team_comps
+        WHERE total_games < min_games
+          AND last_updated < NOW() - INTERVAL '1 day' * days_old;
+        RETURN affected;
+    END IF;
+
+    DELETE FROM compositions.team_comps
+    WHERE id IN (
+        SELECT id FROM compositions.team_comps
+        WHERE total_games < min_games
+          AND last_updated < NOW() - INTERVAL '1 day' * days_old
+        LIMIT batch_size
+        FOR UPDATE SKIP LOCKED
+    );
+
+    GET DIAGNOSTICS affected = ROW_COUNT;
+    RETURN affected;
+END;
+$$ LANGUAGE plpgsql;
+
+-- =====================================================
+-- Grant Permissions
+-- =====================================================
+GRANT ALL PRIVILEGES ON ALL FUNCTIONS IN SCHEMA matches TO tft_user;
+GRANT ALL PRIVILEGES ON ALL FUNCTIONS IN SCHEMA compositions TO tft_user;
+
+-- =====================================================
+-- Comments
+-- =====================================================
+COMMENT ON FUNCTION matches.cleanup_old_matches(INTEGER, INTEGER, BOOLEAN) IS 'Deletes one batch of matches played more than days_old days ago, or counts them on dry run';
+COMMENT ON FUNCTION matches.cleanup_old_timeline_events(INTEGER, INTEGER, BOOLEAN) IS 'Deletes one batch of timeline events of matches older than days_old days, or counts them on dry run';
+COMMENT ON FUNCTION compositions.cleanup_old_comp_games(INTEGER, INTEGER, BOOLEAN) IS 'Deletes one batch of composition games older than days_old days, or counts them on dry run';
+COMMENT ON FUNCTION compositions.cleanup_old_compositions(INTEGER, INTEGER, INTEGER, BOOLEAN) IS 'Deletes one batch of stale low-sample compositions, or counts them on dry run';
@@ -0,0 +1,11 @@
+-- =====================================================
+-- File: migrations/012_warm_cache_job.down.sql
+-- =====================================================
+DELETE FROM jobs.executions WHERE job_type = 'warm_cache';
+
+ALTER TABLE jobs.executions DROP CONSTRAINT IF EXISTS check_job_type_valid;
+
+ALTER TABLE jobs.executions ADD CONSTRAINT check_job_type_valid CHECK (job_type IN (
+    'collect_challenger', 'collect_player', 'analyze_compositions',
+    'refresh_leaderboard', 'cleanup_cache', 'update_meta_data'
+));
@@ -0,0 +1,15 @@
+-- =====================================================
+-- TFT Arena - Cache Warm-up Job Type
+-- File: migrations/012_warm_cache_job.up.sql
+-- =====================================================
+
+-- =====================================================
+-- Job Executions
+-- =====================================================
+ALTER TABLE jobs.executions DROP CONSTRAINT IF EXISTS check_job_type_valid;
+
+ALTER TABLE jobs.executions ADD CONSTRAINT check_job_type_valid CHECK (job_type IN (
+    'collect_challenger', 'collect_player', 'analyze_compositions',
+    'refresh_leaderboard', 'cleanup_cache', 'update_meta_data',
+    'warm_cache'
+));
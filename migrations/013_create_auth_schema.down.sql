@@ -0,0 +1,4 @@
+-- =====================================================
+-- File: migrations/013_create_auth_schema.down.sql
+-- =====================================================
+DROP SCHEMA IF EXISTS auth CASCADE;
@@ -0,0 +1,79 @@
+-- =====================================================
+-- TFT Arena - User Accounts
+-- File: migrations/013_create_auth_schema.up.sql
+-- =====================================================
+
+CREATE SCHEMA IF NOT EXISTS auth;
+
+-- =====================================================
+-- Users Table
+-- =====================================================
+-- Emails are stored lowercased. Passwords are bcrypt hashes; sessions and
+-- tokens live outside the database.
+CREATE TABLE auth.users (
+    id UUID PRIMARY KEY,
+    email VARCHAR(255) NOT NULL,
+    username VARCHAR(32) NOT NULL,
+    password_hash VARCHAR(72) NOT NULL,
+
+    failed_login_attempts INTEGER NOT NULL DEFAULT 0,
+    locked_until TIMESTAMP WITH TIME ZONE,
+    last_login_at TIMESTAMP WITH TIME ZONE,
+
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    CONSTRAINT check_email_lowercase CHECK (email = LOWER(email)),
+    CONSTRAINT check_failed_attempts_positive CHECK (failed_login_attempts >= 0)
+);
+
+CREATE UNIQUE INDEX idx_users_email ON auth.users (email);
+CREATE UNIQUE INDEX idx_users_username ON auth.users (LOWER(username));
+
+-- =====================================================
+-- Login Attempts
+-- =====================================================
+-- Counts a failed login and locks the account once max_attempts is
+-- reached. The count starts over after an expired lockout. Returns the
+-- lockout end, or NULL while the account is not locked.
+CREATE OR REPLACE FUNCTION auth.record_failed_login(
+    p_user_id UUID,
+    max_attempts INTEGER,
+    lockout INTERVAL
+)
+RETURNS TIMESTAMP WITH TIME ZONE AS $$
+DECLARE
+    result TIMESTAMP WITH TIME ZONE;
+BEGIN
+    UPDATE auth.users SET
+        failed_login_attempts = CASE
+            WHEN locked_until IS NOT NULL AND locked_until <= NOW() THEN 1
+            ELSE failed_login_attempts + 1
+        END,
+        updated_at = NOW()
+    WHERE id = p_user_id;
+
+    UPDATE auth.users SET
+        locked_until = NOW() + lockout
+    WHERE id = p_user_id
+      AND failed_login_attempts >= max_attempts
+      AND (locked_until IS NULL OR locked_until <= NOW())
+    RETURNING locked_until INTO result;
+
+    RETURN result;
+END;
+$$ LANGUAGE plpgsql;
+
+-- =====================================================
+-- Grant Permissions
+-- =====================================================
+GRANT USAGE ON SCHEMA auth TO tft_user;
+GRANT ALL PRIVILEGES ON ALL TABLES IN SCHEMA auth TO tft_user;
+GRANT ALL PRIVILEGES ON ALL FUNCTIONS IN SCHEMA auth TO tft_user;
+
+-- =====================================================
+-- Comments
+-- =====================================================
+COMMENT ON SCHEMA auth IS 'Schema for user accounts and authentication';
+COMMENT ON TABLE auth.users IS 'Registered users with bcrypt password hashes and lockout state';
+COMMENT ON FUNCTION auth.record_failed_login(UUID, INTEGER, INTERVAL) IS 'Counts a failed login and returns the lockout end once max_attempts is reached';
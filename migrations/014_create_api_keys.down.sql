@@ -0,0 +1,5 @@
+-- =====================================================
+-- File: migrations/014_create_api_keys.down.sql
+-- =====================================================
+DROP TABLE IF EXISTS auth.api_key_usage;
+DROP TABLE IF EXISTS auth.api_keys;
@@ -0,0 +1,55 @@
+-- =====================================================
+-- TFT Arena - API Keys
+-- File: migrations/014_create_api_keys.up.sql
+-- =====================================================
+
+-- =====================================================
+-- API Keys Table
+-- =====================================================
+-- Only the SHA-256 of a key is stored; the key itself is shown once on
+-- creation. key_prefix identifies it in listings.
+CREATE TABLE auth.api_keys (
+    id UUID PRIMARY KEY,
+    user_id UUID REFERENCES auth.users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    key_prefix VARCHAR(16) NOT NULL,
+    key_hash CHAR(64) NOT NULL,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    requests_per_minute INTEGER NOT NULL DEFAULT 60,
+
+    expires_at TIMESTAMP WITH TIME ZONE,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    CONSTRAINT check_requests_per_minute_positive CHECK (requests_per_minute > 0)
+);
+
+CREATE UNIQUE INDEX idx_api_keys_hash ON auth.api_keys (key_hash);
+CREATE INDEX idx_api_keys_user ON auth.api_keys (user_id) WHERE user_id IS NOT NULL;
+
+-- =====================================================
+-- Usage Table
+-- =====================================================
+-- Daily request counts per key, flushed in batches by the API replicas.
+CREATE TABLE auth.api_key_usage (
+    key_id UUID NOT NULL REFERENCES auth.api_keys(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    requests BIGINT NOT NULL DEFAULT 0,
+    rejected BIGINT NOT NULL DEFAULT 0,
+
+    PRIMARY KEY (key_id, day)
+);
+
+-- =====================================================
+-- Grant Permissions
+-- =====================================================
+GRANT ALL PRIVILEGES ON ALL TABLES IN SCHEMA auth TO tft_user;
+
+-- =====================================================
+-- Comments
+-- =====================================================
+COMMENT ON TABLE auth.api_keys IS 'Hashed API keys for programmatic access, with scopes and a per-minute quota';
+COMMENT ON TABLE auth.api_key_usage IS 'Accepted and rate-limited requests per API key and day';
@@ -0,0 +1,5 @@
+-- =====================================================
+-- File: migrations/015_add_roles.down.sql
+-- =====================================================
+ALTER TABLE auth.api_keys DROP COLUMN IF EXISTS role;
+ALTER TABLE auth.users DROP COLUMN IF EXISTS role;
@@ -0,0 +1,29 @@
+-- =====================================================
+-- TFT Arena - Roles
+-- File: migrations/015_add_roles.up.sql
+-- =====================================================
+
+-- =====================================================
+-- User Roles
+-- =====================================================
+-- Everyone starts as a viewer. The first admin has to be promoted by hand:
+--   UPDATE auth.users SET role = 'admin' WHERE email = '...';
+ALTER TABLE auth.users
+    ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'viewer',
+    ADD CONSTRAINT check_user_role_valid CHECK (role IN ('viewer', 'analyst', 'admin'));
+
+-- =====================================================
+-- API Key Roles
+-- =====================================================
+ALTER TABLE auth.api_keys
+    ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'viewer',
+    ADD CONSTRAINT check_api_key_role_valid CHECK (role IN ('viewer', 'analyst', 'admin'));
+
+-- Keys created with the admin scope keep their access to the admin routes.
+UPDATE auth.api_keys SET role = 'admin' WHERE 'admin' = ANY(scopes);
+
+-- =====================================================
+-- Comments
+-- =====================================================
+COMMENT ON COLUMN auth.users.role IS 'viewer, analyst or admin; decides access to the admin routes';
+COMMENT ON COLUMN auth.api_keys.role IS 'viewer, analyst or admin; decides access to the admin routes';
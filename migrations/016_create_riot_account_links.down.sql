@@ -0,0 +1,4 @@
+-- =====================================================
+-- File: migrations/016_create_riot_account_links.down.sql
+-- =====================================================
+DROP TABLE IF EXISTS auth.riot_accounts;
@@ -0,0 +1,36 @@
+-- =====================================================
+-- TFT Arena - Riot Account Links
+-- File: migrations/016_create_riot_account_links.up.sql
+-- =====================================================
+
+-- =====================================================
+-- Riot Accounts Table
+-- =====================================================
+-- A user proves they own a Riot account by setting a profile icon we ask
+-- for. The proof holds until expires_at; expired links stay listed so the
+-- user can verify again. An account belongs to one user at a time.
+CREATE TABLE auth.riot_accounts (
+    user_id UUID NOT NULL REFERENCES auth.users(id) ON DELETE CASCADE,
+    puuid VARCHAR(78) NOT NULL REFERENCES players.summoners(puuid) ON DELETE CASCADE,
+
+    verified_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    PRIMARY KEY (user_id, puuid),
+    CONSTRAINT check_link_expires_after_verification CHECK (expires_at > verified_at)
+);
+
+CREATE UNIQUE INDEX idx_riot_accounts_puuid ON auth.riot_accounts (puuid);
+
+-- =====================================================
+-- Grant Permissions
+-- =====================================================
+GRANT ALL PRIVILEGES ON ALL TABLES IN SCHEMA auth TO tft_user;
+
+-- =====================================================
+-- Comments
+-- =====================================================
+COMMENT ON TABLE auth.riot_accounts IS 'Riot accounts whose ownership a user verified through their profile icon';
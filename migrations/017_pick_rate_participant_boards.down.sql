@@ -0,0 +1,35 @@
+-- =====================================================
+-- File: migrations/017_pick_rate_participant_boards.down.sql
+-- =====================================================
+CREATE OR REPLACE FUNCTION compositions.recalculate_pick_rates(
+    p_patch VARCHAR(10) DEFAULT NULL,
+    p_region VARCHAR(10) DEFAULT NULL
+)
+RETURNS INTEGER AS $$
+DECLARE
+    updated_count INTEGER;
+BEGIN
+    UPDATE compositions.team_comps tc
+    SET
+        pick_rate = ROUND((tc.total_games::DECIMAL / NULLIF(b.boards, 0)) * 100, 2),
+        pick_rate_sample_size = b.boards,
+        pick_rate_updated_at = NOW()
+    FROM (
+        SELECT patch_version, region, tier, queue_id, SUM(total_games)::int as boards
+        FROM compositions.team_comps
+        WHERE (p_patch IS NULL OR patch_version = p_patch)
+          AND (p_region IS NULL OR region = p_region)
+        GROUP BY patch_version, region, tier, queue_id
+    ) b
+    WHERE tc.patch_version = b.patch_version
+      AND tc.region = b.region
+      AND tc.tier = b.tier
+      AND tc.queue_id = b.queue_id;
+
+    GET DIAGNOSTICS updated_count = ROW_COUNT;
+
+    RETURN updated_count;
+END;
+$$ LANGUAGE plpgsql;
+
+SELECT compositions.recalculate_pick_rates();
@@ -0,0 +1,73 @@
+-- =====================================================
+-- TFT Arena - Pick Rate Against Participant Boards
+-- File: migrations/017_pick_rate_participant_boards.up.sql
+-- =====================================================
+
+-- =====================================================
+-- Batch Pick Rate Recalculation
+-- =====================================================
+-- pick_rate = comp games / participant boards in the same
+-- patch/region/tier/queue bucket. Boards are counted from
+-- matches.participants, so boards whose comp was never recorded or was
+-- removed by retention still count. On both sides of the ratio a board
+-- belongs to the tier its player was attributed at game time.
+CREATE OR REPLACE FUNCTION compositions.recalculate_pick_rates(
+    p_patch VARCHAR(10) DEFAULT NULL,
+    p_region VARCHAR(10) DEFAULT NULL
+)
+RETURNS INTEGER AS $$
+DECLARE
+    updated_count INTEGER;
+BEGIN
+    WITH boards AS (
+        SELECT m.patch_version, m.region, p.tier, m.queue_id, COUNT(*)::int as boards
+        FROM matches.participants p
+        JOIN matches.matches m ON m.match_id = p.match_id
+        WHERE p.tier IS NOT NULL
+          AND (p_patch IS NULL OR m.patch_version = p_patch)
+          AND (p_region IS NULL OR m.region = p_region)
+        GROUP BY m.patch_version, m.region, p.tier, m.queue_id
+    ),
+    tier_games AS (
+        SELECT cg.comp_hash, COUNT(*)::int as games
+        FROM compositions.comp_games cg
+        JOIN compositions.team_comps t ON t.comp_hash = cg.comp_hash
+        WHERE cg.tier = t.tier
+          AND (p_patch IS NULL OR t.patch_version = p_patch)
+          AND (p_region IS NULL OR t.region = p_region)
+        GROUP BY cg.comp_hash
+    )
+    UPDATE compositions.team_comps tc
+    SET
+        pick_rate = COALESCE(ROUND((COALESCE(g.games, 0)::DECIMAL / NULLIF(b.boards, 0)) * 100, 2), 0),
+        pick_rate_sample_size = COALESCE(b.boards, 0),
+        pick_rate_updated_at = NOW()
+    FROM compositions.team_comps t
+    LEFT JOIN boards b
+        ON b.patch_version = t.patch_version
+       AND b.region = t.region
+       AND b.tier = t.tier
+       AND b.queue_id = t.queue_id
+    LEFT JOIN tier_games g ON g.comp_hash = t.comp_hash
+    WHERE tc.id = t.id
+      AND (p_patch IS NULL OR t.patch_version = p_patch)
+      AND (p_region IS NULL OR t.region = p_region);
+
+    GET DIAGNOSTICS updated_count = ROW_COUNT;
+
+    RETURN updated_count;
+END;
+$$ LANGUAGE plpgsql;
+
+SELECT compositions.recalculate_pick_rates();
+
+-- =====================================================
+-- Grant Permissions
+-- =====================================================
+GRANT ALL PRIVILEGES ON ALL FUNCTIONS IN SCHEMA compositions TO tft_user;
+
+-- =====================================================
+-- Comments
+-- =====================================================
+COMMENT ON COLUMN compositions.team_comps.pick_rate_sample_size IS 'Participant boards of players attributed to the bucket tier, the pick rate denominator';
+COMMENT ON FUNCTION compositions.recalculate_pick_rates(VARCHAR, VARCHAR) IS 'Batch recomputation of pick rates against participant boards per bucket';
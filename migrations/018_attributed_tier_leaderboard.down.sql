@@ -0,0 +1,182 @@
+-- =====================================================
+-- File: migrations/018_attributed_tier_leaderboard.down.sql
+-- =====================================================
+DROP MATERIALIZED VIEW IF EXISTS compositions.comp_leaderboard;
+
+CREATE MATERIALIZED VIEW compositions.comp_leaderboard AS
+SELECT
+    tc.id,
+    tc.comp_hash,
+    tc.patch_version,
+    tc.region,
+    tc.tier,
+    tc.queue_id,
+    tc.traits,
+    tc.units,
+    tc.total_games,
+    tc.total_wins,
+    tc.total_top4,
+    tc.avg_placement,
+    tc.win_rate,
+    tc.top4_rate,
+    tc.pick_rate,
+    tc.pick_rate_sample_size,
+
+    -- Calculate tier rank based on performance
+    CASE
+        WHEN tc.win_rate >= 25 AND tc.pick_rate >= 5 AND tc.total_games >= 500 THEN 'S'
+        WHEN tc.win_rate >= 20 AND tc.pick_rate >= 3 AND tc.total_games >= 300 THEN 'A'
+        WHEN tc.win_rate >= 15 AND tc.pick_rate >= 1 AND tc.total_games >= 100 THEN 'B'
+        WHEN tc.total_games >= 50 THEN 'C'
+        ELSE 'D'
+    END as tier_rank,
+
+    -- Sample size categorization
+    CASE
+        WHEN tc.total_games >= 1000 THEN 'high'
+        WHEN tc.total_games >= 100 THEN 'medium'
+        WHEN tc.total_games >= 50 THEN 'low'
+        ELSE 'insufficient'
+    END as sample_size,
+
+    tc.first_seen,
+    tc.last_seen,
+    tc.last_updated
+FROM compositions.team_comps tc
+WHERE tc.total_games >= 50  -- Minimum threshold for leaderboard
+ORDER BY
+    tc.patch_version DESC,
+    tc.region,
+    tc.tier,
+    tc.queue_id,
+    tc.win_rate DESC,
+    tc.pick_rate DESC;
+
+CREATE UNIQUE INDEX idx_comp_leaderboard_id ON compositions.comp_leaderboard (id);
+CREATE INDEX idx_comp_leaderboard_tier_rank ON compositions.comp_leaderboard (tier_rank);
+CREATE INDEX idx_comp_leaderboard_patch_region ON compositions.comp_leaderboard (patch_version, region, queue_id);
+CREATE INDEX idx_comp_leaderboard_performance ON compositions.comp_leaderboard (
+    tier_rank, win_rate DESC, pick_rate DESC
+);
+
+CREATE OR REPLACE FUNCTION compositions.comp_stats_for_tier(
+    p_patch VARCHAR(10),
+    p_region VARCHAR(10),
+    p_min_tier VARCHAR(20)
+)
+RETURNS TABLE (
+    comp_hash VARCHAR(64),
+    total_games BIGINT,
+    total_wins BIGINT,
+    total_top4 BIGINT,
+    avg_placement DECIMAL(4,2),
+    win_rate DECIMAL(5,2),
+    top4_rate DECIMAL(5,2)
+) AS $$
+    SELECT
+        cg.comp_hash,
+        COUNT(*),
+        COUNT(*) FILTER (WHERE cg.placement = 1),
+        COUNT(*) FILTER (WHERE cg.placement <= 4),
+        ROUND(AVG(cg.placement), 2),
+        ROUND((COUNT(*) FILTER (WHERE cg.placement = 1)::DECIMAL / COUNT(*)) * 100, 2),
+        ROUND((COUNT(*) FILTER (WHERE cg.placement <= 4)::DECIMAL / COUNT(*)) * 100, 2)
+    FROM compositions.comp_games cg
+    JOIN compositions.team_comps tc ON tc.comp_hash = cg.comp_hash
+    WHERE tc.patch_version = p_patch
+      AND tc.region = p_region
+      AND players.tier_weight(cg.tier) >= players.tier_weight(p_min_tier)
+    GROUP BY cg.comp_hash;
+$$ LANGUAGE sql STABLE;
+
+DROP FUNCTION IF EXISTS matches.reattribute_unranked_matches(INTEGER, INTERVAL);
+
+CREATE OR REPLACE FUNCTION matches.reattribute_unranked_matches(p_limit INTEGER DEFAULT 1000)
+RETURNS INTEGER AS $$
+DECLARE
+    target_match VARCHAR(50);
+    processed INTEGER := 0;
+BEGIN
+    FOR target_match IN
+        SELECT DISTINCT p.match_id
+        FROM matches.participants p
+        WHERE p.tier IS NULL
+        LIMIT p_limit
+    LOOP
+        PERFORM matches.attribute_lobby_ranks(target_match);
+        processed := processed + 1;
+    END LOOP;
+
+    RETURN processed;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION matches.attribute_lobby_ranks(p_match_id VARCHAR(50))
+RETURNS INTEGER AS $$
+DECLARE
+    ranked_count INTEGER;
+BEGIN
+    UPDATE matches.participants p
+    SET
+        tier = r.tier,
+        rank_division = r.rank_division,
+        league_points = r.league_points,
+        rank_recorded_at = r.recorded_at
+    FROM (
+        SELECT target.id, h.tier, h.rank_division, h.league_points, h.recorded_at
+        FROM matches.participants target
+        JOIN matches.matches m ON m.match_id = target.match_id
+        CROSS JOIN LATERAL (
+            SELECT snap.tier, snap.rank_division, snap.league_points, snap.recorded_at
+            FROM players.league_entry_history snap
+            WHERE snap.puuid = target.puuid
+              AND snap.region = m.region
+              AND snap.queue_type = CASE matches.get_queue_type_from_id(m.queue_id)
+                    WHEN 'RANKED_TFT_DOUBLE_UP' THEN 'RANKED_TFT_DOUBLE_UP'
+                    WHEN 'RANKED_TFT_TURBO' THEN 'RANKED_TFT_TURBO'
+                    ELSE 'RANKED_TFT'
+                  END
+            ORDER BY
+                snap.recorded_at <= to_timestamp(m.game_datetime) DESC,
+                CASE WHEN snap.recorded_at <= to_timestamp(m.game_datetime) THEN snap.recorded_at END DESC,
+                snap.recorded_at ASC
+            LIMIT 1
+        ) h
+        WHERE target.match_id = p_match_id
+    ) r
+    WHERE p.id = r.id;
+
+    SELECT COUNT(*) INTO ranked_count
+    FROM matches.participants
+    WHERE match_id = p_match_id AND tier IS NOT NULL;
+
+    UPDATE matches.matches m
+    SET
+        lobby_tier_weight = l.avg_weight,
+        lobby_tier = players.tier_from_weight(ROUND(l.avg_weight)::int),
+        ranked_participants = ranked_count
+    FROM (
+        SELECT ROUND(AVG(players.tier_weight(tier)), 2) as avg_weight
+        FROM matches.participants
+        WHERE match_id = p_match_id AND tier IS NOT NULL
+    ) l
+    WHERE m.match_id = p_match_id;
+
+    UPDATE compositions.comp_games cg
+    SET
+        tier = p.tier,
+        lobby_tier = m.lobby_tier
+    FROM matches.participants p
+    JOIN matches.matches m ON m.match_id = p.match_id
+    WHERE cg.match_id = p_match_id
+      AND p.match_id = cg.match_id
+      AND p.participant_id = cg.participant_id
+      AND (cg.tier IS DISTINCT FROM p.tier OR cg.lobby_tier IS DISTINCT FROM m.lobby_tier);
+
+    RETURN ranked_count;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP INDEX IF EXISTS matches.idx_matches_rank_attempted;
+
+ALTER TABLE matches.matches DROP COLUMN IF EXISTS rank_attempted_at;
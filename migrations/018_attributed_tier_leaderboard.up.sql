@@ -0,0 +1,244 @@
+-- =====================================================
+-- TFT Arena - Leaderboard by Attributed Tier
+-- File: migrations/018_attributed_tier_leaderboard.up.sql
+-- =====================================================
+
+-- =====================================================
+-- Attribution Attempts
+-- =====================================================
+ALTER TABLE matches.matches
+    ADD COLUMN rank_attempted_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX idx_matches_rank_attempted ON matches.matches (rank_attempted_at NULLS FIRST, game_datetime DESC);
+
+-- Same as in 008, but only rewrites participants whose rank changed, so
+-- retries do not mark meta buckets dirty, and stamps the attempt.
+CREATE OR REPLACE FUNCTION matches.attribute_lobby_ranks(p_match_id VARCHAR(50))
+RETURNS INTEGER AS $$
+DECLARE
+    ranked_count INTEGER;
+BEGIN
+    UPDATE matches.participants p
+    SET
+        tier = r.tier,
+        rank_division = r.rank_division,
+        league_points = r.league_points,
+        rank_recorded_at = r.recorded_at
+    FROM (
+        SELECT target.id, h.tier, h.rank_division, h.league_points, h.recorded_at
+        FROM matches.participants target
+        JOIN matches.matches m ON m.match_id = target.match_id
+        CROSS JOIN LATERAL (
+            SELECT snap.tier, snap.rank_division, snap.league_points, snap.recorded_at
+            FROM players.league_entry_history snap
+            WHERE snap.puuid = target.puuid
+              AND snap.region = m.region
+              AND snap.queue_type = CASE matches.get_queue_type_from_id(m.queue_id)
+                    WHEN 'RANKED_TFT_DOUBLE_UP' THEN 'RANKED_TFT_DOUBLE_UP'
+                    WHEN 'RANKED_TFT_TURBO' THEN 'RANKED_TFT_TURBO'
+                    ELSE 'RANKED_TFT'
+                  END
+            ORDER BY
+                snap.recorded_at <= to_timestamp(m.game_datetime) DESC,
+                CASE WHEN snap.recorded_at <= to_timestamp(m.game_datetime) THEN snap.recorded_at END DESC,
+                snap.recorded_at ASC
+            LIMIT 1
+        ) h
+        WHERE target.match_id = p_match_id
+    ) r
+    WHERE p.id = r.id
+      AND p.rank_recorded_at IS DISTINCT FROM r.recorded_at;
+
+    SELECT COUNT(*) INTO ranked_count
+    FROM matches.participants
+    WHERE match_id = p_match_id AND tier IS NOT NULL;
+
+    UPDATE matches.matches m
+    SET
+        lobby_tier_weight = l.avg_weight,
+        lobby_tier = players.tier_from_weight(ROUND(l.avg_weight)::int),
+        ranked_participants = ranked_count,
+        rank_attempted_at = NOW()
+    FROM (
+        SELECT ROUND(AVG(players.tier_weight(tier)), 2) as avg_weight
+        FROM matches.participants
+        WHERE match_id = p_match_id AND tier IS NOT NULL
+    ) l
+    WHERE m.match_id = p_match_id;
+
+    UPDATE compositions.comp_games cg
+    SET
+        tier = p.tier,
+        lobby_tier = m.lobby_tier
+    FROM matches.participants p
+    JOIN matches.matches m ON m.match_id = p.match_id
+    WHERE cg.match_id = p_match_id
+      AND p.match_id = cg.match_id
+      AND p.participant_id = cg.participant_id
+      AND (cg.tier IS DISTINCT FROM p.tier OR cg.lobby_tier IS DISTINCT FROM m.lobby_tier);
+
+    RETURN ranked_count;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Retries matches with unranked participants, least recently attempted
+-- first. A match is retried at most once per p_retry_after, so players
+-- that never get a rank do not keep the same matches at the front.
+DROP FUNCTION IF EXISTS matches.reattribute_unranked_matches(INTEGER);
+
+CREATE OR REPLACE FUNCTION matches.reattribute_unranked_matches(
+    p_limit INTEGER DEFAULT 1000,
+    p_retry_after INTERVAL DEFAULT '1 day'
+)
+RETURNS INTEGER AS $$
+DECLARE
+    target_match VARCHAR(50);
+    processed INTEGER := 0;
+BEGIN
+    FOR target_match IN
+        SELECT m.match_id
+        FROM matches.matches m
+        WHERE (m.rank_attempted_at IS NULL OR m.rank_attempted_at < NOW() - p_retry_after)
+          AND EXISTS (
+              SELECT 1 FROM matches.participants p
+              WHERE p.match_id = m.match_id AND p.tier IS NULL
+          )
+        ORDER BY m.rank_attempted_at NULLS FIRST, m.game_datetime DESC
+        LIMIT p_limit
+    LOOP
+        PERFORM matches.attribute_lobby_ranks(target_match);
+        processed := processed + 1;
+    END LOOP;
+
+    RETURN processed;
+END;
+$$ LANGUAGE plpgsql;
+
+-- =====================================================
+-- Leaderboard per Composition and Attributed Tier
+-- =====================================================
+-- One row per comp and tier its boards were played at, so tier filters
+-- the leaderboard by who played rather than by the tier the collector was
+-- crawling. Pick rate follows recalculate_pick_rates: games by players of
+-- the tier over their participant boards in the patch/region/queue.
+DROP FUNCTION IF EXISTS compositions.comp_stats_for_tier(VARCHAR, VARCHAR, VARCHAR);
+DROP MATERIALIZED VIEW IF EXISTS compositions.comp_leaderboard;
+
+CREATE MATERIALIZED VIEW compositions.comp_leaderboard AS
+WITH tier_games AS (
+    SELECT
+        cg.comp_hash,
+        cg.tier,
+        COUNT(*)::int as games,
+        COUNT(*) FILTER (WHERE cg.placement = 1)::int as wins,
+        COUNT(*) FILTER (WHERE cg.placement <= 4)::int as top4,
+        ROUND(AVG(cg.placement), 2) as avg_placement
+    FROM compositions.comp_games cg
+    WHERE cg.tier IS NOT NULL
+    GROUP BY cg.comp_hash, cg.tier
+),
+boards AS (
+    SELECT m.patch_version, m.region, p.tier, m.queue_id, COUNT(*)::int as boards
+    FROM matches.participants p
+    JOIN matches.matches m ON m.match_id = p.match_id
+    WHERE p.tier IS NOT NULL
+    GROUP BY m.patch_version, m.region, p.tier, m.queue_id
+),
+stats AS (
+    SELECT
+        tc.id,
+        tc.comp_hash,
+        tc.patch_version,
+        tc.region,
+        g.tier,
+        tc.queue_id,
+        tc.traits,
+        tc.units,
+        g.games as total_games,
+        g.wins as total_wins,
+        g.top4 as total_top4,
+        g.avg_placement,
+        ROUND((g.wins::DECIMAL / g.games) * 100, 2) as win_rate,
+        ROUND((g.top4::DECIMAL / g.games) * 100, 2) as top4_rate,
+        COALESCE(ROUND((g.games::DECIMAL / NULLIF(b.boards, 0)) * 100, 2), 0) as pick_rate,
+        COALESCE(b.boards, 0) as pick_rate_sample_size,
+        tc.first_seen,
+        tc.last_seen,
+        tc.last_updated
+    FROM compositions.team_comps tc
+    JOIN tier_games g ON g.comp_hash = tc.comp_hash
+    LEFT JOIN boards b
+        ON b.patch_version = tc.patch_version
+       AND b.region = tc.region
+       AND b.tier = g.tier
+       AND b.queue_id = tc.queue_id
+)
+SELECT
+    s.id,
+    s.comp_hash,
+    s.patch_version,
+    s.region,
+    s.tier,
+    s.queue_id,
+    s.traits,
+    s.units,
+    s.total_games,
+    s.total_wins,
+    s.total_top4,
+    s.avg_placement,
+    s.win_rate,
+    s.top4_rate,
+    s.pick_rate,
+    s.pick_rate_sample_size,
+
+    -- Calculate tier rank based on performance
+    CASE
+        WHEN s.win_rate >= 25 AND s.pick_rate >= 5 AND s.total_games >= 500 THEN 'S'
+        WHEN s.win_rate >= 20 AND s.pick_rate >= 3 AND s.total_games >= 300 THEN 'A'
+        WHEN s.win_rate >= 15 AND s.pick_rate >= 1 AND s.total_games >= 100 THEN 'B'
+        WHEN s.total_games >= 50 THEN 'C'
+        ELSE 'D'
+    END as tier_rank,
+
+    -- Sample size categorization
+    CASE
+        WHEN s.total_games >= 1000 THEN 'high'
+        WHEN s.total_games >= 100 THEN 'medium'
+        WHEN s.total_games >= 50 THEN 'low'
+        ELSE 'insufficient'
+    END as sample_size,
+
+    s.first_seen,
+    s.last_seen,
+    s.last_updated
+FROM stats s
+WHERE s.total_games >= 50  -- Minimum threshold for leaderboard
+ORDER BY
+    s.patch_version DESC,
+    s.region,
+    s.tier,
+    s.queue_id,
+    s.win_rate DESC,
+    s.pick_rate DESC;
+
+CREATE UNIQUE INDEX idx_comp_leaderboard_id_tier ON compositions.comp_leaderboard (id, tier);
+CREATE INDEX idx_comp_leaderboard_comp_hash ON compositions.comp_leaderboard (comp_hash);
+CREATE INDEX idx_comp_leaderboard_tier_rank ON compositions.comp_leaderboard (tier_rank);
+CREATE INDEX idx_comp_leaderboard_bucket ON compositions.comp_leaderboard (patch_version, region, tier, queue_id);
+CREATE INDEX idx_comp_leaderboard_performance ON compositions.comp_leaderboard (
+    tier_rank, win_rate DESC, pick_rate DESC
+);
+
+-- =====================================================
+-- Grant Permissions
+-- =====================================================
+GRANT ALL PRIVILEGES ON ALL TABLES IN SCHEMA compositions TO tft_user;
+GRANT ALL PRIVILEGES ON ALL FUNCTIONS IN SCHEMA compositions TO tft_user;
+GRANT ALL PRIVILEGES ON ALL FUNCTIONS IN SCHEMA matches TO tft_user;
+
+-- =====================================================
+-- Comments
+-- =====================================================
+COMMENT ON COLUMN matches.matches.rank_attempted_at IS 'Last time rank attribution ran for the match';
+COMMENT ON FUNCTION matches.reattribute_unranked_matches(INTEGER, INTERVAL) IS 'Retries rank attribution for matches with unranked participants, least recently attempted first';
+COMMENT ON MATERIALIZED VIEW compositions.comp_leaderboard IS 'Composition leaderboard per attributed tier with pre-calculated tier rankings';
@@ -0,0 +1,130 @@
+-- =====================================================
+-- File: migrations/019_meta_retention_and_snapshots.down.sql
+-- =====================================================
+DROP TRIGGER IF EXISTS trigger_mark_deleted_participants_dirty ON matches.participants;
+
+CREATE OR REPLACE FUNCTION matches.cleanup_old_matches(
+    days_old INTEGER DEFAULT 180,
+    batch_size INTEGER DEFAULT 500,
+    dry_run BOOLEAN DEFAULT FALSE
+)
+RETURNS INTEGER AS $$
+DECLARE
+    cutoff BIGINT := EXTRACT(EPOCH FROM NOW() - INTERVAL '1 day' * days_old)::BIGINT;
+    affected INTEGER;
+BEGIN
+    IF dry_run THEN
+        SELECT COUNT(*) INTO affected
+        FROM matches.matches
+        WHERE game_datetime < cutoff;
+        RETURN affected;
+    END IF;
+
+    DELETE FROM matches.matches
+    WHERE id IN (
+        SELECT id FROM matches.matches
+        WHERE game_datetime < cutoff
+        ORDER BY game_datetime
+        LIMIT batch_size
+        FOR UPDATE SKIP LOCKED
+    );
+
+    GET DIAGNOSTICS affected = ROW_COUNT;
+    RETURN affected;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP FUNCTION IF EXISTS analytics.create_daily_snapshot(DATE);
+DROP FUNCTION IF EXISTS analytics.calculate_meta_diversity(VARCHAR, VARCHAR, VARCHAR, INTEGER);
+
+TRUNCATE analytics.daily_snapshots;
+
+ALTER TABLE analytics.daily_snapshots
+    DROP CONSTRAINT IF EXISTS daily_snapshots_bucket_key,
+    DROP CONSTRAINT IF EXISTS check_tier_bucket_valid,
+    DROP CONSTRAINT IF EXISTS check_queue_id_valid,
+    DROP COLUMN IF EXISTS queue_id,
+    ADD CONSTRAINT daily_snapshots_snapshot_date_patch_version_region_tier_key UNIQUE (snapshot_date, patch_version, region, tier);
+
+CREATE OR REPLACE FUNCTION analytics.calculate_meta_diversity(
+    p_patch VARCHAR(10),
+    p_region VARCHAR(10),
+    p_tier VARCHAR(20)
+)
+RETURNS DECIMAL(5,2) AS $$
+DECLARE
+    diversity_score DECIMAL(5,2);
+BEGIN
+    SELECT
+        ROUND(
+            (1.0 - (SUM(POWER(play_rate/100.0, 2)))) * 100,
+            2
+        )
+    INTO diversity_score
+    FROM analytics.trait_meta
+    WHERE patch_version = p_patch
+      AND region = p_region
+      AND tier = p_tier
+      AND play_rate >= 1;
+
+    RETURN COALESCE(diversity_score, 0);
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION analytics.create_daily_snapshot(
+    p_date DATE DEFAULT CURRENT_DATE
+)
+RETURNS VOID AS $$
+DECLARE
+    snapshot_patch VARCHAR(10);
+    snapshot_region VARCHAR(10);
+BEGIN
+    SELECT patch_version INTO snapshot_patch
+    FROM matches.matches
+    WHERE DATE(to_timestamp(game_datetime)) = p_date
+    ORDER BY created_at DESC
+    LIMIT 1;
+
+    FOR snapshot_region IN
+        SELECT DISTINCT region FROM matches.matches
+        WHERE patch_version = snapshot_patch
+        AND DATE(to_timestamp(game_datetime)) = p_date
+    LOOP
+        INSERT INTO analytics.daily_snapshots (
+            snapshot_date, patch_version, region, tier,
+            total_games, unique_players, avg_game_length,
+            top_compositions, top_traits, top_champions,
+            meta_diversity_score
+        )
+        SELECT
+            p_date,
+            snapshot_patch,
+            snapshot_region,
+            'CHALLENGER',
+
+            COUNT(DISTINCT m.match_id),
+            COUNT(DISTINCT p.puuid),
+            AVG(m.game_length),
+
+            '[]'::jsonb,
+            '[]'::jsonb,
+            '[]'::jsonb,
+
+            analytics.calculate_meta_diversity(snapshot_patch, snapshot_region, 'CHALLENGER')
+
+        FROM matches.matches m
+        JOIN matches.participants p ON m.match_id = p.match_id
+        WHERE m.patch_version = snapshot_patch
+          AND m.region = snapshot_region
+          AND DATE(to_timestamp(m.game_datetime)) = p_date
+        GROUP BY snapshot_patch, snapshot_region
+
+        ON CONFLICT (snapshot_date, patch_version, region, tier)
+        DO UPDATE SET
+            total_games = EXCLUDED.total_games,
+            unique_players = EXCLUDED.unique_players,
+            avg_game_length = EXCLUDED.avg_game_length,
+            meta_diversity_score = EXCLUDED.meta_diversity_score;
+    END LOOP;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,163 @@
+-- =====================================================
+-- TFT Arena - Meta Buckets for Deletes and Snapshots
+-- File: migrations/019_meta_retention_and_snapshots.up.sql
+-- =====================================================
+
+-- =====================================================
+-- Deleted Participants
+-- =====================================================
+-- Participants deleted on their own still have their match to look the
+-- bucket up from.
+CREATE TRIGGER trigger_mark_deleted_participants_dirty
+    AFTER DELETE ON matches.participants
+    REFERENCING OLD TABLE AS changed_participants
+    FOR EACH STATEMENT
+    EXECUTE FUNCTION analytics.mark_participant_buckets_dirty();
+
+-- Participants removed by the cascade of a match delete do not: the match
+-- is gone by the time their trigger fires. Retention marks the buckets of
+-- each batch itself before deleting it.
+CREATE OR REPLACE FUNCTION matches.cleanup_old_matches(
+    days_old INTEGER DEFAULT 180,
+    batch_size INTEGER DEFAULT 500,
+    dry_run BOOLEAN DEFAULT FALSE
+)
+RETURNS INTEGER AS $$
+DECLARE
+    cutoff BIGINT := EXTRACT(EPOCH FROM NOW() - INTERVAL '1 day' * days_old)::BIGINT;
+    affected INTEGER;
+BEGIN
+    IF dry_run THEN
+        SELECT COUNT(*) INTO affected
+        FROM matches.matches
+        WHERE game_datetime < cutoff;
+        RETURN affected;
+    END IF;
+
+    WITH batch AS (
+        SELECT match_id, patch_version, region, queue_id
+        FROM matches.matches
+        WHERE game_datetime < cutoff
+        ORDER BY game_datetime
+        LIMIT batch_size
+        FOR UPDATE SKIP LOCKED
+    ),
+    marked AS (
+        INSERT INTO analytics.meta_dirty_buckets (patch_version, region, queue_id, tier)
+        SELECT DISTINCT b.patch_version, b.region, b.queue_id, t.bucket
+        FROM batch b
+        JOIN matches.participants p ON p.match_id = b.match_id
+        CROSS JOIN LATERAL unnest(analytics.buckets_for_tier(p.tier)) AS t(bucket)
+        WHERE p.tier IS NOT NULL
+        ON CONFLICT (patch_version, region, queue_id, tier)
+        DO UPDATE SET marked_at = NOW()
+    )
+    DELETE FROM matches.matches m
+    USING batch b
+    WHERE m.match_id = b.match_id;
+
+    GET DIAGNOSTICS affected = ROW_COUNT;
+    RETURN affected;
+END;
+$$ LANGUAGE plpgsql;
+
+-- =====================================================
+-- Queue-Aware Daily Snapshots
+-- =====================================================
+-- Existing rows were computed over every player of the day and labelled
+-- CHALLENGER; they are rebuilt below for the days still in matches.
+TRUNCATE analytics.daily_snapshots;
+
+ALTER TABLE analytics.daily_snapshots
+    ADD COLUMN queue_id INTEGER NOT NULL DEFAULT 1100,
+    ADD CONSTRAINT check_queue_id_valid CHECK (queue_id IN (1090, 1100, 1130, 1160)),
+    ADD CONSTRAINT check_tier_bucket_valid CHECK (tier IN (
+        'IRON', 'BRONZE', 'SILVER', 'GOLD', 'PLATINUM', 'EMERALD',
+        'DIAMOND', 'MASTER', 'GRANDMASTER', 'CHALLENGER',
+        'DIAMOND_PLUS', 'MASTER_PLUS'
+    )),
+    DROP CONSTRAINT daily_snapshots_snapshot_date_patch_version_region_tier_key,
+    ADD CONSTRAINT daily_snapshots_bucket_key UNIQUE (snapshot_date, patch_version, region, tier, queue_id);
+
+DROP FUNCTION IF EXISTS analytics.calculate_meta_diversity(VARCHAR, VARCHAR, VARCHAR);
+
+CREATE OR REPLACE FUNCTION analytics.calculate_meta_diversity(
+    p_patch VARCHAR(10),
+    p_region VARCHAR(10),
+    p_tier VARCHAR(20),
+    p_queue_id INTEGER DEFAULT 1100
+)
+RETURNS DECIMAL(5,2) AS $$
+    SELECT COALESCE(ROUND((1.0 - SUM(POWER(play_rate / 100.0, 2))) * 100, 2), 0)
+    FROM analytics.trait_meta
+    WHERE patch_version = p_patch
+      AND region = p_region
+      AND tier = p_tier
+      AND queue_id = p_queue_id
+      AND play_rate >= 1;
+$$ LANGUAGE sql STABLE;
+
+DROP FUNCTION IF EXISTS analytics.create_daily_snapshot(DATE);
+
+-- One snapshot per patch, region, queue and meta bucket played that day.
+-- Boards count towards every bucket of their player's attributed tier;
+-- unranked boards count towards none.
+CREATE OR REPLACE FUNCTION analytics.create_daily_snapshot(
+    p_date DATE DEFAULT CURRENT_DATE
+)
+RETURNS INTEGER AS $$
+DECLARE
+    saved INTEGER;
+BEGIN
+    INSERT INTO analytics.daily_snapshots (
+        snapshot_date, patch_version, region, tier, queue_id,
+        total_games, unique_players, avg_game_length,
+        top_compositions, top_traits, top_champions,
+        meta_diversity_score
+    )
+    SELECT
+        p_date, d.patch_version, d.region, d.bucket, d.queue_id,
+        COUNT(DISTINCT d.match_id),
+        COUNT(DISTINCT d.puuid),
+        AVG(d.game_length),
+        '[]'::jsonb,
+        '[]'::jsonb,
+        '[]'::jsonb,
+        analytics.calculate_meta_diversity(d.patch_version, d.region, d.bucket, d.queue_id)
+    FROM (
+        SELECT m.match_id, m.patch_version, m.region, m.queue_id, m.game_length, p.puuid, b.bucket
+        FROM matches.matches m
+        JOIN matches.participants p ON p.match_id = m.match_id
+        CROSS JOIN LATERAL unnest(analytics.buckets_for_tier(p.tier)) AS b(bucket)
+        WHERE DATE(to_timestamp(m.game_datetime)) = p_date
+          AND p.tier IS NOT NULL
+    ) d
+    GROUP BY d.patch_version, d.region, d.bucket, d.queue_id
+    ON CONFLICT (snapshot_date, patch_version, region, tier, queue_id)
+    DO UPDATE SET
+        total_games = EXCLUDED.total_games,
+        unique_players = EXCLUDED.unique_players,
+        avg_game_length = EXCLUDED.avg_game_length,
+        meta_diversity_score = EXCLUDED.meta_diversity_score;
+
+    GET DIAGNOSTICS saved = ROW_COUNT;
+    RETURN saved;
+END;
+$$ LANGUAGE plpgsql;
+
+SELECT analytics.create_daily_snapshot(d.day)
+FROM (SELECT DISTINCT DATE(to_timestamp(game_datetime)) AS day FROM matches.matches) d;
+
+-- =====================================================
+-- Grant Permissions
+-- =====================================================
+GRANT ALL PRIVILEGES ON ALL FUNCTIONS IN SCHEMA analytics TO tft_user;
+GRANT ALL PRIVILEGES ON ALL FUNCTIONS IN SCHEMA matches TO tft_user;
+
+-- =====================================================
+-- Comments
+-- =====================================================
+COMMENT ON FUNCTION matches.cleanup_old_matches(INTEGER, INTEGER, BOOLEAN) IS 'Deletes one batch of matches played more than days_old days ago and marks their meta buckets dirty, or counts them on dry run';
+COMMENT ON FUNCTION analytics.calculate_meta_diversity(VARCHAR, VARCHAR, VARCHAR, INTEGER) IS 'Trait diversity of a meta bucket, 100 minus the Herfindahl index of trait play rates';
+COMMENT ON FUNCTION analytics.create_daily_snapshot(DATE) IS 'Snapshots the given day per patch, region, queue and meta bucket and returns how many rows were written';
+COMMENT ON TABLE analytics.daily_snapshots IS 'Daily meta snapshots per patch, region, queue and meta bucket for trend analysis';
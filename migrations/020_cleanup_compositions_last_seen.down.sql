@@ -0,0 +1,36 @@
+-- =====================================================
+-- File: migrations/020_cleanup_compositions_last_seen.down.sql
+-- =====================================================
+CREATE OR REPLACE FUNCTION compositions.cleanup_old_compositions(
+    min_games INTEGER DEFAULT 10,
+    days_old INTEGER DEFAULT 30,
+    batch_size INTEGER DEFAULT 500,
+    dry_run BOOLEAN DEFAULT FALSE
+)
+RETURNS INTEGER AS $$
+DECLARE
+    affected INTEGER;
+BEGIN
+    IF dry_run THEN
+        SELECT COUNT(*) INTO affected
+        FROM compositions.team_comps
+        WHERE total_games < min_games
+          AND last_updated < NOW() - INTERVAL '1 day' * days_old;
+        RETURN affected;
+    END IF;
+
+    DELETE FROM compositions.team_comps
+    WHERE id IN (
+        SELECT id FROM compositions.team_comps
+        WHERE total_games < min_games
+          AND last_updated < NOW() - INTERVAL '1 day' * days_old
+        LIMIT batch_size
+        FOR UPDATE SKIP LOCKED
+    );
+
+    GET DIAGNOSTICS affected = ROW_COUNT;
+    RETURN affected;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP INDEX IF EXISTS compositions.idx_team_comps_last_seen;
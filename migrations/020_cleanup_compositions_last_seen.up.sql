@@ -0,0 +1,52 @@
+-- =====================================================
+-- TFT Arena - Stale Compositions by Last Game
+-- File: migrations/020_cleanup_compositions_last_seen.up.sql
+-- =====================================================
+
+-- last_updated moves on every stats recalculation, including the pick
+-- rate runs that touch every composition, so nothing ever aged out.
+-- last_seen only moves when a composition receives a game.
+CREATE INDEX idx_team_comps_last_seen ON compositions.team_comps (last_seen);
+
+CREATE OR REPLACE FUNCTION compositions.cleanup_old_compositions(
+    min_games INTEGER DEFAULT 10,
+    days_old INTEGER DEFAULT 30,
+    batch_size INTEGER DEFAULT 500,
+    dry_run BOOLEAN DEFAULT FALSE
+)
+RETURNS INTEGER AS $$
+DECLARE
+    affected INTEGER;
+BEGIN
+    IF dry_run THEN
+        SELECT COUNT(*) INTO affected
+        FROM compositions.team_comps
+        WHERE total_games < min_games
+          AND last_seen < NOW() - INTERVAL '1 day' * days_old;
+        RETURN affected;
+    END IF;
+
+    DELETE FROM compositions.team_comps
+    WHERE id IN (
+        SELECT id FROM compositions.team_comps
+        WHERE total_games < min_games
+          AND last_seen < NOW() - INTERVAL '1 day' * days_old
+        ORDER BY last_seen
+        LIMIT batch_size
+        FOR UPDATE SKIP LOCKED
+    );
+
+    GET DIAGNOSTICS affected = ROW_COUNT;
+    RETURN affected;
+END;
+$$ LANGUAGE plpgsql;
+
+-- =====================================================
+-- Grant Permissions
+-- =====================================================
+GRANT ALL PRIVILEGES ON ALL FUNCTIONS IN SCHEMA compositions TO tft_user;
+
+-- =====================================================
+-- Comments
+-- =====================================================
+COMMENT ON FUNCTION compositions.cleanup_old_compositions(INTEGER, INTEGER, INTEGER, BOOLEAN) IS 'Deletes one batch of low-sample compositions without games for days_old days, or counts them on dry run';
@@ -0,0 +1,80 @@
+-- =====================================================
+-- TFT Arena - Region-Scoped Meta Updates
+-- File: migrations/021_meta_update_region.up.sql
+-- =====================================================
+
+-- update_meta jobs can be limited to one region, so the cache
+-- invalidation they publish only evicts that region's reads.
+DROP FUNCTION IF EXISTS analytics.update_meta_from_matches(VARCHAR, BOOLEAN);
+DROP FUNCTION IF EXISTS analytics.mark_all_buckets_dirty(VARCHAR);
+
+CREATE OR REPLACE FUNCTION analytics.mark_all_buckets_dirty(
+    p_patch VARCHAR(10) DEFAULT NULL,
+    p_region VARCHAR(10) DEFAULT NULL
+)
+RETURNS INTEGER AS $$
+DECLARE
+    marked INTEGER;
+BEGIN
+    INSERT INTO analytics.meta_dirty_buckets (patch_version, region, queue_id, tier)
+    SELECT DISTINCT m.patch_version, m.region, m.queue_id, b.bucket
+    FROM matches.participants p
+    JOIN matches.matches m ON m.match_id = p.match_id
+    CROSS JOIN LATERAL unnest(analytics.buckets_for_tier(p.tier)) AS b(bucket)
+    WHERE p.tier IS NOT NULL
+      AND (
+          (p_patch IS NULL AND m.patch_version IN (SELECT analytics.active_patches()))
+          OR m.patch_version = p_patch
+      )
+      AND (p_region IS NULL OR m.region = p_region)
+    ON CONFLICT (patch_version, region, queue_id, tier)
+    DO UPDATE SET marked_at = NOW();
+
+    GET DIAGNOSTICS marked = ROW_COUNT;
+    RETURN marked;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION analytics.update_meta_from_matches(
+    p_patch VARCHAR(10) DEFAULT NULL,
+    p_region VARCHAR(10) DEFAULT NULL,
+    p_full BOOLEAN DEFAULT FALSE
+)
+RETURNS INTEGER AS $$
+DECLARE
+    bucket RECORD;
+    processed INTEGER := 0;
+BEGIN
+    IF p_full THEN
+        PERFORM analytics.mark_all_buckets_dirty(p_patch, p_region);
+    END IF;
+
+    FOR bucket IN
+        DELETE FROM analytics.meta_dirty_buckets
+        WHERE (p_patch IS NULL OR patch_version = p_patch)
+          AND (p_region IS NULL OR region = p_region)
+        RETURNING patch_version, region, queue_id, tier
+    LOOP
+        PERFORM analytics.recalculate_trait_meta(bucket.patch_version, bucket.region, bucket.tier, bucket.queue_id);
+        PERFORM analytics.recalculate_champion_meta(bucket.patch_version, bucket.region, bucket.tier, bucket.queue_id);
+        processed := processed + 1;
+    END LOOP;
+
+    IF processed > 0 THEN
+        REFRESH MATERIALIZED VIEW CONCURRENTLY analytics.current_meta;
+    END IF;
+
+    RETURN processed;
+END;
+$$ LANGUAGE plpgsql;
+
+-- =====================================================
+-- Grant Permissions
+-- =====================================================
+GRANT ALL PRIVILEGES ON ALL FUNCTIONS IN SCHEMA analytics TO tft_user;
+
+-- =====================================================
+-- Comments
+-- =====================================================
+COMMENT ON FUNCTION analytics.mark_all_buckets_dirty(VARCHAR, VARCHAR) IS 'Marks every bucket of a patch (or all active patches) and optionally one region dirty';
+COMMENT ON FUNCTION analytics.update_meta_from_matches(VARCHAR, VARCHAR, BOOLEAN) IS 'Recomputes trait and champion meta for dirty buckets of a patch and region and returns how many were processed';
@@ -0,0 +1,13 @@
+-- =====================================================
+-- File: migrations/022_summoner_ids_nullable.down.sql
+-- =====================================================
+-- summoner_id is unique, so summoners without one get a placeholder
+-- derived from their row ID.
+UPDATE players.summoners SET summoner_id = 'missing:' || id WHERE summoner_id IS NULL;
+UPDATE players.summoners SET account_id = '' WHERE account_id IS NULL;
+
+ALTER TABLE players.summoners ALTER COLUMN summoner_id SET NOT NULL;
+ALTER TABLE players.summoners ALTER COLUMN account_id SET NOT NULL;
+
+COMMENT ON COLUMN players.summoners.summoner_id IS NULL;
+COMMENT ON COLUMN players.summoners.account_id IS NULL;
@@ -0,0 +1,20 @@
+-- =====================================================
+-- TFT Arena - Optional Summoner and Account IDs
+-- File: migrations/022_summoner_ids_nullable.up.sql
+-- =====================================================
+
+-- The Riot API no longer returns the encrypted summoner and account IDs
+-- for every summoner; the PUUID is the only identifier it guarantees.
+-- Missing IDs are stored as NULL, which the summoner_id unique constraint
+-- allows any number of times, rather than as a shared empty string.
+ALTER TABLE players.summoners ALTER COLUMN summoner_id DROP NOT NULL;
+ALTER TABLE players.summoners ALTER COLUMN account_id DROP NOT NULL;
+
+UPDATE players.summoners SET summoner_id = NULL WHERE summoner_id = '';
+UPDATE players.summoners SET account_id = NULL WHERE account_id = '';
+
+-- =====================================================
+-- Comments
+-- =====================================================
+COMMENT ON COLUMN players.summoners.summoner_id IS 'Encrypted summoner ID, NULL when the Riot API did not return one';
+COMMENT ON COLUMN players.summoners.account_id IS 'Encrypted account ID, NULL when the Riot API did not return one';